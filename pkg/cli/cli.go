@@ -4,11 +4,16 @@ package cli
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"gox-ide/pkg/core"
 )
@@ -21,8 +26,24 @@ type CLI struct {
 	logger      core.Logger
 	input       io.Reader
 	output      io.Writer
+	sink        core.OutputSink
+	processes   *core.ProcessManager
+	debugger    *core.Debugger
 	currentFile string
+	currentLine int
 	files       []core.FileInfo
+	diagnostics []core.Diagnostic
+	testReport  *core.TestReport
+	profile     []core.ProfileEntry
+	symbols     *core.SymbolIndex
+	symbolHits  []core.SymbolMatch
+
+	// Watch mode runs commands from a background goroutine; mu
+	// serializes them with commands typed at the prompt
+	mu           sync.Mutex
+	watchCancel  context.CancelFunc
+	watchAction  core.WatchAction
+	watchProcess int
 }
 
 // Config holds CLI configuration
@@ -47,19 +68,27 @@ func New(config Config) *CLI {
 		output = os.Stdout
 	}
 
-	return &CLI{
-		project:  config.Project,
-		renderer: config.Renderer,
-		builder:  config.Builder,
-		logger:   config.Logger,
-		input:    input,
-		output:   output,
+	c := &CLI{
+		project:   config.Project,
+		renderer:  config.Renderer,
+		builder:   config.Builder,
+		logger:    config.Logger,
+		input:     input,
+		output:    output,
+		sink:      core.NewWriterSink(output, output),
+		processes: core.NewProcessManager(config.Builder, config.Logger),
+		debugger:  core.NewDebugger(config.Logger),
+		symbols:   core.NewSymbolIndex(config.Project),
 	}
+	c.processes.SetOnExit(c.reportExit)
+	c.debugger.SetOnEvent(c.reportDebugEvent)
+
+	return c
 }
 
 // Run starts the CLI interface
 func (c *CLI) Run(ctx context.Context) error {
-	c.showWelcome()
+	c.showWelcome(ctx)
 
 	scanner := bufio.NewScanner(c.input)
 
@@ -71,6 +100,7 @@ func (c *CLI) Run(ctx context.Context) error {
 			fmt.Fprint(c.output, "gox> ")
 
 			if !scanner.Scan() {
+				c.shutdown()
 				return scanner.Err()
 			}
 
@@ -79,7 +109,10 @@ func (c *CLI) Run(ctx context.Context) error {
 				continue
 			}
 
-			if err := c.executeCommand(ctx, command); err != nil {
+			c.mu.Lock()
+			err := c.executeCommand(ctx, command)
+			c.mu.Unlock()
+			if err != nil {
 				fmt.Fprintf(c.output, "❌ Error: %v\n", err)
 				if c.logger != nil {
 					c.logger.Error("Command error", core.Field{Key: "command", Value: command}, core.Field{Key: "error", Value: err.Error()})
@@ -89,12 +122,21 @@ func (c *CLI) Run(ctx context.Context) error {
 	}
 }
 
-func (c *CLI) showWelcome() {
+func (c *CLI) showWelcome(ctx context.Context) {
 	fmt.Fprintf(c.output, "🚀 GoX IDE - A Go-native IDE built for speed!\n")
 	fmt.Fprintf(c.output, "Project: %s\n\n", c.project.Path())
 
+	// Finding the modules walks the tree unless there is a go.work file,
+	// which Ctrl+C skips. IsGoProject counts the modules found, and the
+	// workspace is cached, failed or not, so commands do not walk again.
+	walkCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	ws, err := c.project.Workspace(walkCtx)
+	stop()
 	if c.project.IsGoProject() {
 		fmt.Fprintf(c.output, "🐹 Go project detected!\n")
+		if err == nil && len(ws.Modules) > 1 {
+			fmt.Fprintf(c.output, "📦 Workspace with %d modules (type 'modules' to list them)\n", len(ws.Modules))
+		}
 	}
 
 	fmt.Fprintf(c.output, "💡 Type 'help' for available commands\n\n")
@@ -115,28 +157,174 @@ func (c *CLI) executeCommand(ctx context.Context, command string) error {
 	case "help", "h":
 		return c.showHelp()
 	case "ls", "list":
-		return c.listFiles()
+		return c.listFiles(ctx, cmd.Args)
 	case "tree":
-		return c.showTree()
+		return c.showTree(ctx)
 	case "open", "o":
 		if len(cmd.Args) < 1 {
 			return fmt.Errorf("usage: open <filename>")
 		}
-		return c.openFile(cmd.Args[0])
+		return c.openFile(ctx, cmd.Args[0])
+	case "symbols", "sym":
+		if len(cmd.Args) < 1 {
+			return fmt.Errorf("usage: symbols <query>")
+		}
+		return c.searchSymbols(ctx, strings.Join(cmd.Args, " "))
+	case "def", "definition":
+		if len(cmd.Args) != 1 {
+			return fmt.Errorf("usage: def [file:]line:col")
+		}
+		return c.goToDefinition(ctx, cmd.Args[0])
 	case "cat", "view":
 		if len(cmd.Args) < 1 {
 			return fmt.Errorf("usage: cat <filename>")
 		}
 		return c.viewFile(cmd.Args[0])
 	case "run":
-		return c.runProject(ctx)
+		name := ""
+		if len(cmd.Args) > 0 {
+			name = cmd.Args[0]
+		}
+		_, err := c.startProject(ctx, name)
+		return err
+	case "configs":
+		return c.listRunConfigs()
+	case "ps":
+		return c.renderer.RenderProcesses(c.output, c.processes.List())
+	case "stop":
+		id, err := parseProcessID(cmd.Args, "stop")
+		if err != nil {
+			return err
+		}
+		// The exit callback reports the stop
+		return c.processes.Stop(id)
+	case "restart":
+		id, err := parseProcessID(cmd.Args, "restart")
+		if err != nil {
+			return err
+		}
+		return c.restartProcess(ctx, id)
+	case "watch":
+		return c.watch(ctx, cmd.Args)
+	case "logs":
+		id, err := parseProcessID(cmd.Args, "logs")
+		if err != nil {
+			return err
+		}
+		p, ok := c.processes.Get(id)
+		if !ok {
+			return fmt.Errorf("%w: %d (type 'ps' to list them)", core.ErrProcessNotFound, id)
+		}
+		return c.renderer.RenderProcessLogs(c.output, p)
 	case "test":
-		return c.runTests(ctx)
+		args, affected := cutFlag(cmd.Args, "--affected")
+		opts, err := parseTestArgs(args)
+		if err != nil {
+			return err
+		}
+		if affected {
+			return c.runAffectedTests(ctx, opts, nil)
+		}
+		return c.runTests(ctx, opts)
+	case "cover":
+		opts, err := parseTestArgs(cmd.Args)
+		if err != nil {
+			return err
+		}
+		return c.runCoverage(ctx, opts)
+	case "bench":
+		return c.runBench(ctx, cmd.Args)
+	case "tests":
+		if len(cmd.Args) < 1 || cmd.Args[0] != "failed" {
+			return fmt.Errorf("usage: tests failed")
+		}
+		return c.showFailedTests()
 	case "build":
-		return c.buildProject(ctx)
+		if len(cmd.Args) == 0 {
+			return c.buildProject(ctx)
+		}
+		opts, err := parseMatrixArgs(cmd.Args)
+		if err != nil {
+			return err
+		}
+		return c.buildMatrix(ctx, opts)
+	case "clean":
+		return c.cleanProject(ctx, cmd.Args)
+	case "tasks":
+		tasks, err := core.DiscoverTasks(c.project.Path())
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			fmt.Fprint(c.output, "📋 No Makefile targets or scripts found\n")
+			return nil
+		}
+		return c.renderer.RenderTasks(c.output, tasks)
+	case "task":
+		if len(cmd.Args) < 1 {
+			return fmt.Errorf("usage: task <name> [args...]")
+		}
+		return c.runTask(ctx, cmd.Args[0], cmd.Args[1:])
+	case "check":
+		return c.runChecks(ctx)
+	case "profile":
+		return c.runProfile(ctx, cmd.Args)
+	case "generate", "gen":
+		return c.generate(ctx, cmd.Args)
+	case "deps":
+		return c.showDeps(ctx, cmd.Args)
+	case "modules":
+		return c.showModules(ctx)
+	case "debug":
+		return c.debug(ctx, cmd.Args)
+	case "break", "b":
+		if len(cmd.Args) == 0 {
+			return c.renderer.RenderBreakpoints(c.output, c.project.Path(), c.debugger.Breakpoints())
+		}
+		return c.setBreakpoint(ctx, cmd.Args[0])
+	case "clear":
+		if len(cmd.Args) < 1 {
+			return fmt.Errorf("usage: clear <file:line>")
+		}
+		file, line, err := c.parseLocation(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+		if err := c.debugger.ClearBreakpoint(ctx, file, line); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.output, "✅ Cleared breakpoint at %s:%d\n", filepath.Base(file), line)
+		return nil
+	case "continue", "c":
+		return c.debugger.Resume(ctx, core.DebugContinue)
+	case "next", "n":
+		return c.debugger.Resume(ctx, core.DebugNext)
+	case "step", "s":
+		return c.debugger.Resume(ctx, core.DebugStepIn)
+	case "stepout", "out":
+		return c.debugger.Resume(ctx, core.DebugStepOut)
+	case "pause":
+		return c.debugger.Pause(ctx)
+	case "locals":
+		return c.showLocals(ctx, cmd.Args)
+	case "bt", "stack":
+		frames, err := c.debugger.StackTrace(ctx, 0)
+		if err != nil {
+			return err
+		}
+		return c.renderer.RenderStackTrace(c.output, c.project.Path(), frames)
+	case "goroutines":
+		threads, err := c.debugger.Goroutines(ctx)
+		if err != nil {
+			return err
+		}
+		return c.renderer.RenderGoroutines(c.output, threads, c.debugger.CurrentGoroutine())
+	case "problems", "diag":
+		return c.showDiagnostics()
 	case "version":
 		return c.showVersion()
 	case "exit", "quit", "q":
+		c.shutdown()
 		fmt.Fprintf(c.output, "Goodbye! Thanks for using GoX IDE 🚀\n")
 		os.Exit(0)
 		return nil
@@ -150,15 +338,92 @@ func (c *CLI) showHelp() error {
 🚀 GoX IDE Commands:
 ═══════════════════════════════════════════════════════════════
   📁 File Operations:
-    ls, list         - List files in project
+    ls, list         - List files in project, minus those ignored by
+                       .gitignore and .goxignore files
+    ls --target os/arch[,tags=a,b]
+                     - Flag files excluded by build constraints for a
+                       target (default: buildContext in .gox/config.json,
+                       else the host)
     tree             - Show project tree structure
     open, o <file>   - Open file for editing
     cat, view <file> - View file contents
+    def [file:]line:col
+                     - Go to the definition of the identifier at a
+                       position (default file: the open one)
+    symbols, sym <query>
+                     - Fuzzy-search Go declarations; "pkg.Type.Method"
+                       matches qualified names, 'open s<N>' jumps to one
     
   🔨 Build Operations:
-    run              - Run the Go project (go run .)
-    test             - Run tests (go test ./...)
+    run [config]     - Start the project or a named run configuration
+                       in the background
+    configs          - List run configurations (.gox/config.json)
+    ps               - List started processes
+    stop <id>        - Stop a process (interrupt, then kill)
+    restart <id>     - Rebuild and restart a process
+    logs <id>        - Show recent output of a process
+    watch build|test - Rebuild, or test changed packages, on file change
+    watch run [config]
+                     - Start a program and restart it on file change
+    watch [stop]     - Show or stop watch mode
+    test [pkg] [-run re] [-count n] [-v] [-race]
+                     - Run tests (default: go test ./...)
+    test --affected [-run re] ...
+                     - Test only packages affected by uncommitted
+                       changes, following reverse imports
+    tests failed     - List failing tests from the last run
+    bench [pkg] [-bench re] [-count n] [-benchtime d]
+                     - Run and store benchmarks (.gox/bench)
+    bench list       - List stored benchmark runs
+    bench compare [base] [head]
+                     - Compare runs (default: previous vs latest)
+    cover [pkg] [-run re]
+                     - Run tests with coverage; 'cat' then marks lines
     build            - Build the project (go build)
+    build --matrix[=os/arch,...] [-j n] [pkg]
+                     - Cross-compile into dist/ (targets from
+                       .gox/config.json, else linux, windows, darwin)
+    clean [--dry-run] [--cache] [--testcache]
+                     - Remove build artifacts (.gox/config.json
+                       "clean" globs); --dry-run only lists them
+    tasks            - List Makefile targets and root scripts
+    task <name> [args...]
+                     - Run a Makefile target or script
+    check            - Run build, vet and configured checks
+                       (.gox/config.json "check") concurrently
+    problems, diag   - Show diagnostics from the last build or check
+    modules          - List the workspace modules (go.work or nested
+                       go.mod); commands use the open file's module
+    deps             - List module dependencies with versions
+    deps who <module> - Show the modules requiring a module
+    deps why <module> - Show the import chain that needs a module
+    deps dot [file]  - Export the module graph as Graphviz DOT
+    generate, gen    - List go:generate directives
+    generate run [pkg | g<N>]
+                     - Run all directives, a package's, or one
+    profile cpu|mem [pkg] [-run re] [-bench re] [-n N] [-cum] [-sample type]
+                     - Profile a package's tests or benchmarks
+    profile cpu|mem run [config] [-n N] [-cum] [-sample type]
+                     - Profile a program accepting -cpuprofile or
+                       -memprofile; 'open p<N>' jumps to a function
+    
+  🐞 Debugging (Delve):
+    debug [config]   - Debug a run configuration with dlv dap
+    debug --connect host:port [config]
+                     - Debug through a running "dlv dap --listen"
+    debug stop       - End the debug session
+    break, b [file:]line
+                     - Set a breakpoint (no argument lists them)
+    clear [file:]line
+                     - Remove a breakpoint
+    continue, c      - Continue to the next breakpoint
+    next, n          - Step over
+    step, s          - Step into
+    stepout, out     - Step out of the current function
+    pause            - Pause the running program
+    locals [frame]   - Show the variables of a frame (default: top)
+    bt, stack        - Show the stack of the stopped goroutine
+    goroutines       - List goroutines
     
   ℹ️  Information:
     help, h          - Show this help
@@ -169,6 +434,8 @@ func (c *CLI) showHelp() error {
 
 💡 Navigation Tips:
   • Use file numbers from 'ls' command: open 1, cat 2
+  • Jump to build diagnostics by number: open e1
+  • Jump to symbols from 'symbols' by number: open s1
   • GoX IDE is optimized for Go development
   • Built with native Go performance in mind
 ═══════════════════════════════════════════════════════════════
@@ -177,30 +444,102 @@ func (c *CLI) showHelp() error {
 	return nil
 }
 
-func (c *CLI) listFiles() error {
-	files, err := c.project.Files()
+func (c *CLI) listFiles(ctx context.Context, args []string) error {
+	buildCtx, err := c.parseListArgs(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	opts := c.walkOptions()
+	opts.MarkGenerated = true
+	files, err := c.project.WalkFiles(ctx, opts)
 	if err != nil {
 		return err
 	}
+	buildCtx.MarkFiles(files)
 
 	c.files = files
 
 	fmt.Fprintf(c.output, "\n📁 Files in %s:\n", c.project.Name())
+	fmt.Fprintf(c.output, "🎯 Build context: %s\n", buildCtx)
 	fmt.Fprint(c.output, "─────────────────────────────────────\n")
 
+	excluded := 0
 	for i, file := range files {
 		icon := core.GetIconForLanguage(file.Language)
-		fmt.Fprintf(c.output, "  %2d. %s %s\n", i+1, icon, file.RelPath)
+		var notes []string
+		if file.Generated {
+			notes = append(notes, "generated")
+		}
+		if file.BuildExcluded {
+			notes = append(notes, "excluded")
+			excluded++
+		}
+		suffix := ""
+		if len(notes) > 0 {
+			suffix = " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintf(c.output, "  %2d. %s %s%s\n", i+1, icon, file.RelPath, suffix)
 	}
 
 	fmt.Fprint(c.output, "─────────────────────────────────────\n")
-	fmt.Fprintf(c.output, "Total: %d files\n\n", len(files))
+	if excluded > 0 {
+		fmt.Fprintf(c.output, "Total: %d files, %d excluded by build constraints\n\n", len(files), excluded)
+	} else {
+		fmt.Fprintf(c.output, "Total: %d files\n\n", len(files))
+	}
 
 	return nil
 }
 
-func (c *CLI) showTree() error {
-	tree, err := c.project.FileTree()
+// walkOptions returns the options of whole-project walks, which report
+// progress once they take more than a second
+func (c *CLI) walkOptions() core.WalkOptions {
+	opts := core.DefaultWalkOptions()
+	opts.Logger = c.logger
+	last := time.Now()
+	opts.Progress = func(p core.WalkProgress) {
+		if time.Since(last) < time.Second {
+			return
+		}
+		last = time.Now()
+		fmt.Fprintf(c.output, "⏳ Scanned %d files in %d directories (Ctrl+C to cancel)...\n", p.Files, p.Dirs)
+	}
+	return opts
+}
+
+// parseListArgs parses "ls [--target os/arch,tags=a,b]". Without a target
+// the project's configured build context, or the host's, applies.
+func (c *CLI) parseListArgs(args []string) (core.BuildContext, error) {
+	const usage = "usage: ls [--target os/arch,tags=a,b]"
+
+	var spec string
+	switch {
+	case len(args) == 0:
+		projectConfig, err := core.LoadProjectConfig(c.project.Path())
+		if err != nil {
+			return core.BuildContext{}, err
+		}
+		return projectConfig.BuildContextOrHost()
+	case len(args) == 2 && strings.TrimLeft(args[0], "-") == "target":
+		spec = args[1]
+	case len(args) == 1 && strings.HasPrefix(strings.TrimLeft(args[0], "-"), "target="):
+		_, spec, _ = strings.Cut(args[0], "=")
+	default:
+		return core.BuildContext{}, fmt.Errorf("%s", usage)
+	}
+
+	buildCtx, err := core.ParseBuildContext(spec)
+	if err != nil {
+		return core.BuildContext{}, fmt.Errorf("%v\n%s", err, usage)
+	}
+	return buildCtx, nil
+}
+
+func (c *CLI) showTree(ctx context.Context) error {
+	trees, err := core.ModuleTrees(ctx, c.project)
 	if err != nil {
 		return err
 	}
@@ -208,17 +547,71 @@ func (c *CLI) showTree() error {
 	fmt.Fprintf(c.output, "\n🌳 Project Structure: %s\n", c.project.Name())
 	fmt.Fprint(c.output, "═══════════════════════════════════════════════════════════════\n")
 
-	return c.renderer.RenderFileTree(c.output, tree)
+	for _, tree := range trees {
+		if err := c.renderer.RenderFileTree(c.output, tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// module returns the project of the module owning the open file, which
+// build and test commands operate on
+func (c *CLI) module(ctx context.Context) core.Project {
+	return c.project.ForModule(ctx, c.currentFile)
+}
+
+// showModules lists the workspace modules, marking the one commands
+// operate on
+func (c *CLI) showModules(ctx context.Context) error {
+	ws, err := c.project.Workspace(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ws.Modules) == 0 {
+		fmt.Fprint(c.output, "📦 No modules found\n")
+		return nil
+	}
+	if err := c.renderer.RenderWorkspace(c.output, ws, c.module(ctx).Path()); err != nil {
+		return err
+	}
+	fmt.Fprint(c.output, "💡 Commands operate on the module of the open file\n")
+	return nil
 }
 
-func (c *CLI) openFile(filename string) error {
+func (c *CLI) openFile(ctx context.Context, filename string) error {
+	if diag, ok := c.resolveDiagnostic(filename); ok {
+		return c.openDiagnostic(diag)
+	}
+	if sym, ok := c.resolveSymbol(filename); ok {
+		return c.openDiagnostic(core.Diagnostic{
+			File:    sym.File,
+			Line:    sym.Line,
+			Message: string(sym.Kind) + " " + sym.QualifiedName(),
+		})
+	}
+	if entry, ok := c.resolveProfileEntry(filename); ok {
+		return c.openDiagnostic(core.Diagnostic{
+			File:    entry.File,
+			Line:    int(entry.Line),
+			Message: entry.Function,
+		})
+	}
+
 	filePath, err := c.resolveFile(filename)
 	if err != nil {
 		return err
 	}
 
 	c.currentFile = filePath
+	c.currentLine = 1
 	fmt.Fprintf(c.output, "✅ Opened: %s\n", filename)
+	if module := c.module(ctx); module.Path() != c.project.Path() {
+		fmt.Fprintf(c.output, "📦 Module: %s\n", relativePath(c.project.Path(), module.Path()))
+	}
+	if core.IsGeneratedFile(filePath) {
+		fmt.Fprint(c.output, "🔒 Generated file: edit its generator instead\n")
+	}
 	fmt.Fprintf(c.output, "💡 Use 'cat %s' to view contents\n", filename)
 
 	return nil
@@ -267,19 +660,1295 @@ func (c *CLI) resolveFile(filename string) (string, error) {
 	return "", fmt.Errorf("file not found: %s", filename)
 }
 
-func (c *CLI) runProject(ctx context.Context) error {
-	fmt.Fprint(c.output, "🏃 Running Go project...\n")
-	return c.builder.Run(ctx, c.project)
+// resolveDiagnostic resolves an "e<N>" reference from the last build
+func (c *CLI) resolveDiagnostic(ref string) (core.Diagnostic, bool) {
+	numStr, ok := strings.CutPrefix(ref, "e")
+	if !ok {
+		return core.Diagnostic{}, false
+	}
+
+	num, err := strconv.Atoi(numStr)
+	if err != nil || num < 1 || num > len(c.diagnostics) {
+		return core.Diagnostic{}, false
+	}
+
+	return c.diagnostics[num-1], true
+}
+
+// resolveProfileEntry resolves a "p<N>" reference from the last profile
+func (c *CLI) resolveProfileEntry(ref string) (core.ProfileEntry, bool) {
+	numStr, ok := strings.CutPrefix(ref, "p")
+	if !ok {
+		return core.ProfileEntry{}, false
+	}
+
+	num, err := strconv.Atoi(numStr)
+	if err != nil || num < 1 || num > len(c.profile) || c.profile[num-1].File == "" {
+		return core.ProfileEntry{}, false
+	}
+
+	return c.profile[num-1], true
 }
 
-func (c *CLI) runTests(ctx context.Context) error {
-	fmt.Fprint(c.output, "🧪 Running Go tests...\n")
-	return c.builder.Test(ctx, c.project)
+// resolveSymbol resolves an "s<N>" reference from the last symbol search
+func (c *CLI) resolveSymbol(ref string) (core.Symbol, bool) {
+	numStr, ok := strings.CutPrefix(ref, "s")
+	if !ok {
+		return core.Symbol{}, false
+	}
+
+	num, err := strconv.Atoi(numStr)
+	if err != nil || num < 1 || num > len(c.symbolHits) {
+		return core.Symbol{}, false
+	}
+
+	return c.symbolHits[num-1].Symbol, true
 }
 
-func (c *CLI) buildProject(ctx context.Context) error {
-	fmt.Fprint(c.output, "🔨 Building Go project...\n")
-	return c.builder.Build(ctx, c.project)
+// openDiagnostic opens the file of a diagnostic and shows the offending line
+func (c *CLI) openDiagnostic(diag core.Diagnostic) error {
+	content, err := os.ReadFile(diag.File)
+	if err != nil {
+		return err
+	}
+
+	c.currentFile = diag.File
+	c.currentLine = diag.Line
+
+	rel := relativePath(c.project.Path(), diag.File)
+	fmt.Fprintf(c.output, "✅ Opened: %s:%d\n", rel, diag.Line)
+	fmt.Fprintf(c.output, "   %s\n", diag.Message)
+
+	// Show a few lines of context around the diagnostic
+	lines := strings.Split(string(content), "\n")
+	start := max(diag.Line-3, 1)
+	end := min(diag.Line+2, len(lines))
+
+	fmt.Fprint(c.output, "─────────────────────────────────────\n")
+	for i := start; i <= end; i++ {
+		marker := " "
+		if i == diag.Line {
+			marker = ">"
+		}
+		fmt.Fprintf(c.output, "%s%4d │ %s\n", marker, i, lines[i-1])
+	}
+	fmt.Fprint(c.output, "─────────────────────────────────────\n")
+
+	return nil
+}
+
+// maxSymbolResults bounds the matches listed by 'symbols'
+const maxSymbolResults = 30
+
+// searchSymbols brings the symbol index up to date, re-parsing only files
+// changed since the last search, and lists the best matches
+func (c *CLI) searchSymbols(ctx context.Context, query string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	if err := c.symbols.Update(ctx, c.walkOptions()); err != nil {
+		return err
+	}
+
+	c.symbolHits = c.symbols.Search(query, maxSymbolResults)
+	if len(c.symbolHits) == 0 {
+		fmt.Fprintf(c.output, "🔎 No symbols match %q\n", query)
+		return nil
+	}
+	if err := c.renderer.RenderSymbols(c.output, c.project.Path(), c.symbolHits); err != nil {
+		return err
+	}
+	fmt.Fprint(c.output, "💡 Use 'open s<N>' to jump to a symbol\n")
+	return nil
+}
+
+func (c *CLI) showDiagnostics() error {
+	if len(c.diagnostics) == 0 {
+		fmt.Fprint(c.output, "✅ No diagnostics\n")
+		return nil
+	}
+	return c.renderer.RenderDiagnostics(c.output, c.project.Path(), c.diagnostics)
+}
+
+// startProject starts a run configuration in the background
+func (c *CLI) startProject(ctx context.Context, name string) (*core.Process, error) {
+	config, err := c.resolveRunConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.output, "🏃 Starting %s (%s)...\n", config.Name, config.PackageOrDefault())
+	p, err := c.processes.Start(ctx, c.module(ctx), config, c.sink)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.output, "▶️ [%d] %s started (pid %d)\n", p.ID, config.Name, p.PID)
+	return p, nil
+}
+
+func (c *CLI) restartProcess(ctx context.Context, id int) error {
+	fmt.Fprintf(c.output, "🔄 Restarting [%d]...\n", id)
+	p, err := c.processes.Restart(ctx, id, c.sink)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output, "▶️ [%d] %s started (pid %d)\n", p.ID, p.Config.Name, p.PID)
+	return nil
+}
+
+// reportExit announces a finished background process. It is called from
+// the process goroutine, so it writes through the synchronized sink.
+func (c *CLI) reportExit(p *core.Process) {
+	var msg string
+	switch {
+	case p.State() == core.ProcessStopped:
+		msg = fmt.Sprintf("⏹️ [%d] %s stopped", p.ID, p.Config.Name)
+	case p.ExitCode() == 0:
+		msg = fmt.Sprintf("✅ [%d] %s exited", p.ID, p.Config.Name)
+	default:
+		msg = fmt.Sprintf("❌ [%d] %s exited: %v", p.ID, p.Config.Name, p.Err())
+	}
+	c.sink.WriteLine(core.OutputLine{Text: msg, Time: time.Now()})
+}
+
+// shutdown stops everything started from the CLI before it exits
+func (c *CLI) shutdown() {
+	c.processes.StopAll()
+	if c.debugger.State() != core.DebugIdle {
+		c.debugger.Stop()
+	}
+}
+
+// reportDebugEvent prints where the debugged program stopped or that the
+// session ended. It is called from a background goroutine.
+func (c *CLI) reportDebugEvent(ev core.DebugEvent) {
+	var msg string
+	switch ev.Kind {
+	case core.DebugEventStopped:
+		msg = fmt.Sprintf("⏸️ Goroutine %d stopped (%s)", ev.ThreadID, ev.Reason)
+		if f := ev.Frame; f != nil && f.File != "" {
+			msg = fmt.Sprintf("⏸️ Stopped at %s:%d in %s (%s)", relativePath(c.project.Path(), f.File), f.Line, f.Name, ev.Reason)
+			if source, ok := sourceLine(f.File, f.Line); ok {
+				msg += fmt.Sprintf("\n  %4d │ %s", f.Line, source)
+			}
+		}
+	case core.DebugEventExited:
+		msg = fmt.Sprintf("🐞 Debug session ended (exit code %d)", ev.ExitCode)
+	default:
+		return
+	}
+	c.sink.WriteLine(core.OutputLine{Text: msg, Time: time.Now()})
+}
+
+// debug starts or stops a debug session
+func (c *CLI) debug(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "stop" {
+		return c.debugger.Stop()
+	}
+
+	const usage = "usage: debug [--connect host:port] [config]"
+	var addr, name string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--connect":
+			if i+1 >= len(args) {
+				return fmt.Errorf(usage)
+			}
+			i++
+			addr = args[i]
+		case strings.HasPrefix(arg, "--connect="):
+			addr = strings.TrimPrefix(arg, "--connect=")
+		case strings.HasPrefix(arg, "-") || name != "":
+			return fmt.Errorf(usage)
+		default:
+			name = arg
+		}
+	}
+
+	config, err := c.resolveRunConfig(name)
+	if err != nil {
+		return err
+	}
+
+	if addr != "" {
+		fmt.Fprintf(c.output, "🐞 Debugging %s via %s...\n", config.Name, addr)
+		err = c.debugger.Connect(ctx, addr, c.module(ctx), config, c.sink)
+	} else {
+		fmt.Fprintf(c.output, "🐞 Debugging %s...\n", config.Name)
+		err = c.debugger.Launch(ctx, c.module(ctx), config, c.sink)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output, "🐞 %s is running with %d breakpoints\n", config.Name, len(c.debugger.Breakpoints()))
+	return nil
+}
+
+// setBreakpoint sets a breakpoint at a [file:]line location
+func (c *CLI) setBreakpoint(ctx context.Context, location string) error {
+	file, line, err := c.parseLocation(location)
+	if err != nil {
+		return err
+	}
+
+	bp, err := c.debugger.SetBreakpoint(ctx, file, line)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.debugger.State() == core.DebugIdle:
+		fmt.Fprintf(c.output, "🔴 Breakpoint at %s:%d (set when debugging starts)\n", relativePath(c.project.Path(), file), line)
+	case bp.Verified:
+		fmt.Fprintf(c.output, "🔴 Breakpoint at %s:%d\n", relativePath(c.project.Path(), file), bp.Line)
+	default:
+		fmt.Fprintf(c.output, "⚠️ Breakpoint at %s:%d not verified: %s\n", relativePath(c.project.Path(), file), line, bp.Message)
+	}
+	return nil
+}
+
+// parseLocation resolves "file:line", or "line" in the open file, to an
+// absolute path and line
+func (c *CLI) parseLocation(location string) (string, int, error) {
+	file, lineStr := c.currentFile, location
+	if i := strings.LastIndex(location, ":"); i >= 0 {
+		file, lineStr = location[:i], location[i+1:]
+	}
+
+	line, err := strconv.Atoi(lineStr)
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("invalid location %q (want file:line)", location)
+	}
+	if file == "" {
+		return "", 0, fmt.Errorf("no open file; use file:line")
+	}
+
+	if file != c.currentFile && !filepath.IsAbs(file) {
+		path := filepath.Join(c.project.Path(), file)
+		if _, err := os.Stat(path); err == nil {
+			file = path
+		} else if file, err = c.resolveFile(file); err != nil {
+			return "", 0, err
+		}
+	}
+	return file, line, nil
+}
+
+// goToDefinition resolves the identifier at "[file:]line:col" with
+// go/types and opens its declaration
+func (c *CLI) goToDefinition(ctx context.Context, location string) error {
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return fmt.Errorf("invalid location %q (want file:line:col)", location)
+	}
+	col, err := strconv.Atoi(location[i+1:])
+	if err != nil || col < 1 {
+		return fmt.Errorf("invalid location %q (want file:line:col)", location)
+	}
+	file, line, err := c.parseLocation(location[:i])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.output, "🔍 Type-checking...\n")
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	def, err := core.FindDefinition(ctx, file, nil, line, col)
+	if err != nil {
+		return err
+	}
+
+	return c.openDiagnostic(core.Diagnostic{
+		File:    def.File,
+		Line:    def.Line,
+		Column:  def.Column,
+		Message: def.Kind + " " + def.QualifiedName(),
+	})
+}
+
+// showLocals renders the variables of a frame of the stopped goroutine
+func (c *CLI) showLocals(ctx context.Context, args []string) error {
+	frameID := 0
+	if len(args) > 0 {
+		// Frames are numbered as in "bt"
+		index, err := strconv.Atoi(args[0])
+		if err != nil || index < 0 {
+			return fmt.Errorf("usage: locals [frame]")
+		}
+		frames, err := c.debugger.StackTrace(ctx, 0)
+		if err != nil {
+			return err
+		}
+		if index >= len(frames) {
+			return fmt.Errorf("no frame %d (type 'bt' to list them)", index)
+		}
+		frameID = frames[index].ID
+	}
+
+	vars, err := c.debugger.Locals(ctx, frameID)
+	if err != nil {
+		return err
+	}
+	return c.renderer.RenderVariables(c.output, vars)
+}
+
+// sourceLine returns a trimmed line of a source file
+func sourceLine(file string, line int) (string, bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+	return strings.TrimSpace(lines[line-1]), true
+}
+
+// watch starts, stops or reports watch mode
+func (c *CLI) watch(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		if c.watchCancel == nil {
+			fmt.Fprint(c.output, "👀 Watch mode is off\n")
+		} else {
+			fmt.Fprintf(c.output, "👀 Watching for changes (%s)\n", c.watchAction)
+		}
+		return nil
+	}
+
+	if args[0] == "stop" {
+		if c.watchCancel == nil {
+			return fmt.Errorf("watch mode is not running")
+		}
+		c.stopWatch()
+		fmt.Fprint(c.output, "👀 Watch mode stopped\n")
+		return nil
+	}
+
+	action, ok := core.ParseWatchAction(args[0])
+	if !ok || (action != core.WatchRun && len(args) > 1) || len(args) > 2 {
+		return fmt.Errorf("usage: watch build|test|run [config] or watch stop")
+	}
+
+	c.stopWatch()
+
+	// Watching a run starts the program right away
+	if action == core.WatchRun {
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		p, err := c.startProject(ctx, name)
+		if err != nil {
+			return err
+		}
+		c.watchProcess = p.ID
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	c.watchCancel = cancel
+	c.watchAction = action
+
+	watcher := core.NewWatcher(c.project, c.logger)
+	go watcher.Watch(watchCtx, func(changed []string) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// Watch mode may have been stopped while waiting for the lock
+		if watchCtx.Err() != nil {
+			return
+		}
+		c.runWatchAction(watchCtx, action, changed)
+		fmt.Fprint(c.output, "gox> ")
+	})
+
+	fmt.Fprintf(c.output, "👀 Watching for changes (%s); 'watch stop' to end\n", action)
+	return nil
+}
+
+// stopWatch stops watch mode if it is running
+func (c *CLI) stopWatch() {
+	if c.watchCancel != nil {
+		c.watchCancel()
+		c.watchCancel = nil
+		c.watchAction = ""
+	}
+}
+
+// runWatchAction runs the watch action for a set of changed files.
+// The caller must hold c.mu.
+func (c *CLI) runWatchAction(ctx context.Context, action core.WatchAction, changed []string) {
+	c.project.FilesChanged(changed)
+
+	const maxNames = 3
+	var names []string
+	for i, path := range changed {
+		if i == maxNames {
+			names = append(names, fmt.Sprintf("+%d more", len(changed)-i))
+			break
+		}
+		if rel, err := filepath.Rel(c.project.Path(), path); err == nil {
+			path = rel
+		}
+		names = append(names, path)
+	}
+	fmt.Fprintf(c.output, "\n👀 Changed: %s\n", strings.Join(names, ", "))
+
+	var err error
+	switch action {
+	case core.WatchBuild:
+		err = c.buildProject(ctx)
+	case core.WatchTest:
+		err = c.runAffectedTests(ctx, core.TestOptions{}, changed)
+	case core.WatchRun:
+		err = c.restartProcess(ctx, c.watchProcess)
+	}
+
+	if err != nil {
+		fmt.Fprintf(c.output, "❌ Error: %v\n", err)
+	}
+}
+
+// parseProcessID parses the process ID argument of a process command
+func parseProcessID(args []string, command string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("usage: %s <id>", command)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid process id: %s", args[0])
+	}
+	return id, nil
+}
+
+// resolveRunConfig returns the named run configuration, or the first
+// configured one (falling back to "go run .") if name is empty
+func (c *CLI) resolveRunConfig(name string) (core.RunConfig, error) {
+	projectConfig, err := core.LoadProjectConfig(c.project.Path())
+	if err != nil {
+		return core.RunConfig{}, err
+	}
+
+	if name == "" {
+		return projectConfig.RunConfigsOrDefault()[0], nil
+	}
+
+	config, ok := projectConfig.RunConfig(name)
+	if !ok {
+		return core.RunConfig{}, fmt.Errorf("unknown run configuration: %s (type 'configs' to list them)", name)
+	}
+	return config, nil
+}
+
+func (c *CLI) listRunConfigs() error {
+	projectConfig, err := core.LoadProjectConfig(c.project.Path())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.output, "\n⚙️ Run configurations:\n")
+	fmt.Fprint(c.output, "─────────────────────────────────────\n")
+
+	for _, config := range projectConfig.RunConfigsOrDefault() {
+		fmt.Fprintf(c.output, "  %-16s %s", config.Name, config.PackageOrDefault())
+		if len(config.Args) > 0 {
+			fmt.Fprintf(c.output, " %s", strings.Join(config.Args, " "))
+		}
+		if len(config.Tags) > 0 {
+			fmt.Fprintf(c.output, "  [tags: %s]", strings.Join(config.Tags, ","))
+		}
+		if config.WorkDir != "" {
+			fmt.Fprintf(c.output, "  (in %s)", config.WorkDir)
+		}
+		fmt.Fprintln(c.output)
+	}
+
+	fmt.Fprint(c.output, "─────────────────────────────────────\n")
+	if len(projectConfig.RunConfigs) == 0 {
+		fmt.Fprintf(c.output, "💡 Add configurations under \"run\" in %s\n",
+			filepath.Join(core.ProjectDataDir, core.ProjectConfigFile))
+	}
+
+	return nil
+}
+
+func (c *CLI) runTests(ctx context.Context, opts core.TestOptions) error {
+	fmt.Fprintf(c.output, "🧪 Running Go tests (%s)...\n", opts.PackagePattern())
+
+	// Only stderr is streamed; test output is summarized in the report
+	sink := core.OutputSinkFunc(func(line core.OutputLine) {
+		if line.Stream == core.StreamStderr {
+			c.sink.WriteLine(line)
+		}
+	})
+
+	report, err := c.builder.Test(ctx, c.module(ctx), opts, sink)
+	if report == nil {
+		return err
+	}
+	c.testReport = report
+
+	if renderErr := c.renderer.RenderTestReport(c.output, report); renderErr != nil {
+		return renderErr
+	}
+
+	// A failing run is fully described by the report
+	if !report.Passed() {
+		fmt.Fprint(c.output, "💡 Use 'tests failed' to list only failing tests\n")
+		return nil
+	}
+	return err
+}
+
+func (c *CLI) runBench(ctx context.Context, args []string) error {
+	store := core.NewBenchStore(c.project.Path())
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			runs, err := store.List()
+			if err != nil {
+				return err
+			}
+			if len(runs) == 0 {
+				fmt.Fprint(c.output, "No stored benchmark runs. Run 'bench' first\n")
+				return nil
+			}
+			return c.renderer.RenderBenchRuns(c.output, runs)
+		case "compare":
+			return c.compareBench(store, args[1:])
+		}
+	}
+
+	opts, err := parseBenchArgs(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(c.output, "⏱️ Running benchmarks...\n")
+
+	// Only stderr is streamed; results are rendered as a table
+	sink := core.OutputSinkFunc(func(line core.OutputLine) {
+		if line.Stream == core.StreamStderr {
+			c.sink.WriteLine(line)
+		}
+	})
+
+	run, err := c.builder.Bench(ctx, c.module(ctx), opts, sink)
+	if run == nil {
+		return err
+	}
+	if len(run.Results) == 0 {
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(c.output, "No benchmarks matched\n")
+		return nil
+	}
+
+	if saveErr := store.Save(run); saveErr != nil {
+		return saveErr
+	}
+	if renderErr := c.renderer.RenderBenchRun(c.output, run); renderErr != nil {
+		return renderErr
+	}
+	fmt.Fprint(c.output, "💡 Use 'bench compare' to compare with the previous run\n")
+
+	return err
+}
+
+// compareBench compares two stored runs; by default the latest run is
+// compared with the one before it
+func (c *CLI) compareBench(store *core.BenchStore, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: bench compare [base] [head]")
+	}
+
+	runs, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	var base, head *core.BenchRun
+	switch len(args) {
+	case 0:
+		if len(runs) < 2 {
+			return fmt.Errorf("need at least two stored runs to compare (have %d)", len(runs))
+		}
+		base, head = runs[len(runs)-2], runs[len(runs)-1]
+	case 1:
+		if len(runs) == 0 {
+			return fmt.Errorf("no stored benchmark runs. Run 'bench' first")
+		}
+		if base, err = store.Load(args[0]); err != nil {
+			return err
+		}
+		head = runs[len(runs)-1]
+	case 2:
+		if base, err = store.Load(args[0]); err != nil {
+			return err
+		}
+		if head, err = store.Load(args[1]); err != nil {
+			return err
+		}
+	}
+
+	return c.renderer.RenderBenchComparison(c.output, base, head, core.CompareBenchRuns(base, head))
+}
+
+// parseBenchArgs parses "[pkg] [-bench re] [-count n] [-benchtime d]"
+func parseBenchArgs(args []string) (core.BenchOptions, error) {
+	var opts core.BenchOptions
+	const usage = "usage: bench [pkg] [-bench regexp] [-count n] [-benchtime d]"
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if opts.Package != "" {
+				return opts, fmt.Errorf("only one package may be given\n%s", usage)
+			}
+			opts.Package = arg
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("flag -%s needs a value\n%s", name, usage)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "bench":
+			opts.Bench = value
+		case "count":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("invalid -count %q\n%s", value, usage)
+			}
+			opts.Count = n
+		case "benchtime":
+			opts.Benchtime = value
+		default:
+			return opts, fmt.Errorf("unknown flag %s\n%s", arg, usage)
+		}
+	}
+
+	return opts, nil
+}
+
+// parseTestArgs parses "[pkg] [-run re] [-count n] [-v] [-race]"
+func parseTestArgs(args []string) (core.TestOptions, error) {
+	var opts core.TestOptions
+	const usage = "usage: test [pkg] [-run regexp] [-count n] [-v] [-race]"
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		// Flags taking a value accept both "-flag value" and "-flag=value"
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("flag -%s needs a value\n%s", name, usage)
+			}
+			i++
+			return args[i], nil
+		}
+
+		if !strings.HasPrefix(arg, "-") {
+			if opts.Package != "" {
+				return opts, fmt.Errorf("only one package may be given\n%s", usage)
+			}
+			opts.Package = arg
+			continue
+		}
+
+		switch name {
+		case "run":
+			v, err := takeValue()
+			if err != nil {
+				return opts, err
+			}
+			opts.Run = v
+		case "count":
+			v, err := takeValue()
+			if err != nil {
+				return opts, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("invalid -count %q\n%s", v, usage)
+			}
+			opts.Count = n
+		case "v", "race":
+			enabled := true
+			if hasValue {
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return opts, fmt.Errorf("invalid boolean value %q for -%s\n%s", value, name, usage)
+				}
+				enabled = b
+			}
+			if name == "v" {
+				opts.Verbose = enabled
+			} else {
+				opts.Race = enabled
+			}
+		default:
+			return opts, fmt.Errorf("unknown flag %s\n%s", arg, usage)
+		}
+	}
+
+	return opts, nil
+}
+
+// runAffectedTests tests the packages affected by changed files, or by
+// the files changed since the last git commit if changed is nil
+func (c *CLI) runAffectedTests(ctx context.Context, opts core.TestOptions, changed []string) error {
+	if opts.Package != "" || len(opts.Packages) > 0 {
+		return fmt.Errorf("--affected selects the packages itself; remove %s", opts.PackagePattern())
+	}
+
+	if changed == nil {
+		var err error
+		if changed, err = core.ChangedFiles(ctx, c.project.Path()); err != nil {
+			return err
+		}
+	}
+
+	affected, ok, err := core.AffectedTestOptions(ctx, c.module(ctx).Path(), changed)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintf(c.output, "✅ No packages affected by %d changed files\n", len(changed))
+		return nil
+	}
+
+	opts.Packages = affected.Packages
+	fmt.Fprintf(c.output, "🎯 %d packages affected by %d changed files\n",
+		len(opts.Packages), len(changed))
+	return c.runTests(ctx, opts)
+}
+
+// cutFlag removes a boolean flag from args and reports whether it was present
+func cutFlag(args []string, flag string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+func (c *CLI) runCoverage(ctx context.Context, opts core.TestOptions) error {
+	fmt.Fprintf(c.output, "📈 Running Go tests with coverage (%s)...\n", opts.PackagePattern())
+
+	// Only stderr is streamed; test output is summarized in the report
+	sink := core.OutputSinkFunc(func(line core.OutputLine) {
+		if line.Stream == core.StreamStderr {
+			c.sink.WriteLine(line)
+		}
+	})
+
+	report, err := c.builder.Cover(ctx, c.module(ctx), opts, sink)
+	if report == nil {
+		return err
+	}
+	c.testReport = report.Tests
+	c.renderer.SetCoverage(report)
+
+	if report.Tests != nil && !report.Tests.Passed() {
+		if renderErr := c.renderer.RenderTestFailures(c.output, report.Tests); renderErr != nil {
+			return renderErr
+		}
+	}
+
+	if renderErr := c.renderer.RenderCoverage(c.output, report); renderErr != nil {
+		return renderErr
+	}
+	fmt.Fprint(c.output, "💡 Use 'cat <file>' to see covered (✓) and uncovered (✗) lines\n")
+
+	return err
+}
+
+func (c *CLI) showFailedTests() error {
+	if c.testReport == nil {
+		return fmt.Errorf("no test results yet. Run 'test' first")
+	}
+	return c.renderer.RenderTestFailures(c.output, c.testReport)
+}
+
+func (c *CLI) buildProject(ctx context.Context) error {
+	fmt.Fprint(c.output, "🔨 Building Go project...\n")
+
+	// Compiler errors are rendered as a numbered list below instead
+	sink := core.OutputSinkFunc(func(line core.OutputLine) {
+		if line.Stream == core.StreamStderr && isDiagnosticOutput(line.Text) {
+			return
+		}
+		c.sink.WriteLine(line)
+	})
+
+	diags, err := c.builder.Build(ctx, c.module(ctx), sink)
+	c.diagnostics = diags
+
+	if len(diags) > 0 {
+		if renderErr := c.renderer.RenderDiagnostics(c.output, c.project.Path(), diags); renderErr != nil {
+			return renderErr
+		}
+		fmt.Fprint(c.output, "💡 Use 'open e<N>' to jump to a diagnostic\n")
+	}
+
+	if err == nil {
+		fmt.Fprint(c.output, "✅ Build successful\n")
+	}
+
+	return err
+}
+
+func (c *CLI) cleanProject(ctx context.Context, args []string) error {
+	args, dryRun := cutFlag(args, "--dry-run")
+	args, cache := cutFlag(args, "--cache")
+	args, testCache := cutFlag(args, "--testcache")
+	if len(args) > 0 {
+		return fmt.Errorf("usage: clean [--dry-run] [--cache] [--testcache]")
+	}
+
+	projectConfig, err := core.LoadProjectConfig(c.project.Path())
+	if err != nil {
+		return err
+	}
+	config := projectConfig.CleanConfigOrDefault(c.project)
+	config.Cache = config.Cache || cache
+	config.TestCache = config.TestCache || testCache
+
+	if dryRun {
+		plan, err := c.builder.PlanClean(ctx, c.project, config)
+		if err != nil {
+			return err
+		}
+		return c.renderer.RenderCleanPlan(c.output, c.project.Path(), plan, true)
+	}
+
+	fmt.Fprint(c.output, "🧹 Cleaning...\n")
+	plan, err := c.builder.Clean(ctx, c.project, config, c.sink)
+	if plan == nil {
+		return err
+	}
+	if renderErr := c.renderer.RenderCleanPlan(c.output, c.project.Path(), plan, false); renderErr != nil {
+		return renderErr
+	}
+	if err == nil {
+		fmt.Fprint(c.output, "✅ Clean successful\n")
+	}
+	return err
+}
+
+// showDeps lists, explains or exports the module dependencies
+func (c *CLI) showDeps(ctx context.Context, args []string) error {
+	const usage = "usage: deps [who <module> | why <module> | dot [file]]"
+	if !c.project.IsGoProject() {
+		return core.ErrNotGoProject
+	}
+
+	sub := "list"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch {
+	case sub == "list" && len(args) > 1,
+		(sub == "who" || sub == "why") && len(args) != 2,
+		sub == "dot" && len(args) > 2:
+		return fmt.Errorf(usage)
+	case sub == "why":
+		// go mod why does not need the graph
+		why, err := core.WhyModule(ctx, c.module(ctx).Path(), args[1])
+		if err != nil {
+			return err
+		}
+		return c.renderer.RenderModuleWhy(c.output, why)
+	}
+
+	graph, err := core.LoadModuleGraph(ctx, c.module(ctx).Path())
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "list":
+		return c.renderer.RenderModules(c.output, graph)
+	case "who":
+		path := args[1]
+		if _, ok := graph.Module(path); !ok {
+			return fmt.Errorf("%w: %s", core.ErrModuleNotFound, path)
+		}
+		return c.renderer.RenderModuleRequirements(c.output, graph, path, graph.RequiredBy(path))
+	case "dot":
+		if len(args) == 1 {
+			return graph.WriteDOT(c.output)
+		}
+
+		path := args[1]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.project.Path(), path)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := graph.WriteDOT(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.output, "✅ Wrote %d requirements to %s\n", len(graph.Requirements), args[1])
+		return nil
+	}
+	return fmt.Errorf(usage)
+}
+
+// generate lists or runs go:generate directives
+func (c *CLI) generate(ctx context.Context, args []string) error {
+	const usage = "usage: generate [run [pkg | g<N>]]"
+
+	walkCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	directives, err := core.FindGenerateDirectives(walkCtx, c.project, c.walkOptions())
+	stop()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 || args[0] == "list" {
+		if len(args) > 1 {
+			return fmt.Errorf(usage)
+		}
+		if len(directives) == 0 {
+			fmt.Fprint(c.output, "⚙️ No go:generate directives found\n")
+			return nil
+		}
+		if err := c.renderer.RenderGenerateDirectives(c.output, directives); err != nil {
+			return err
+		}
+		fmt.Fprint(c.output, "💡 Use 'generate run g<N>' to run one directive\n")
+		return nil
+	}
+
+	if args[0] != "run" || len(args) > 2 {
+		return fmt.Errorf(usage)
+	}
+
+	var opts core.GenerateOptions
+	target := "all packages"
+	if len(args) == 2 {
+		if numStr, ok := strings.CutPrefix(args[1], "g"); ok {
+			num, err := strconv.Atoi(numStr)
+			if err != nil || num < 1 || num > len(directives) {
+				return fmt.Errorf("no directive %s (type 'generate' to list them)", args[1])
+			}
+			opts.Directive = &directives[num-1]
+			target = fmt.Sprintf("%s:%d", filepath.ToSlash(opts.Directive.RelPath), opts.Directive.Line)
+		} else {
+			opts.Package = args[1]
+			target = args[1]
+		}
+	}
+
+	fmt.Fprintf(c.output, "⚙️ Running go generate for %s...\n", target)
+	if err := c.builder.Generate(ctx, c.module(ctx), opts, c.sink); err != nil {
+		return err
+	}
+	fmt.Fprint(c.output, "✅ Generate complete\n")
+	return nil
+}
+
+func (c *CLI) runTask(ctx context.Context, name string, args []string) error {
+	tasks, err := core.DiscoverTasks(c.project.Path())
+	if err != nil {
+		return err
+	}
+	task, err := core.FindTask(tasks, name)
+	if err != nil {
+		return fmt.Errorf("%w (type 'tasks' to list them)", err)
+	}
+
+	fmt.Fprintf(c.output, "📋 Running task %s...\n", task.Name)
+	if err := c.builder.RunTask(ctx, c.project, task, args, c.sink); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output, "✅ Task %s completed\n", task.Name)
+	return nil
+}
+
+func (c *CLI) runChecks(ctx context.Context) error {
+	projectConfig, err := core.LoadProjectConfig(c.project.Path())
+	if err != nil {
+		return err
+	}
+	config := projectConfig.CheckConfigOrDefault()
+
+	fmt.Fprintf(c.output, "🔍 Running %d checks...\n", len(config.Steps()))
+
+	// Findings are rendered as one merged list below instead
+	sink := core.OutputSinkFunc(func(line core.OutputLine) {
+		if _, text, ok := strings.Cut(line.Text, "] "); ok && isDiagnosticOutput(text) {
+			return
+		}
+		c.sink.WriteLine(line)
+	})
+
+	report, err := c.builder.Check(ctx, c.module(ctx), config, sink)
+	if report == nil {
+		return err
+	}
+	c.diagnostics = report.Diagnostics
+
+	if renderErr := c.renderer.RenderCheckReport(c.output, report); renderErr != nil {
+		return renderErr
+	}
+	if len(report.Diagnostics) > 0 {
+		if renderErr := c.renderer.RenderDiagnostics(c.output, c.project.Path(), report.Diagnostics); renderErr != nil {
+			return renderErr
+		}
+		fmt.Fprint(c.output, "💡 Use 'open e<N>' to jump to a finding\n")
+	}
+
+	if err == nil {
+		fmt.Fprint(c.output, "✅ All checks passed\n")
+	}
+	return err
+}
+
+// profileViewOptions controls how a profile is shown
+type profileViewOptions struct {
+	top        int
+	cumulative bool
+	sampleType string
+}
+
+func (c *CLI) runProfile(ctx context.Context, args []string) error {
+	opts, view, err := parseProfileArgs(args)
+	if err != nil {
+		return err
+	}
+
+	target := opts.Test.Package
+	if opts.Run != nil {
+		config, err := c.resolveRunConfig(opts.Run.Name)
+		if err != nil {
+			return err
+		}
+		opts.Run = &config
+		target = config.Name
+	} else if target == "" {
+		target = "."
+	}
+	fmt.Fprintf(c.output, "🔥 Profiling %s (%s)...\n", target, opts.Kind)
+
+	profile, err := c.builder.Profile(ctx, c.module(ctx), opts, c.sink)
+	if profile == nil {
+		return err
+	}
+
+	if view.sampleType == "" {
+		view.sampleType = opts.Kind.DefaultSampleType()
+	}
+	index, indexErr := profile.SampleIndex(view.sampleType)
+	if indexErr != nil {
+		return errors.Join(err, indexErr)
+	}
+
+	report := profile.Summarize(index)
+	c.profile = report.Top(view.top, view.cumulative)
+	if renderErr := c.renderer.RenderProfile(c.output, c.project.Path(), report, c.profile); renderErr != nil {
+		return renderErr
+	}
+	if len(c.profile) > 0 {
+		fmt.Fprint(c.output, "💡 Use 'open p<N>' to jump to a function\n")
+	}
+
+	return err
+}
+
+// parseProfileArgs parses the arguments of "profile"
+func parseProfileArgs(args []string) (core.ProfileOptions, profileViewOptions, error) {
+	const usage = "usage: profile cpu|mem [pkg | run [config]] [-run re] [-bench re] [-n N] [-cum] [-sample type]"
+	view := profileViewOptions{top: 15}
+	var opts core.ProfileOptions
+
+	if len(args) == 0 {
+		return opts, view, fmt.Errorf(usage)
+	}
+	kind, err := core.ParseProfileKind(args[0])
+	if err != nil {
+		return opts, view, fmt.Errorf("%v\n%s", err, usage)
+	}
+	opts.Kind = kind
+
+	// Take the viewing flags out; the rest select what to profile
+	var rest []string
+	for i := 1; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		switch name {
+		case "n", "sample", "bench":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return opts, view, fmt.Errorf("flag -%s needs a value\n%s", name, usage)
+				}
+				i++
+				value = args[i]
+			}
+			switch name {
+			case "n":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return opts, view, fmt.Errorf("invalid -n %q\n%s", value, usage)
+				}
+				view.top = n
+			case "sample":
+				view.sampleType = value
+			case "bench":
+				opts.Bench = value
+			}
+		case "cum":
+			view.cumulative = true
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	if len(rest) > 0 && rest[0] == "run" {
+		if len(rest) > 2 || opts.Bench != "" {
+			return opts, view, fmt.Errorf(usage)
+		}
+		// The configuration is resolved by the caller
+		opts.Run = &core.RunConfig{}
+		if len(rest) == 2 {
+			opts.Run.Name = rest[1]
+		}
+		return opts, view, nil
+	}
+
+	test, err := parseTestArgs(rest)
+	if err != nil {
+		return opts, view, err
+	}
+	opts.Test = test
+	return opts, view, nil
+}
+
+func (c *CLI) buildMatrix(ctx context.Context, opts core.MatrixOptions) error {
+	if len(opts.Targets) == 0 {
+		projectConfig, err := core.LoadProjectConfig(c.project.Path())
+		if err != nil {
+			return err
+		}
+		if opts.Targets, err = projectConfig.MatrixTargets(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(c.output, "🔨 Building %d targets...\n", len(opts.Targets))
+	results, err := c.builder.BuildMatrix(ctx, c.module(ctx), opts, c.sink)
+	if results == nil {
+		return err
+	}
+
+	if renderErr := c.renderer.RenderMatrixResults(c.output, c.project.Path(), results); renderErr != nil {
+		return renderErr
+	}
+	if err == nil {
+		fmt.Fprint(c.output, "✅ Matrix build successful\n")
+	}
+	return err
+}
+
+// parseMatrixArgs parses the arguments of "build --matrix"
+func parseMatrixArgs(args []string) (core.MatrixOptions, error) {
+	var opts core.MatrixOptions
+	const usage = "usage: build --matrix[=os/arch,...] [-j n] [-tags t,...] [-ldflags f] [pkg]"
+
+	matrix := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if opts.Package != "" {
+				return opts, fmt.Errorf("only one package may be given\n%s", usage)
+			}
+			opts.Package = arg
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name == "matrix" {
+			matrix = true
+			if hasValue {
+				targets, err := core.ParseBuildTargets(value)
+				if err != nil {
+					return opts, fmt.Errorf("%v\n%s", err, usage)
+				}
+				opts.Targets = targets
+			}
+			continue
+		}
+
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("flag -%s needs a value\n%s", name, usage)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "j":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("invalid -j %q\n%s", value, usage)
+			}
+			opts.Parallel = n
+		case "tags":
+			opts.Tags = strings.Split(value, ",")
+		case "ldflags":
+			opts.LDFlags = value
+		default:
+			return opts, fmt.Errorf("unknown flag %s\n%s", arg, usage)
+		}
+	}
+
+	if !matrix {
+		return opts, fmt.Errorf("%s", usage)
+	}
+	return opts, nil
+}
+
+// isDiagnosticOutput reports whether a line of build output is part of a
+// compiler diagnostic (package header, diagnostic or continuation line)
+func isDiagnosticOutput(text string) bool {
+	if strings.HasPrefix(text, "# ") || strings.HasPrefix(text, "\t") {
+		return true
+	}
+	return len(core.ParseDiagnostics(text, "")) > 0
 }
 
 func (c *CLI) showVersion() error {
@@ -3,13 +3,18 @@ package cli
 import (
 	"fmt"
 	"io"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"gox-ide/pkg/core"
 )
 
 // Renderer implements core.Renderer for CLI output
-type Renderer struct{}
+type Renderer struct {
+	coverage *core.CoverageReport
+}
 
 // NewRenderer creates a new CLI renderer
 func NewRenderer() *Renderer {
@@ -56,6 +61,9 @@ func (r *Renderer) renderTreeNode(w io.Writer, node core.TreeNode, prefix string
 			icon = core.GetIconForLanguage(node.File.Language)
 			suffix = ""
 		}
+		if node.File.Generated {
+			suffix = " (generated)"
+		}
 
 		fmt.Fprintf(w, "%s%s%s %s%s\n", prefix, connector, icon, node.File.Name, suffix)
 	}
@@ -79,19 +87,74 @@ func (r *Renderer) renderTreeNode(w io.Writer, node core.TreeNode, prefix string
 	return nil
 }
 
-// RenderFile renders file content with line numbers
+// RenderFile renders file content with line numbers.
+// If coverage is set and covers the file, each line is marked as
+// covered (✓), uncovered (✗) or not instrumented.
 func (r *Renderer) RenderFile(w io.Writer, file core.FileInfo, content string) error {
 	lines := strings.Split(content, "\n")
 
+	var coverLines map[int]core.CoverState
+	fileCoverage := r.coverage.File(file.Path)
+	if fileCoverage != nil {
+		coverLines = fileCoverage.Lines()
+	}
+
 	fmt.Fprintf(w, "\n📄 %s (%d lines)\n", file.RelPath, len(lines))
+	if file.Generated {
+		fmt.Fprint(w, "🔒 Generated file, do not edit\n")
+	}
 	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
 
 	for i, line := range lines {
-		fmt.Fprintf(w, "%4d │ %s\n", i+1, line)
+		if coverLines == nil {
+			fmt.Fprintf(w, "%4d │ %s\n", i+1, line)
+			continue
+		}
+
+		marker := " "
+		switch coverLines[i+1] {
+		case core.CoverCovered:
+			marker = "✓"
+		case core.CoverUncovered:
+			marker = "✗"
+		}
+		fmt.Fprintf(w, "%4d %s│ %s\n", i+1, marker, line)
 	}
 
 	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
-	fmt.Fprintf(w, "📊 File info: %d bytes, %d lines\n\n", len(content), len(lines))
+	fmt.Fprintf(w, "📊 File info: %d bytes, %d lines\n", len(content), len(lines))
+	if fileCoverage != nil {
+		covered, total := fileCoverage.Statements()
+		fmt.Fprintf(w, "🧪 Coverage: %.1f%% (%d/%d statements)\n", fileCoverage.Percent(), covered, total)
+	}
+	fmt.Fprintln(w)
+
+	return nil
+}
+
+// SetCoverage sets the coverage used to annotate lines in RenderFile
+func (r *Renderer) SetCoverage(report *core.CoverageReport) {
+	r.coverage = report
+}
+
+// RenderCoverage renders per-package and per-file coverage percentages
+func (r *Renderer) RenderCoverage(w io.Writer, report *core.CoverageReport) error {
+	fmt.Fprint(w, "\n📈 Coverage:\n")
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+
+	for _, pkg := range report.Packages() {
+		fmt.Fprintf(w, "📦 %-48s %6.1f%%\n", pkg.ImportPath, pkg.Percent())
+
+		for _, file := range report.Files {
+			if file.Package != pkg.ImportPath {
+				continue
+			}
+			fmt.Fprintf(w, "     %-46s %6.1f%%\n", path.Base(file.Name), file.Percent())
+		}
+	}
+
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "Total: %.1f%% of statements\n\n", report.Percent())
 
 	return nil
 }
@@ -101,3 +164,608 @@ func (r *Renderer) RenderError(w io.Writer, err error) error {
 	fmt.Fprintf(w, "❌ Error: %v\n", err)
 	return nil
 }
+
+// RenderDiagnostics renders a numbered list of diagnostics with paths relative to root
+func (r *Renderer) RenderDiagnostics(w io.Writer, root string, diags []core.Diagnostic) error {
+	if len(diags) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "\n🩺 Diagnostics (%d):\n", len(diags))
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for i, d := range diags {
+		icon := "❌"
+		if d.Severity != core.SeverityError {
+			icon = "⚠️"
+		}
+
+		file := d.File
+		if rel, err := filepath.Rel(root, d.File); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+
+		// Continuation lines (have/want) are indented under the message
+		message := strings.ReplaceAll(d.Message, "\n", "\n          ")
+
+		if d.Source != "" {
+			message += " (" + d.Source + ")"
+		}
+
+		if d.Column > 0 {
+			fmt.Fprintf(w, "  e%-3d %s %s:%d:%d: %s\n", i+1, icon, file, d.Line, d.Column, message)
+		} else {
+			fmt.Fprintf(w, "  e%-3d %s %s:%d: %s\n", i+1, icon, file, d.Line, message)
+		}
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	errs, warnings := core.CountDiagnostics(diags)
+	fmt.Fprintf(w, "Total: %d errors, %d warnings\n\n", errs, warnings)
+
+	return nil
+}
+
+// RenderTestReport renders a test summary table with failures expanded
+func (r *Renderer) RenderTestReport(w io.Writer, report *core.TestReport) error {
+	fmt.Fprint(w, "\n🧪 Test Results:\n")
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "  %-6s %-36s %5s %5s %5s %8s\n", "STATUS", "PACKAGE", "PASS", "FAIL", "SKIP", "TIME")
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+
+	for _, pkg := range report.Packages {
+		passed, failed, skipped := pkg.Counts()
+		fmt.Fprintf(w, "  %s %-36s %5d %5d %5d %7.2fs\n",
+			testStatusLabel(pkg.Status), pkg.ImportPath, passed, failed, skipped, pkg.Elapsed.Seconds())
+
+		if pkg.Status == core.TestFail {
+			r.renderPackageFailures(w, pkg)
+		}
+	}
+
+	passed, failed, skipped := report.Counts()
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "Total: %d passed, %d failed, %d skipped\n\n", passed, failed, skipped)
+
+	return nil
+}
+
+// RenderTestFailures renders only the failing tests with their output
+func (r *Renderer) RenderTestFailures(w io.Writer, report *core.TestReport) error {
+	if report.Passed() {
+		fmt.Fprint(w, "✅ No failing tests\n")
+		return nil
+	}
+
+	fmt.Fprint(w, "\n❌ Failing Tests:\n")
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+
+	for _, pkg := range report.Packages {
+		if pkg.Status != core.TestFail {
+			continue
+		}
+		fmt.Fprintf(w, "📦 %s\n", pkg.ImportPath)
+		r.renderPackageFailures(w, pkg)
+	}
+
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	return nil
+}
+
+// renderPackageFailures renders the failing tests of a package, or the
+// package output if it failed without a failing test (e.g. build errors)
+func (r *Renderer) renderPackageFailures(w io.Writer, pkg *core.PackageResult) {
+	failed := pkg.Failed()
+	if len(failed) == 0 {
+		for _, line := range pkg.Output {
+			fmt.Fprintf(w, "         %s\n", line)
+		}
+		return
+	}
+
+	for _, test := range failed {
+		fmt.Fprintf(w, "       ❌ %s (%.2fs)\n", test.Name, test.Elapsed.Seconds())
+		for _, line := range test.Output {
+			// Skip test framework progress markers
+			if strings.HasPrefix(line, "=== ") {
+				continue
+			}
+			fmt.Fprintf(w, "            %s\n", line)
+		}
+	}
+}
+
+// testStatusLabel returns a fixed-width label for a test status
+func testStatusLabel(status core.TestStatus) string {
+	switch status {
+	case core.TestPass:
+		return "✅ ok  "
+	case core.TestFail:
+		return "❌ FAIL"
+	case core.TestSkip:
+		return "⏭️ skip"
+	default:
+		return "⏳ run "
+	}
+}
+
+// RenderBenchRun renders the results of a benchmark run
+func (r *Renderer) RenderBenchRun(w io.Writer, run *core.BenchRun) error {
+	fmt.Fprintf(w, "\n⏱️ Benchmarks (run %s):\n", run.ID)
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "  %-40s %14s %12s %12s\n", "BENCHMARK", "ns/op", "B/op", "allocs/op")
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+
+	stats, keys := run.Stats()
+	for _, key := range keys {
+		s := stats[key]
+		fmt.Fprintf(w, "  %-40s %14.2f %12.0f %12.0f\n", key, s.NsPerOp, s.BytesPerOp, s.AllocsPerOp)
+	}
+
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "Total: %d benchmarks", len(keys))
+	if run.CPU != "" {
+		fmt.Fprintf(w, " on %s", run.CPU)
+	}
+	fmt.Fprint(w, "\n\n")
+
+	return nil
+}
+
+// RenderBenchRuns renders a list of stored benchmark runs
+func (r *Renderer) RenderBenchRuns(w io.Writer, runs []*core.BenchRun) error {
+	fmt.Fprint(w, "\n⏱️ Stored benchmark runs:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for _, run := range runs {
+		pattern, pkg := run.Pattern, run.Package
+		if pattern == "" {
+			pattern = "."
+		}
+		if pkg == "" {
+			pkg = "./..."
+		}
+		fmt.Fprintf(w, "  %-20s %3d results  -bench %s %s\n", run.ID, len(run.Results), pattern, pkg)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d runs\n\n", len(runs))
+
+	return nil
+}
+
+// RenderBenchComparison renders the deltas between two benchmark runs
+func (r *Renderer) RenderBenchComparison(w io.Writer, base, head *core.BenchRun, deltas []core.BenchDelta) error {
+	fmt.Fprintf(w, "\n⚖️ Benchmark comparison: %s → %s\n", base.ID, head.ID)
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprintf(w, "  %-40s %14s %14s %9s %9s %9s\n", "BENCHMARK", "old ns/op", "new ns/op", "Δ time", "Δ B/op", "Δ allocs")
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+
+	for _, d := range deltas {
+		switch {
+		case d.Base == nil:
+			fmt.Fprintf(w, "  %-40s %14s %14.2f %9s\n", d.Key, "-", d.Head.NsPerOp, "new")
+		case d.Head == nil:
+			fmt.Fprintf(w, "  %-40s %14.2f %14s %9s\n", d.Key, d.Base.NsPerOp, "-", "removed")
+		default:
+			fmt.Fprintf(w, "  %-40s %14.2f %14.2f %s %s %s\n", d.Key, d.Base.NsPerOp, d.Head.NsPerOp,
+				formatDelta(d.NsPerOpDelta()), formatDelta(d.BytesPerOpDelta()), formatDelta(d.AllocsPerOpDelta()))
+		}
+	}
+
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	fmt.Fprint(w, "Negative deltas are improvements\n\n")
+
+	return nil
+}
+
+// RenderCheckReport renders the status of each check step
+func (r *Renderer) RenderCheckReport(w io.Writer, report *core.CheckReport) error {
+	fmt.Fprint(w, "\n🔍 Check pipeline:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for _, result := range report.Results {
+		icon := "✅"
+		status := fmt.Sprintf("%d findings", len(result.Diagnostics))
+		if result.Err != nil {
+			icon = "❌"
+			if len(result.Diagnostics) == 0 {
+				status = result.Err.Error()
+			}
+		}
+		fmt.Fprintf(w, "  %s %-20s %7.2fs  %s\n", icon, result.Step.Name, result.Duration.Seconds(), status)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	return nil
+}
+
+// RenderMatrixResults renders the per-target results of a matrix build
+func (r *Renderer) RenderMatrixResults(w io.Writer, root string, results []core.MatrixResult) error {
+	fmt.Fprint(w, "\n📦 Matrix build:\n")
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+
+	for _, result := range results {
+		if result.Err != nil {
+			msg := result.Err.Error()
+			if len(result.Diagnostics) > 0 {
+				msg = result.Diagnostics[0].String()
+			}
+			fmt.Fprintf(w, "  ❌ %-16s %s\n", result.Target, msg)
+			continue
+		}
+
+		output := result.Output
+		if rel, err := filepath.Rel(root, output); err == nil {
+			output = rel
+		}
+		fmt.Fprintf(w, "  ✅ %-16s %-36s %9s %7.2fs\n",
+			result.Target, output, formatSize(result.Size), result.Duration.Seconds())
+	}
+
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+	return nil
+}
+
+// formatSize formats a byte count with a binary unit
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// RenderCleanPlan renders the paths and caches a clean removes
+func (r *Renderer) RenderCleanPlan(w io.Writer, root string, plan *core.CleanPlan, dryRun bool) error {
+	if dryRun {
+		fmt.Fprint(w, "\n🧹 Clean would remove:\n")
+	} else {
+		fmt.Fprint(w, "\n🧹 Cleaned:\n")
+	}
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for _, item := range plan.Items {
+		path := item.Path
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = rel
+		}
+		icon := "📄"
+		if item.IsDir {
+			icon = "📁"
+			path += string(filepath.Separator)
+		}
+		fmt.Fprintf(w, "  %s %-36s %9s\n", icon, path, formatSize(item.Size))
+	}
+	for _, command := range plan.Commands {
+		if command.Dir != "" {
+			fmt.Fprintf(w, "  ⚙️ %-36s %9s  %s\n", command, formatSize(command.Size), command.Dir)
+		} else {
+			fmt.Fprintf(w, "  ⚙️ %s\n", command)
+		}
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d paths, %s\n\n", len(plan.Items), formatSize(plan.Size()))
+
+	return nil
+}
+
+// RenderBreakpoints renders the debugger's breakpoints
+func (r *Renderer) RenderBreakpoints(w io.Writer, root string, breakpoints []core.Breakpoint) error {
+	fmt.Fprint(w, "\n🔴 Breakpoints:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for i, bp := range breakpoints {
+		status := "pending"
+		if bp.Verified {
+			status = "verified"
+		} else if bp.Message != "" {
+			status = bp.Message
+		}
+		fmt.Fprintf(w, "  %-3d %s:%d  (%s)\n", i+1, relativePath(root, bp.File), bp.Line, status)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d breakpoints\n\n", len(breakpoints))
+
+	return nil
+}
+
+// RenderStackTrace renders the call stack of a stopped goroutine
+func (r *Renderer) RenderStackTrace(w io.Writer, root string, frames []core.StackFrame) error {
+	fmt.Fprint(w, "\n📚 Stack trace:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for i, frame := range frames {
+		fmt.Fprintf(w, "  #%-3d %s\n", i, frame.Name)
+		if frame.File != "" {
+			fmt.Fprintf(w, "        %s:%d\n", relativePath(root, frame.File), frame.Line)
+		}
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	return nil
+}
+
+// RenderVariables renders variables of a stack frame
+func (r *Renderer) RenderVariables(w io.Writer, vars []core.Variable) error {
+	fmt.Fprint(w, "\n🔎 Locals:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	if len(vars) == 0 {
+		fmt.Fprint(w, "  (none)\n")
+	}
+	for _, v := range vars {
+		fmt.Fprintf(w, "  %-16s %-16s = %s\n", v.Name, v.Type, v.Value)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	return nil
+}
+
+// RenderGoroutines renders the goroutines of the debugged program,
+// marking the one that stopped
+func (r *Renderer) RenderGoroutines(w io.Writer, threads []core.DebugThread, current int) error {
+	fmt.Fprint(w, "\n🧵 Goroutines:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for _, t := range threads {
+		marker := " "
+		if t.ID == current {
+			marker = "▶"
+		}
+		fmt.Fprintf(w, "  %s %-6d %s\n", marker, t.ID, t.Name)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d goroutines\n\n", len(threads))
+
+	return nil
+}
+
+// relativePath returns file relative to root if it is inside it
+func relativePath(root, file string) string {
+	if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return file
+}
+
+// RenderProfile renders the costliest functions of a profile
+func (r *Renderer) RenderProfile(w io.Writer, root string, report *core.ProfileReport, entries []core.ProfileEntry) error {
+	fmt.Fprintf(w, "\n🔥 Profile: %s (total %s)\n", report.SampleType.Type, report.Format(report.Total))
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+	fmt.Fprintf(w, "  %-5s %10s %6s %10s %6s  %s\n", "", "FLAT", "FLAT%", "CUM", "CUM%", "FUNCTION")
+
+	percent := func(v int64) float64 {
+		if report.Total == 0 {
+			return 0
+		}
+		return 100 * float64(v) / float64(report.Total)
+	}
+
+	for i, e := range entries {
+		fmt.Fprintf(w, "  p%-4d %10s %5.1f%% %10s %5.1f%%  %s\n",
+			i+1, report.Format(e.Flat), percent(e.Flat), report.Format(e.Cum), percent(e.Cum), e.Function)
+		if e.File != "" {
+			fmt.Fprintf(w, "  %52s%s:%d\n", "", relativePath(root, e.File), e.Line)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Fprint(w, "  No samples recorded\n")
+	}
+
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+	return nil
+}
+
+// RenderGenerateDirectives renders a numbered list of go:generate
+// directives
+func (r *Renderer) RenderGenerateDirectives(w io.Writer, directives []core.GenerateDirective) error {
+	fmt.Fprint(w, "\n⚙️ go:generate directives:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for i, d := range directives {
+		fmt.Fprintf(w, "  g%-3d %s:%d\n", i+1, filepath.ToSlash(d.RelPath), d.Line)
+		fmt.Fprintf(w, "       %s\n", d.Command)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d directives\n\n", len(directives))
+
+	return nil
+}
+
+// RenderSymbols renders a numbered list of symbol search results with
+// the first line of their doc comments
+func (r *Renderer) RenderSymbols(w io.Writer, root string, matches []core.SymbolMatch) error {
+	fmt.Fprint(w, "\n🔎 Symbols:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for i, m := range matches {
+		fmt.Fprintf(w, "  s%-3d %-7s %s  %s:%d\n", i+1, m.Kind, m.QualifiedName(), relativePath(root, m.File), m.Line)
+		if summary := m.Summary(); summary != "" {
+			fmt.Fprintf(w, "               %s\n", summary)
+		}
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d symbols\n\n", len(matches))
+
+	return nil
+}
+
+// RenderWorkspace renders the modules of a workspace, marking the one in
+// the active directory
+func (r *Renderer) RenderWorkspace(w io.Writer, ws *core.Workspace, active string) error {
+	source := "nested go.mod files"
+	if ws.WorkFile != "" {
+		source = "go.work"
+	}
+	fmt.Fprintf(w, "\n📦 Modules (%s):\n", source)
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+
+	for _, mod := range ws.Modules {
+		marker := " "
+		if mod.Dir == active {
+			marker = "▶"
+		}
+		fmt.Fprintf(w, "  %s %-30s %s\n", marker, mod.Path, relativePath(ws.Root, mod.Dir))
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d modules\n\n", len(ws.Modules))
+
+	return nil
+}
+
+// RenderModules renders the dependencies of the main module with their
+// versions, direct ones first
+func (r *Renderer) RenderModules(w io.Writer, graph *core.ModuleGraph) error {
+	name := "module"
+	if main := graph.Main(); main != nil {
+		name = main.Path
+	}
+	fmt.Fprintf(w, "\n📦 Dependencies of %s:\n", name)
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+
+	var direct, indirect int
+	for _, mod := range graph.Dependencies() {
+		kind := "direct"
+		if mod.Indirect {
+			kind = "indirect"
+			indirect++
+		} else {
+			direct++
+		}
+
+		fmt.Fprintf(w, "  %-8s %s %s\n", kind, mod.Path, mod.Version)
+		if mod.Replace != nil {
+			fmt.Fprintf(w, "           => %s\n", strings.TrimSpace(mod.Replace.Path+" "+mod.Replace.Version))
+		}
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d direct, %d indirect\n\n", direct, indirect)
+
+	return nil
+}
+
+// RenderModuleRequirements renders the modules requiring a module and
+// the versions they require, marking the selected version
+func (r *Renderer) RenderModuleRequirements(w io.Writer, graph *core.ModuleGraph, path string, reqs []core.ModuleRequirement) error {
+	selected := ""
+	if mod, ok := graph.Module(path); ok {
+		selected = mod.Version
+	}
+	fmt.Fprintf(w, "\n🔗 Modules requiring %s (selected %s):\n", path, selected)
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+
+	for _, req := range reqs {
+		_, version, _ := strings.Cut(req.To, "@")
+		marker := " "
+		if graph.Selected(req.To) {
+			marker = "✓"
+		}
+		fmt.Fprintf(w, "  %s %s requires %s\n", marker, req.From, version)
+	}
+	if len(reqs) == 0 {
+		fmt.Fprint(w, "  No module requires it\n")
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+	return nil
+}
+
+// RenderModuleWhy renders the import chain explaining why a module is
+// needed
+func (r *Renderer) RenderModuleWhy(w io.Writer, why *core.ModuleWhy) error {
+	fmt.Fprintf(w, "\n❓ Why %s is needed:\n", why.Module)
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+
+	if why.NotNeeded || len(why.Packages) == 0 {
+		fmt.Fprint(w, "  No package of the main module imports it\n")
+	}
+	for i, pkg := range why.Packages {
+		prefix := "  "
+		if i > 0 {
+			prefix = strings.Repeat("  ", i) + "└─ "
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, pkg)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────────────────────\n")
+	return nil
+}
+
+// RenderTasks renders the discovered project tasks
+func (r *Renderer) RenderTasks(w io.Writer, tasks []core.Task) error {
+	fmt.Fprint(w, "\n📋 Tasks:\n")
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+
+	for _, task := range tasks {
+		icon := "🛠️"
+		if task.Kind == core.TaskScript {
+			icon = "📜"
+		}
+		fmt.Fprintf(w, "  %s %-16s %s\n", icon, task.Name, task.Description)
+	}
+
+	fmt.Fprint(w, "─────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d tasks\n\n", len(tasks))
+
+	return nil
+}
+
+// RenderProcesses renders a table of background processes
+func (r *Renderer) RenderProcesses(w io.Writer, processes []*core.Process) error {
+	fmt.Fprint(w, "\n⚙️ Processes:\n")
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+	fmt.Fprintf(w, "  %-4s %-16s %-8s %-10s %s\n", "ID", "NAME", "PID", "STATE", "UPTIME")
+
+	for _, p := range processes {
+		state := string(p.State())
+		if p.State() == core.ProcessExited {
+			state = fmt.Sprintf("exited(%d)", p.ExitCode())
+		}
+		fmt.Fprintf(w, "  %-4d %-16s %-8d %-10s %s\n",
+			p.ID, p.Config.Name, p.PID, state, p.Uptime().Round(time.Second))
+	}
+
+	fmt.Fprint(w, "───────────────────────────────────────────────────────────────\n")
+	fmt.Fprintf(w, "Total: %d processes\n\n", len(processes))
+
+	return nil
+}
+
+// RenderProcessLogs renders the retained output of a process
+func (r *Renderer) RenderProcessLogs(w io.Writer, process *core.Process) error {
+	fmt.Fprintf(w, "\n📜 Logs of [%d] %s (pid %d, %s):\n",
+		process.ID, process.Config.Name, process.PID, process.State())
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+
+	for _, line := range process.Logs() {
+		if line.Stream == core.StreamStderr {
+			fmt.Fprintf(w, "❗ %s\n", line.Text)
+		} else {
+			fmt.Fprintf(w, "   %s\n", line.Text)
+		}
+	}
+
+	fmt.Fprint(w, "═══════════════════════════════════════════════════════════════\n")
+	return nil
+}
+
+// formatDelta formats a percentage change with a sign, right-aligned
+func formatDelta(delta float64) string {
+	if delta == 0 {
+		return fmt.Sprintf("%9s", "~")
+	}
+	return fmt.Sprintf("%+8.1f%%", delta)
+}
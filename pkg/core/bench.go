@@ -0,0 +1,332 @@
+// Package core provides benchmark parsing, history and comparison.
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProjectDataDir is the per-project directory for IDE state
+const ProjectDataDir = ".gox"
+
+// ErrBenchRunNotFound is returned when a stored benchmark run does not exist
+var ErrBenchRunNotFound = errors.New("benchmark run not found")
+
+// BenchOptions selects which benchmarks to run and how
+type BenchOptions struct {
+	Package   string // Package pattern; defaults to "./..."
+	Bench     string // -bench regular expression; defaults to "."
+	Count     int    // -count; 0 leaves the go default
+	Benchtime string // -benchtime, e.g. "2s" or "1000x"
+}
+
+// Args returns the go test arguments for the options
+func (o BenchOptions) Args() []string {
+	bench := o.Bench
+	if bench == "" {
+		bench = "."
+	}
+
+	// Skip regular tests so only benchmarks run
+	args := []string{"-run", "^$", "-bench", bench, "-benchmem"}
+	if o.Count > 0 {
+		args = append(args, "-count", strconv.Itoa(o.Count))
+	}
+	if o.Benchtime != "" {
+		args = append(args, "-benchtime", o.Benchtime)
+	}
+
+	pkg := o.Package
+	if pkg == "" {
+		pkg = "./..."
+	}
+	return append(args, pkg)
+}
+
+// BenchResult is a single benchmark result line
+type BenchResult struct {
+	Name        string // Without the -GOMAXPROCS suffix
+	Package     string
+	Procs       int
+	Iterations  int64
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+	MBPerSec    float64
+	HasMem      bool // B/op and allocs/op were reported
+}
+
+// Key identifies the same benchmark across runs
+func (r BenchResult) Key() string {
+	return fmt.Sprintf("%s.%s-%d", r.Package, r.Name, r.Procs)
+}
+
+// BenchRun is a stored set of benchmark results
+type BenchRun struct {
+	ID      string
+	Time    time.Time
+	Pattern string
+	Package string
+	GOOS    string
+	GOARCH  string
+	CPU     string
+	Results []BenchResult
+}
+
+// ParseBenchOutput parses go test -bench output into results
+func ParseBenchOutput(r io.Reader) (*BenchRun, error) {
+	run := &BenchRun{}
+
+	var pkg string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "pkg: "):
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg: "))
+		case strings.HasPrefix(line, "goos: "):
+			run.GOOS = strings.TrimSpace(strings.TrimPrefix(line, "goos: "))
+		case strings.HasPrefix(line, "goarch: "):
+			run.GOARCH = strings.TrimSpace(strings.TrimPrefix(line, "goarch: "))
+		case strings.HasPrefix(line, "cpu: "):
+			run.CPU = strings.TrimSpace(strings.TrimPrefix(line, "cpu: "))
+		case strings.HasPrefix(line, "Benchmark"):
+			if result, ok := parseBenchLine(line); ok {
+				result.Package = pkg
+				run.Results = append(run.Results, result)
+			}
+		}
+	}
+
+	return run, scanner.Err()
+}
+
+// parseBenchLine parses "BenchmarkX-8  1000  123 ns/op  16 B/op  1 allocs/op"
+func parseBenchLine(line string) (BenchResult, bool) {
+	var result BenchResult
+
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return result, false
+	}
+
+	iterations, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return result, false
+	}
+	result.Iterations = iterations
+
+	result.Name = fields[0]
+	result.Procs = 1
+	if i := strings.LastIndex(fields[0], "-"); i > 0 {
+		if procs, err := strconv.Atoi(fields[0][i+1:]); err == nil {
+			result.Name = fields[0][:i]
+			result.Procs = procs
+		}
+	}
+
+	// Remaining fields are value/unit pairs
+	for i := 2; i+1 < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			continue
+		}
+		switch fields[i+1] {
+		case "ns/op":
+			result.NsPerOp = value
+		case "B/op":
+			result.BytesPerOp = value
+			result.HasMem = true
+		case "allocs/op":
+			result.AllocsPerOp = value
+			result.HasMem = true
+		case "MB/s":
+			result.MBPerSec = value
+		}
+	}
+
+	return result, true
+}
+
+// BenchStats is the mean of repeated results of one benchmark
+type BenchStats struct {
+	Samples     int
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+}
+
+// Stats returns per-benchmark means keyed by BenchResult.Key, and the
+// keys in the order they first appear
+func (r *BenchRun) Stats() (map[string]BenchStats, []string) {
+	stats := make(map[string]BenchStats)
+	var keys []string
+
+	for _, result := range r.Results {
+		key := result.Key()
+		s, ok := stats[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		// Incremental mean keeps repeated -count samples cheap
+		s.Samples++
+		n := float64(s.Samples)
+		s.NsPerOp += (result.NsPerOp - s.NsPerOp) / n
+		s.BytesPerOp += (result.BytesPerOp - s.BytesPerOp) / n
+		s.AllocsPerOp += (result.AllocsPerOp - s.AllocsPerOp) / n
+		stats[key] = s
+	}
+
+	return stats, keys
+}
+
+// BenchDelta compares one benchmark across two runs
+type BenchDelta struct {
+	Key  string
+	Base *BenchStats // nil if the benchmark is new
+	Head *BenchStats // nil if the benchmark was removed
+}
+
+// NsPerOpDelta returns the relative change in ns/op as a percentage
+func (d BenchDelta) NsPerOpDelta() float64 {
+	return relDelta(d.Base, d.Head, func(s *BenchStats) float64 { return s.NsPerOp })
+}
+
+// BytesPerOpDelta returns the relative change in B/op as a percentage
+func (d BenchDelta) BytesPerOpDelta() float64 {
+	return relDelta(d.Base, d.Head, func(s *BenchStats) float64 { return s.BytesPerOp })
+}
+
+// AllocsPerOpDelta returns the relative change in allocs/op as a percentage
+func (d BenchDelta) AllocsPerOpDelta() float64 {
+	return relDelta(d.Base, d.Head, func(s *BenchStats) float64 { return s.AllocsPerOp })
+}
+
+// relDelta returns (head-base)/base in percent, 0 if either is missing or base is 0
+func relDelta(base, head *BenchStats, value func(*BenchStats) float64) float64 {
+	if base == nil || head == nil || value(base) == 0 {
+		return 0
+	}
+	return (value(head) - value(base)) / value(base) * 100
+}
+
+// CompareBenchRuns compares every benchmark present in either run
+func CompareBenchRuns(base, head *BenchRun) []BenchDelta {
+	baseStats, baseKeys := base.Stats()
+	headStats, headKeys := head.Stats()
+
+	var deltas []BenchDelta
+	for _, key := range headKeys {
+		h := headStats[key]
+		delta := BenchDelta{Key: key, Head: &h}
+		if b, ok := baseStats[key]; ok {
+			delta.Base = &b
+		}
+		deltas = append(deltas, delta)
+	}
+	for _, key := range baseKeys {
+		if _, ok := headStats[key]; ok {
+			continue
+		}
+		b := baseStats[key]
+		deltas = append(deltas, BenchDelta{Key: key, Base: &b})
+	}
+
+	return deltas
+}
+
+// BenchStore persists benchmark runs under <project>/.gox/bench
+type BenchStore struct {
+	dir string
+}
+
+// NewBenchStore creates a benchmark store for the project at projectPath
+func NewBenchStore(projectPath string) *BenchStore {
+	return &BenchStore{
+		dir: filepath.Join(projectPath, ProjectDataDir, "bench"),
+	}
+}
+
+// Save assigns the run an ID based on its time and writes it to the store
+func (s *BenchStore) Save(run *BenchRun) error {
+	if run.Time.IsZero() {
+		run.Time = time.Now()
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	// Runs within the same second get a numeric suffix
+	base := run.Time.Format("20060102-150405")
+	run.ID = base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(s.dir, run.ID+".json")); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		run.ID = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(s.dir, run.ID+".json"), data, 0644)
+}
+
+// Load reads the run with the given ID
+func (s *BenchStore) Load(id string) (*BenchRun, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.Base(id)+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBenchRunNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var run BenchRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("corrupt benchmark run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// List returns all stored runs, oldest first
+func (s *BenchStore) List() ([]*BenchRun, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*BenchRun, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		run, err := s.Load(id)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Time.Before(runs[j].Time)
+	})
+	return runs, nil
+}
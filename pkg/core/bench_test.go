@@ -0,0 +1,100 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// benchOutput is go test -bench . -benchmem -count 2 output of two
+// packages
+const benchOutput = `goos: linux
+goarch: amd64
+pkg: example.com/m/parse
+cpu: AMD EPYC 7B13
+BenchmarkParse
+BenchmarkParse-8          	   50000	     23456 ns/op	    4096 B/op	      12 allocs/op
+BenchmarkParse-8          	   52000	     22456 ns/op	    4096 B/op	      14 allocs/op
+BenchmarkParse/size-10-8  	  100000	     10.5 ns/op	  95.24 MB/s	       0 B/op	       0 allocs/op
+BenchmarkCustom-8         	    1000	   1000000 ns/op	         3.500 widgets/op	      64 B/op	       1 allocs/op
+BenchmarkNoProcs          	     200	     5000 ns/op
+--- FAIL: BenchmarkBroken-8
+    parse_test.go:40: broken
+BenchmarkLog says hello
+PASS
+ok  	example.com/m/parse	5.123s
+pkg: example.com/m/other
+BenchmarkOther-4          	    3000	    400000 ns/op
+PASS
+ok  	example.com/m/other	1.002s
+`
+
+func TestParseBenchOutput(t *testing.T) {
+	run, err := ParseBenchOutput(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("ParseBenchOutput: %v", err)
+	}
+	if run.GOOS != "linux" || run.GOARCH != "amd64" || run.CPU != "AMD EPYC 7B13" {
+		t.Errorf("environment = %s/%s %q, want linux/amd64 %q", run.GOOS, run.GOARCH, run.CPU, "AMD EPYC 7B13")
+	}
+
+	const parse = "example.com/m/parse"
+	want := []BenchResult{
+		{Name: "BenchmarkParse", Package: parse, Procs: 8, Iterations: 50000, NsPerOp: 23456, BytesPerOp: 4096, AllocsPerOp: 12, HasMem: true},
+		{Name: "BenchmarkParse", Package: parse, Procs: 8, Iterations: 52000, NsPerOp: 22456, BytesPerOp: 4096, AllocsPerOp: 14, HasMem: true},
+		{Name: "BenchmarkParse/size-10", Package: parse, Procs: 8, Iterations: 100000, NsPerOp: 10.5, MBPerSec: 95.24, HasMem: true},
+		// Custom metrics are skipped without losing the standard ones
+		{Name: "BenchmarkCustom", Package: parse, Procs: 8, Iterations: 1000, NsPerOp: 1000000, BytesPerOp: 64, AllocsPerOp: 1, HasMem: true},
+		{Name: "BenchmarkNoProcs", Package: parse, Procs: 1, Iterations: 200, NsPerOp: 5000},
+		{Name: "BenchmarkOther", Package: "example.com/m/other", Procs: 4, Iterations: 3000, NsPerOp: 400000},
+	}
+	if !reflect.DeepEqual(run.Results, want) {
+		t.Errorf("Results =\n%+v\nwant\n%+v", run.Results, want)
+	}
+}
+
+func TestBenchRunStats(t *testing.T) {
+	run, err := ParseBenchOutput(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("ParseBenchOutput: %v", err)
+	}
+
+	stats, keys := run.Stats()
+	wantKeys := []string{
+		"example.com/m/parse.BenchmarkParse-8",
+		"example.com/m/parse.BenchmarkParse/size-10-8",
+		"example.com/m/parse.BenchmarkCustom-8",
+		"example.com/m/parse.BenchmarkNoProcs-1",
+		"example.com/m/other.BenchmarkOther-4",
+	}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("keys = %q, want %q", keys, wantKeys)
+	}
+
+	// -count repeats are averaged
+	want := BenchStats{Samples: 2, NsPerOp: 22956, BytesPerOp: 4096, AllocsPerOp: 13}
+	if got := stats["example.com/m/parse.BenchmarkParse-8"]; got != want {
+		t.Errorf("BenchmarkParse stats = %+v, want %+v", got, want)
+	}
+	if got := stats["example.com/m/other.BenchmarkOther-4"]; got.Samples != 1 || got.NsPerOp != 400000 {
+		t.Errorf("BenchmarkOther stats = %+v, want one sample of 400000 ns/op", got)
+	}
+}
+
+func TestBenchOptionsArgs(t *testing.T) {
+	tests := []struct {
+		opts BenchOptions
+		want []string
+	}{
+		{BenchOptions{}, []string{"-run", "^$", "-bench", ".", "-benchmem", "./..."}},
+		{
+			BenchOptions{Package: "./parse", Bench: "Parse$", Count: 5, Benchtime: "100x"},
+			[]string{"-run", "^$", "-bench", "Parse$", "-benchmem", "-count", "5", "-benchtime", "100x", "./parse"},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Args(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Args(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
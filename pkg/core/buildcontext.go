@@ -0,0 +1,113 @@
+// Package core provides build-constraint evaluation for a target platform.
+package core
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// buildSourceExts are the extensions of files the go command selects with
+// build constraints and _GOOS_GOARCH name suffixes
+var buildSourceExts = map[string]bool{
+	".go": true, ".s": true, ".S": true, ".sx": true, ".syso": true,
+	".c": true, ".cc": true, ".cpp": true, ".cxx": true, ".m": true,
+	".h": true, ".hh": true, ".hpp": true, ".hxx": true,
+	".f": true, ".F": true, ".for": true, ".f90": true, ".swig": true, ".swigcxx": true,
+}
+
+// BuildContext is a target platform and tag set that source files are
+// classified for
+type BuildContext struct {
+	Target BuildTarget
+	Tags   []string
+}
+
+// HostBuildContext returns the context of the platform the IDE runs on
+func HostBuildContext() BuildContext {
+	return BuildContext{Target: BuildTarget{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}}
+}
+
+// ParseBuildContext parses "os/arch,tags=a,b". The target defaults to the
+// host and either part may be omitted, e.g. "tags=integration".
+func ParseBuildContext(s string) (BuildContext, error) {
+	ctx := HostBuildContext()
+
+	inTags := false
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(part, "tags="); ok {
+			inTags = true
+			part = tag
+		}
+
+		switch {
+		case inTags:
+			if part != "" {
+				ctx.Tags = append(ctx.Tags, part)
+			}
+		case strings.Contains(part, "/"):
+			target, err := ParseBuildTarget(part)
+			if err != nil {
+				return BuildContext{}, err
+			}
+			ctx.Target = target
+		default:
+			return BuildContext{}, fmt.Errorf("invalid build context %q, expected os/arch,tags=a,b", s)
+		}
+	}
+	return ctx, nil
+}
+
+// String returns the context as "os/arch" followed by its tags, if any
+func (c BuildContext) String() string {
+	if len(c.Tags) == 0 {
+		return c.Target.String()
+	}
+	return c.Target.String() + " tags=" + strings.Join(c.Tags, ",")
+}
+
+// Includes reports whether the go command builds a file for the context,
+// evaluating its //go:build line and _GOOS_GOARCH name suffix. Directories
+// and files that are not build sources are always included.
+func (c BuildContext) Includes(file FileInfo) bool {
+	if file.IsDir || !buildSourceExts[filepath.Ext(file.Name)] {
+		return true
+	}
+
+	goCtx := c.goContext()
+	match, err := goCtx.MatchFile(filepath.Dir(file.Path), filepath.Base(file.Path))
+	// Unreadable files are left to the build to report
+	return err != nil || match
+}
+
+// MarkFiles sets BuildExcluded on the files the context excludes
+func (c BuildContext) MarkFiles(files []FileInfo) {
+	for i := range files {
+		files[i].BuildExcluded = !c.Includes(files[i])
+	}
+}
+
+// MarkTree sets BuildExcluded on the files of a tree the context excludes
+func (c BuildContext) MarkTree(node *TreeNode) {
+	node.File.BuildExcluded = !c.Includes(node.File)
+	for i := range node.Children {
+		c.MarkTree(&node.Children[i])
+	}
+}
+
+// goContext returns the go/build context for the target. Like the go
+// command, cgo is only enabled by default for native builds.
+func (c BuildContext) goContext() build.Context {
+	ctx := build.Default
+	ctx.GOOS = c.Target.GOOS
+	ctx.GOARCH = c.Target.GOARCH
+	ctx.BuildTags = c.Tags
+	ctx.CgoEnabled = build.Default.CgoEnabled && c.Target.GOOS == runtime.GOOS && c.Target.GOARCH == runtime.GOARCH
+	return ctx
+}
@@ -4,9 +4,14 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Common errors
@@ -26,93 +31,225 @@ func NewGoBuilder(logger Logger) *GoBuilder {
 	}
 }
 
-// Build builds the Go project
-func (b *GoBuilder) Build(ctx context.Context, project Project) error {
+// Build builds the Go project, streaming output to out, and returns the compiler diagnostics
+func (b *GoBuilder) Build(ctx context.Context, project Project, out OutputSink) ([]Diagnostic, error) {
 	if !project.IsGoProject() {
-		return ErrNotGoProject
+		return nil, ErrNotGoProject
 	}
 
+	// Collect stderr for diagnostics while still streaming it
+	var (
+		mu     sync.Mutex
+		stderr strings.Builder
+	)
+	out = sinkOrDiscard(out)
+	collect := OutputSinkFunc(func(line OutputLine) {
+		if line.Stream == StreamStderr {
+			mu.Lock()
+			stderr.WriteString(line.Text)
+			stderr.WriteByte('\n')
+			mu.Unlock()
+		}
+		out.WriteLine(line)
+	})
+
 	cmd := exec.CommandContext(ctx, "go", "build", ".")
 	cmd.Dir = project.Path()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 
 	if b.logger != nil {
 		b.logger.Info("Building project", Field{Key: "project", Value: project.Path()})
 	}
 
-	return cmd.Run()
+	err := runCommand(cmd, collect)
+	return ParseDiagnostics(stderr.String(), project.Path()), err
 }
 
-// Run runs the Go project
-func (b *GoBuilder) Run(ctx context.Context, project Project) error {
+// Run builds and runs the program described by config, streaming output to out.
+// The program is built first so that it can run in any working directory.
+func (b *GoBuilder) Run(ctx context.Context, project Project, config RunConfig, out OutputSink) error {
 	if !project.IsGoProject() {
 		return ErrNotGoProject
 	}
 
-	cmd := exec.CommandContext(ctx, "go", "run", ".")
-	cmd.Dir = project.Path()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd, cleanup, err := b.PrepareRun(ctx, project, config, out)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	cmd.Stdin = os.Stdin
 
 	if b.logger != nil {
-		b.logger.Info("Running project", Field{Key: "project", Value: project.Path()})
+		b.logger.Info("Running project",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "config", Value: config.Name})
+	}
+
+	return runCommand(cmd, out)
+}
+
+// PrepareRun builds the configured package into a temporary directory and
+// returns the command to run it. cleanup removes the binary.
+func (b *GoBuilder) PrepareRun(ctx context.Context, project Project, config RunConfig, out OutputSink) (*exec.Cmd, func(), error) {
+	env, err := config.Environ(project.Path())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gox-run-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	name := config.Name
+	if name == "" {
+		name = project.Name()
 	}
+	binary := filepath.Join(tmpDir, filepath.Base(name))
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	args := append([]string{"build", "-o", binary}, config.BuildFlags()...)
+	args = append(args, config.PackageOrDefault())
 
-	return cmd.Run()
+	build := exec.CommandContext(ctx, "go", args...)
+	build.Dir = project.Path()
+	if err := runCommand(build, out); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, binary, config.Args...)
+	cmd.Dir = config.Dir(project.Path())
+	cmd.Env = env
+
+	return cmd, cleanup, nil
 }
 
-// Test runs tests for the Go project
-func (b *GoBuilder) Test(ctx context.Context, project Project) error {
+// Test runs the tests selected by opts with -json and returns the aggregated report.
+// The human-readable test output is streamed to out.
+func (b *GoBuilder) Test(ctx context.Context, project Project, opts TestOptions, out OutputSink) (*TestReport, error) {
 	if !project.IsGoProject() {
-		return ErrNotGoProject
+		return nil, ErrNotGoProject
 	}
 
-	cmd := exec.CommandContext(ctx, "go", "test", "./...")
+	// Events arrive on the stdout goroutine only, so the report needs no locking
+	report := NewTestReport()
+	out = sinkOrDiscard(out)
+	collect := OutputSinkFunc(func(line OutputLine) {
+		if line.Stream != StreamStdout {
+			out.WriteLine(line)
+			return
+		}
+
+		ev, ok := report.AddLine(line.Text)
+		if !ok {
+			out.WriteLine(line)
+			return
+		}
+		if ev.Output != "" {
+			line.Text = strings.TrimSuffix(ev.Output, "\n")
+			out.WriteLine(line)
+		}
+	})
+
+	args := append([]string{"test", "-json"}, opts.Args()...)
+	args = append(args, opts.PackagePatterns()...)
+
+	cmd := exec.CommandContext(ctx, "go", args...)
 	cmd.Dir = project.Path()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 
 	if b.logger != nil {
-		b.logger.Info("Testing project", Field{Key: "project", Value: project.Path()})
+		b.logger.Info("Testing project",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "package", Value: opts.PackagePattern()},
+			Field{Key: "run", Value: opts.Run})
 	}
 
-	return cmd.Run()
+	err := runCommand(cmd, collect)
+	return report, err
 }
 
-// Clean cleans build artifacts
-func (b *GoBuilder) Clean(ctx context.Context, project Project) error {
+// Cover runs the selected tests with -coverprofile and returns the parsed profile
+func (b *GoBuilder) Cover(ctx context.Context, project Project, opts TestOptions, out OutputSink) (*CoverageReport, error) {
 	if !project.IsGoProject() {
-		return ErrNotGoProject
+		return nil, ErrNotGoProject
 	}
 
-	// Remove common build artifacts
-	artifacts := []string{
-		filepath.Join(project.Path(), project.Name()),
-		filepath.Join(project.Path(), project.Name()+".exe"),
-		filepath.Join(project.Path(), "dist"),
+	profile, err := os.CreateTemp("", "gox-cover-*.out")
+	if err != nil {
+		return nil, err
 	}
+	profile.Close()
+	defer os.Remove(profile.Name())
 
-	for _, artifact := range artifacts {
-		if _, err := os.Stat(artifact); err == nil {
-			if err := os.RemoveAll(artifact); err != nil {
-				if b.logger != nil {
-					b.logger.Warn("Failed to remove artifact",
-						Field{Key: "artifact", Value: artifact},
-						Field{Key: "error", Value: err.Error()})
-				}
-			}
-		}
+	opts.CoverProfile = profile.Name()
+	tests, testErr := b.Test(ctx, project, opts, out)
+
+	// A failing test run still writes the profile for the packages it covered
+	f, err := os.Open(profile.Name())
+	if err != nil {
+		return nil, errors.Join(testErr, err)
+	}
+	defer f.Close()
+
+	modulePath, err := ReadModulePath(project.Path())
+	if err != nil && b.logger != nil {
+		b.logger.Warn("Cannot resolve coverage file paths", Field{Key: "error", Value: err.Error()})
+	}
+
+	report, err := ParseCoverProfile(f, modulePath, project.Path())
+	if err != nil {
+		return nil, errors.Join(testErr, err)
 	}
+	report.Tests = tests
 
-	// Run go clean
-	cmd := exec.CommandContext(ctx, "go", "clean")
+	return report, testErr
+}
+
+// Bench runs the selected benchmarks, streaming output to out, and returns the parsed run
+func (b *GoBuilder) Bench(ctx context.Context, project Project, opts BenchOptions, out OutputSink) (*BenchRun, error) {
+	if !project.IsGoProject() {
+		return nil, ErrNotGoProject
+	}
+
+	// Collect stdout for parsing while still streaming it
+	var (
+		mu     sync.Mutex
+		stdout strings.Builder
+	)
+	out = sinkOrDiscard(out)
+	collect := OutputSinkFunc(func(line OutputLine) {
+		if line.Stream == StreamStdout {
+			mu.Lock()
+			stdout.WriteString(line.Text)
+			stdout.WriteByte('\n')
+			mu.Unlock()
+		}
+		out.WriteLine(line)
+	})
+
+	cmd := exec.CommandContext(ctx, "go", append([]string{"test"}, opts.Args()...)...)
 	cmd.Dir = project.Path()
 
 	if b.logger != nil {
-		b.logger.Info("Cleaning project", Field{Key: "project", Value: project.Path()})
+		b.logger.Info("Benchmarking project",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "bench", Value: opts.Bench})
+	}
+
+	started := time.Now()
+	err := runCommand(cmd, collect)
+
+	run, parseErr := ParseBenchOutput(strings.NewReader(stdout.String()))
+	if parseErr != nil {
+		return nil, errors.Join(err, parseErr)
 	}
+	run.Time = started
+	run.Pattern = opts.Bench
+	run.Package = opts.Package
 
-	return cmd.Run()
+	return run, err
 }
@@ -0,0 +1,249 @@
+// Package core provides the check pipeline of vet, build and custom tools.
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckConfig defines the check pipeline. It is stored under "check" in
+// the project configuration.
+type CheckConfig struct {
+	Vet      bool           `json:"vet"`            // go vet ./...
+	Race     bool           `json:"race"`           // go test -race -run=^$ ./... compile check
+	TagSets  [][]string     `json:"tags,omitempty"` // Extra go build ./... per tag set
+	Commands []CheckCommand `json:"commands,omitempty"`
+}
+
+// CheckCommand is an extra tool run by the check pipeline. Findings in
+// file:line:col form are reported as warnings.
+type CheckCommand struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// DefaultCheckConfig returns the pipeline used when none is configured
+func DefaultCheckConfig() CheckConfig {
+	return CheckConfig{Vet: true}
+}
+
+// CheckConfigOrDefault returns the configured check pipeline, or
+// DefaultCheckConfig if there is none
+func (c *ProjectConfig) CheckConfigOrDefault() CheckConfig {
+	if c.Check == nil {
+		return DefaultCheckConfig()
+	}
+	return *c.Check
+}
+
+// CheckStep is a single command of the check pipeline
+type CheckStep struct {
+	Name     string
+	Command  string
+	Args     []string
+	Severity Severity // Highest severity of the step's findings
+}
+
+// Steps returns the commands of the pipeline. go build ./... always runs;
+// binaries are discarded.
+func (c CheckConfig) Steps() []CheckStep {
+	steps := []CheckStep{
+		{Name: "build", Command: "go", Args: []string{"build", "-o", os.DevNull, "./..."}, Severity: SeverityError},
+	}
+
+	for _, tags := range c.TagSets {
+		tagList := strings.Join(tags, ",")
+		steps = append(steps, CheckStep{
+			Name:     "build[" + tagList + "]",
+			Command:  "go",
+			Args:     []string{"build", "-o", os.DevNull, "-tags", tagList, "./..."},
+			Severity: SeverityError,
+		})
+	}
+	if c.Vet {
+		steps = append(steps, CheckStep{
+			Name:     "vet",
+			Command:  "go",
+			Args:     []string{"vet", "./..."},
+			Severity: SeverityWarning,
+		})
+	}
+	if c.Race {
+		steps = append(steps, CheckStep{
+			Name:     "race",
+			Command:  "go",
+			Args:     []string{"test", "-race", "-vet=off", "-run=^$", "./..."},
+			Severity: SeverityError,
+		})
+	}
+	for _, cmd := range c.Commands {
+		name := cmd.Name
+		if name == "" {
+			name = cmd.Command
+		}
+		steps = append(steps, CheckStep{
+			Name:     name,
+			Command:  cmd.Command,
+			Args:     cmd.Args,
+			Severity: SeverityWarning,
+		})
+	}
+
+	return steps
+}
+
+// CheckResult is the outcome of one check step
+type CheckResult struct {
+	Step        CheckStep
+	Duration    time.Duration
+	Err         error
+	Diagnostics []Diagnostic
+}
+
+// CheckReport is the outcome of the check pipeline
+type CheckReport struct {
+	Results     []CheckResult // In pipeline order
+	Diagnostics []Diagnostic  // Merged and deduplicated findings of all steps
+}
+
+// Passed returns true if every step succeeded
+func (r *CheckReport) Passed() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Check runs every step of the pipeline concurrently, streaming output
+// prefixed with the step name, and merges the findings
+func (b *GoBuilder) Check(ctx context.Context, project Project, config CheckConfig, out OutputSink) (*CheckReport, error) {
+	if !project.IsGoProject() {
+		return nil, ErrNotGoProject
+	}
+
+	steps := config.Steps()
+	report := &CheckReport{Results: make([]CheckResult, len(steps))}
+	out = sinkOrDiscard(out)
+
+	if b.logger != nil {
+		b.logger.Info("Checking project",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "steps", Value: len(steps)})
+	}
+
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Results[i] = runCheckStep(ctx, project, step, out)
+		}()
+	}
+	wg.Wait()
+
+	var all []Diagnostic
+	failed := 0
+	for _, result := range report.Results {
+		all = append(all, result.Diagnostics...)
+		if result.Err != nil {
+			failed++
+		}
+	}
+	report.Diagnostics = MergeDiagnostics(all)
+
+	if failed > 0 {
+		return report, fmt.Errorf("%d of %d checks failed", failed, len(steps))
+	}
+	return report, nil
+}
+
+// runCheckStep runs a check step and parses its findings
+func runCheckStep(ctx context.Context, project Project, step CheckStep, out OutputSink) CheckResult {
+	result := CheckResult{Step: step}
+	start := time.Now()
+
+	cmd := exec.CommandContext(ctx, step.Command, step.Args...)
+	cmd.Dir = project.Path()
+
+	// Tools differ in which stream they report on, so collect both
+	var (
+		mu     sync.Mutex
+		output strings.Builder
+	)
+	prefix := "[" + step.Name + "] "
+	sink := OutputSinkFunc(func(line OutputLine) {
+		mu.Lock()
+		output.WriteString(line.Text)
+		output.WriteByte('\n')
+		mu.Unlock()
+
+		line.Text = prefix + line.Text
+		out.WriteLine(line)
+	})
+
+	result.Err = runCommand(cmd, sink)
+	result.Duration = time.Since(start)
+
+	// Severities are ordered from most to least severe
+	result.Diagnostics = ParseDiagnostics(output.String(), project.Path())
+	for i := range result.Diagnostics {
+		result.Diagnostics[i].Severity = max(result.Diagnostics[i].Severity, step.Severity)
+		result.Diagnostics[i].Source = step.Name
+	}
+
+	return result
+}
+
+// MergeDiagnostics deduplicates findings reported by several tools at the
+// same position with the same message, keeping the highest severity and
+// joining their sources. The result is sorted by position.
+func MergeDiagnostics(diags []Diagnostic) []Diagnostic {
+	type key struct {
+		file         string
+		line, column int
+		message      string
+	}
+
+	index := make(map[key]int)
+	var merged []Diagnostic
+	for _, d := range diags {
+		k := key{d.File, d.Line, d.Column, d.Message}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(merged)
+			merged = append(merged, d)
+			continue
+		}
+
+		existing := &merged[i]
+		existing.Severity = min(existing.Severity, d.Severity)
+		if d.Source != "" && !strings.Contains(", "+existing.Source+", ", ", "+d.Source+", ") {
+			if existing.Source == "" {
+				existing.Source = d.Source
+			} else {
+				existing.Source += ", " + d.Source
+			}
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return merged
+}
@@ -0,0 +1,268 @@
+// Package core provides configurable cleaning of build artifacts.
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrOutsideProject is returned when a clean rule selects a path that is
+// not strictly inside the project root
+var ErrOutsideProject = errors.New("path is outside the project root")
+
+// CleanConfig defines what Clean removes. It is stored under "clean" in
+// the project configuration.
+type CleanConfig struct {
+	Paths     []string `json:"paths,omitempty"` // Glob patterns relative to the project root
+	Cache     bool     `json:"cache"`           // go clean -cache
+	TestCache bool     `json:"testcache"`       // go clean -testcache
+}
+
+// DefaultCleanConfig returns the rules used when none are configured:
+// the project binary and the matrix build output
+func DefaultCleanConfig(project Project) CleanConfig {
+	return CleanConfig{
+		Paths: []string{project.Name(), project.Name() + ".exe", MatrixOutputDir},
+	}
+}
+
+// CleanConfigOrDefault returns the configured clean rules, or
+// DefaultCleanConfig if there are none
+func (c *ProjectConfig) CleanConfigOrDefault(project Project) CleanConfig {
+	if c.Clean == nil {
+		return DefaultCleanConfig(project)
+	}
+	return *c.Clean
+}
+
+// CleanItem is a file or directory selected for removal
+type CleanItem struct {
+	Path  string // Absolute path
+	Size  int64  // Total size of the file or directory tree
+	IsDir bool
+}
+
+// CleanCommand is a go clean invocation run after the items are removed
+type CleanCommand struct {
+	Args []string
+	Dir  string // Cache directory the command empties, if known
+	Size int64  // Size of Dir
+}
+
+// String returns the command line
+func (c CleanCommand) String() string {
+	return "go " + strings.Join(c.Args, " ")
+}
+
+// CleanPlan lists exactly what Clean removes
+type CleanPlan struct {
+	Items    []CleanItem
+	Commands []CleanCommand
+}
+
+// Size returns the total size of the items and cache directories
+func (p *CleanPlan) Size() int64 {
+	var size int64
+	for _, item := range p.Items {
+		size += item.Size
+	}
+	for _, command := range p.Commands {
+		size += command.Size
+	}
+	return size
+}
+
+// PlanClean resolves the clean rules against the project without removing
+// anything. Patterns that are absolute or select the project root or
+// anything outside it are refused with ErrOutsideProject.
+func (b *GoBuilder) PlanClean(ctx context.Context, project Project, config CleanConfig) (*CleanPlan, error) {
+	if !project.IsGoProject() {
+		return nil, ErrNotGoProject
+	}
+
+	root, err := filepath.EvalSymlinks(project.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &CleanPlan{}
+	seen := make(map[string]bool)
+	for _, pattern := range config.Paths {
+		if filepath.IsAbs(pattern) || filepath.VolumeName(pattern) != "" || !isLocalPattern(pattern) {
+			return nil, fmt.Errorf("%w: %s", ErrOutsideProject, pattern)
+		}
+
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("clean pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			rel, err := relInsideRoot(root, match)
+			if err != nil {
+				return nil, err
+			}
+			if seen[rel] {
+				continue
+			}
+			seen[rel] = true
+
+			item, err := cleanItem(filepath.Join(project.Path(), rel))
+			if err != nil {
+				return nil, err
+			}
+			plan.Items = append(plan.Items, item)
+		}
+	}
+
+	// Drop items already covered by a selected parent directory
+	sort.Slice(plan.Items, func(i, j int) bool {
+		return plan.Items[i].Path < plan.Items[j].Path
+	})
+	items := plan.Items[:0]
+	for _, item := range plan.Items {
+		if n := len(items); n > 0 && items[n-1].IsDir &&
+			strings.HasPrefix(item.Path, items[n-1].Path+string(filepath.Separator)) {
+			continue
+		}
+		items = append(items, item)
+	}
+	plan.Items = items
+
+	plan.Commands = append(plan.Commands, CleanCommand{Args: []string{"clean"}})
+	if config.Cache {
+		plan.Commands = append(plan.Commands, b.cacheCommand(ctx, project, "-cache", "GOCACHE"))
+	}
+	if config.TestCache && !config.Cache {
+		// -cache already removes the test results
+		plan.Commands = append(plan.Commands, CleanCommand{Args: []string{"clean", "-testcache"}})
+	}
+
+	return plan, nil
+}
+
+// Clean removes the paths selected by config and runs go clean, streaming
+// its output to out. It returns the plan that was carried out.
+func (b *GoBuilder) Clean(ctx context.Context, project Project, config CleanConfig, out OutputSink) (*CleanPlan, error) {
+	plan, err := b.PlanClean(ctx, project, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.logger != nil {
+		b.logger.Info("Cleaning project",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "items", Value: len(plan.Items)})
+	}
+
+	var errs []error
+	for _, item := range plan.Items {
+		if err := os.RemoveAll(item.Path); err != nil {
+			if b.logger != nil {
+				b.logger.Warn("Failed to remove artifact",
+					Field{Key: "artifact", Value: item.Path},
+					Field{Key: "error", Value: err.Error()})
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	for _, command := range plan.Commands {
+		cmd := exec.CommandContext(ctx, "go", command.Args...)
+		cmd.Dir = project.Path()
+		if err := runCommand(cmd, out); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", command, err))
+		}
+	}
+
+	return plan, errors.Join(errs...)
+}
+
+// cacheCommand returns the go clean command for a cache flag, with the
+// location and size of the cache reported by go env
+func (b *GoBuilder) cacheCommand(ctx context.Context, project Project, flag, envVar string) CleanCommand {
+	command := CleanCommand{Args: []string{"clean", flag}}
+
+	cmd := exec.CommandContext(ctx, "go", "env", envVar)
+	cmd.Dir = project.Path()
+	output, err := cmd.Output()
+	if err != nil {
+		if b.logger != nil {
+			b.logger.Warn("Cannot locate cache",
+				Field{Key: "var", Value: envVar},
+				Field{Key: "error", Value: err.Error()})
+		}
+		return command
+	}
+
+	command.Dir = strings.TrimSpace(string(output))
+	if command.Dir != "" && command.Dir != "off" {
+		command.Size, _ = treeSize(command.Dir)
+	}
+	return command
+}
+
+// isLocalPattern reports whether a pattern stays below the project root
+// lexically, i.e. selects neither the root itself nor a path above it
+func isLocalPattern(pattern string) bool {
+	rel := filepath.Clean(pattern)
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// relInsideRoot returns path relative to root, or ErrOutsideProject unless
+// it is strictly inside root once the symlinks of its parent directories
+// are resolved. The last element is not resolved since removing a symlink
+// leaves its target alone.
+func relInsideRoot(root, path string) (string, error) {
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, filepath.Join(parent, filepath.Base(path)))
+	if err != nil || !isLocalPattern(rel) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideProject, path)
+	}
+	return rel, nil
+}
+
+// cleanItem describes path without following a final symlink
+func cleanItem(path string) (CleanItem, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return CleanItem{}, err
+	}
+
+	item := CleanItem{Path: path, Size: info.Size(), IsDir: info.IsDir()}
+	if item.IsDir {
+		if item.Size, err = treeSize(path); err != nil {
+			return CleanItem{}, err
+		}
+	}
+	return item, nil
+}
+
+// treeSize returns the total size of the regular files under dir
+func treeSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
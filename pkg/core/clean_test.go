@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsLocalPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"bin", true},
+		{"bin/*.exe", true},
+		{"bin/../out", true},
+		{"./bin", true},
+		{"**", true},
+		{"**/*.test", true},
+		{".", false},
+		{"./", false},
+		{"bin/..", false},
+		{"..", false},
+		{"../x", false},
+		{"../**", false},
+		{"bin/../../x", false},
+		{"**/../..", false},
+	}
+	for _, tt := range tests {
+		if got := isLocalPattern(filepath.FromSlash(tt.pattern)); got != tt.want {
+			t.Errorf("isLocalPattern(%q) = %t, want %t", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+// cleanTree creates a project next to a directory outside it, with
+// symlinks from the project to the outside directory
+func cleanTree(t *testing.T) (root, outside string) {
+	t.Helper()
+	dir := writeFiles(t, map[string]string{
+		"project/go.mod":        "module example.com/p\n",
+		"project/main.go":       "package main\n",
+		"project/bin/app":       "12345",
+		"project/bin/tool":      "123",
+		"project/bin/sub/x":     "1",
+		"project/out.test":      "1234",
+		"project/cmd/a/a.test":  "12",
+		"outside/keep.txt":      "precious",
+		"outside/sub/other.txt": "precious",
+	})
+	root, outside = filepath.Join(dir, "project"), filepath.Join(dir, "outside")
+
+	if err := os.Symlink(outside, filepath.Join(root, "linkdir")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "keep.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	return root, outside
+}
+
+func TestPlanClean(t *testing.T) {
+	root, outside := cleanTree(t)
+	project := NewGoProject(root, testFS{})
+	b := NewGoBuilder(nil)
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []CleanItem // Paths relative to root
+		outside bool        // Refused with ErrOutsideProject
+	}{
+		{
+			name:  "file and directory with its size",
+			paths: []string{"bin", "out.test"},
+			want:  []CleanItem{{Path: "bin", Size: 9, IsDir: true}, {Path: "out.test", Size: 4}},
+		},
+		{
+			name:  "items inside a selected directory are dropped",
+			paths: []string{"bin/app", "bin", "bin/sub/x"},
+			want:  []CleanItem{{Path: "bin", Size: 9, IsDir: true}},
+		},
+		{
+			name:  "duplicate matches are listed once",
+			paths: []string{"out.test", "*.test"},
+			want:  []CleanItem{{Path: "out.test", Size: 4}},
+		},
+		{
+			name:  "glob",
+			paths: []string{"bin/*"},
+			want:  []CleanItem{{Path: "bin/app", Size: 5}, {Path: "bin/sub", Size: 1, IsDir: true}, {Path: "bin/tool", Size: 3}},
+		},
+		{
+			name:  "** matches a single path element",
+			paths: []string{"**/*.test"},
+			want:  nil,
+		},
+		{
+			name:  "** below a directory",
+			paths: []string{"cmd/**/*.test"},
+			want:  []CleanItem{{Path: "cmd/a/a.test", Size: 2}},
+		},
+		{
+			name:  "no match",
+			paths: []string{"dist"},
+			want:  nil,
+		},
+		{
+			name:  "final symlink is removed, not its target",
+			paths: []string{"link.txt"},
+			want:  []CleanItem{{Path: "link.txt", Size: int64(len(filepath.Join(outside, "keep.txt")))}},
+		},
+		{name: "parent directory", paths: []string{"../outside"}, outside: true},
+		{name: "parent glob", paths: []string{"../**"}, outside: true},
+		{name: "root", paths: []string{"."}, outside: true},
+		{name: "root through a subdirectory", paths: []string{"bin/.."}, outside: true},
+		{name: "absolute path", paths: []string{filepath.Join(root, "bin")}, outside: true},
+		{name: "symlinked parent directory", paths: []string{"linkdir/keep.txt"}, outside: true},
+		{name: "glob through a symlinked parent", paths: []string{"linkdir/*"}, outside: true},
+		{name: "refused among allowed patterns", paths: []string{"bin", "../outside"}, outside: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := b.PlanClean(context.Background(), project, CleanConfig{Paths: tt.paths})
+			if tt.outside {
+				if !errors.Is(err, ErrOutsideProject) {
+					t.Fatalf("PlanClean(%q) error = %v, want %v", tt.paths, err, ErrOutsideProject)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanClean(%q): %v", tt.paths, err)
+			}
+
+			var want []CleanItem
+			for _, item := range tt.want {
+				item.Path = filepath.Join(root, filepath.FromSlash(item.Path))
+				want = append(want, item)
+			}
+			if !reflect.DeepEqual(plan.Items, want) {
+				t.Errorf("PlanClean(%q) items = %+v, want %+v", tt.paths, plan.Items, want)
+			}
+			if want := []CleanCommand{{Args: []string{"clean"}}}; !reflect.DeepEqual(plan.Commands, want) {
+				t.Errorf("PlanClean(%q) commands = %+v, want %+v", tt.paths, plan.Commands, want)
+			}
+		})
+	}
+}
+
+func TestPlanCleanCacheCommands(t *testing.T) {
+	root, _ := cleanTree(t)
+	project := NewGoProject(root, testFS{})
+
+	plan, err := NewGoBuilder(nil).PlanClean(context.Background(), project, CleanConfig{TestCache: true})
+	if err != nil {
+		t.Fatalf("PlanClean: %v", err)
+	}
+	want := []CleanCommand{{Args: []string{"clean"}}, {Args: []string{"clean", "-testcache"}}}
+	if !reflect.DeepEqual(plan.Commands, want) {
+		t.Errorf("commands = %+v, want %+v", plan.Commands, want)
+	}
+}
+
+func TestCleanLeavesSymlinkTargets(t *testing.T) {
+	root, outside := cleanTree(t)
+	project := NewGoProject(root, testFS{})
+
+	config := CleanConfig{Paths: []string{"bin", "link.txt"}}
+	if _, err := NewGoBuilder(nil).Clean(context.Background(), project, config, nil); err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+
+	for _, removed := range []string{"bin", "link.txt"} {
+		if _, err := os.Lstat(filepath.Join(root, removed)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Clean: %v", removed, err)
+		}
+	}
+	for _, kept := range []string{"main.go", "linkdir"} {
+		if _, err := os.Lstat(filepath.Join(root, kept)); err != nil {
+			t.Errorf("%s was removed: %v", kept, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(outside, "keep.txt")); err != nil || string(data) != "precious" {
+		t.Errorf("symlink target = %q, %v; want it untouched", data, err)
+	}
+}
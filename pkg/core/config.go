@@ -0,0 +1,219 @@
+// Package core provides per-project configuration for the IDE.
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProjectConfigFile is the project configuration file inside ProjectDataDir
+const ProjectConfigFile = "config.json"
+
+// ProjectConfig holds IDE settings stored with a project in .gox/config.json
+type ProjectConfig struct {
+	RunConfigs   []RunConfig  `json:"run,omitempty"`
+	BuildTargets []string     `json:"targets,omitempty"`      // "os/arch" targets for matrix builds
+	BuildContext string       `json:"buildContext,omitempty"` // "os/arch,tags=a,b" files are classified for
+	Check        *CheckConfig `json:"check,omitempty"`
+	Clean        *CleanConfig `json:"clean,omitempty"`
+}
+
+// RunConfig describes how to run a program from the project
+type RunConfig struct {
+	Name    string            `json:"name"`
+	Package string            `json:"package,omitempty"` // Relative to the project root; defaults to "."
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	EnvFile string            `json:"envFile,omitempty"` // Relative to the project root
+	Tags    []string          `json:"tags,omitempty"`
+	LDFlags string            `json:"ldflags,omitempty"`
+	WorkDir string            `json:"workDir,omitempty"` // Relative to the project root; defaults to it
+}
+
+// DefaultRunConfig returns the configuration equivalent to "go run ."
+func DefaultRunConfig() RunConfig {
+	return RunConfig{Name: "default", Package: "."}
+}
+
+// LoadProjectConfig reads the project configuration.
+// A missing file yields an empty configuration.
+func LoadProjectConfig(projectPath string) (*ProjectConfig, error) {
+	path := filepath.Join(projectPath, ProjectDataDir, ProjectConfigFile)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ProjectConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config ProjectConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// RunConfig returns the run configuration with the given name
+func (c *ProjectConfig) RunConfig(name string) (RunConfig, bool) {
+	for _, rc := range c.RunConfigs {
+		if rc.Name == name {
+			return rc, true
+		}
+	}
+	return RunConfig{}, false
+}
+
+// RunConfigsOrDefault returns the configured run configurations, or the
+// default configuration if there are none
+func (c *ProjectConfig) RunConfigsOrDefault() []RunConfig {
+	if len(c.RunConfigs) == 0 {
+		return []RunConfig{DefaultRunConfig()}
+	}
+	return c.RunConfigs
+}
+
+// MatrixTargets returns the configured matrix build targets, or
+// DefaultBuildTargets if there are none
+func (c *ProjectConfig) MatrixTargets() ([]BuildTarget, error) {
+	if len(c.BuildTargets) == 0 {
+		return DefaultBuildTargets, nil
+	}
+	return ParseBuildTargets(strings.Join(c.BuildTargets, ","))
+}
+
+// BuildContextOrHost returns the configured build context, or the host's
+// if there is none
+func (c *ProjectConfig) BuildContextOrHost() (BuildContext, error) {
+	if c.BuildContext == "" {
+		return HostBuildContext(), nil
+	}
+	return ParseBuildContext(c.BuildContext)
+}
+
+// PackageOrDefault returns the target package, defaulting to "."
+func (rc RunConfig) PackageOrDefault() string {
+	if rc.Package == "" {
+		return "."
+	}
+	return rc.Package
+}
+
+// BuildFlags returns the go build flags for the configuration
+func (rc RunConfig) BuildFlags() []string {
+	var flags []string
+	if len(rc.Tags) > 0 {
+		flags = append(flags, "-tags", strings.Join(rc.Tags, ","))
+	}
+	if rc.LDFlags != "" {
+		flags = append(flags, "-ldflags", rc.LDFlags)
+	}
+	return flags
+}
+
+// Dir returns the absolute working directory for the program
+func (rc RunConfig) Dir(projectPath string) string {
+	if rc.WorkDir == "" {
+		return projectPath
+	}
+	if filepath.IsAbs(rc.WorkDir) {
+		return rc.WorkDir
+	}
+	return filepath.Join(projectPath, rc.WorkDir)
+}
+
+// Environ returns the program environment: the IDE's environment,
+// overridden by the env file, overridden by Env
+func (rc RunConfig) Environ(projectPath string) ([]string, error) {
+	overrides, err := rc.EnvOverrides(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// exec.Cmd uses the last value for duplicate keys
+	return append(os.Environ(), overrides...), nil
+}
+
+// EnvOverrides returns the variables the configuration sets on top of the
+// IDE's environment: the env file, overridden by Env
+func (rc RunConfig) EnvOverrides(projectPath string) ([]string, error) {
+	var env []string
+
+	if rc.EnvFile != "" {
+		path := rc.EnvFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectPath, path)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("env file: %w", err)
+		}
+		defer f.Close()
+
+		fileEnv, err := ParseEnvFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("env file %s: %w", rc.EnvFile, err)
+		}
+		env = append(env, fileEnv...)
+	}
+
+	for key, value := range rc.Env {
+		env = append(env, key+"="+value)
+	}
+
+	return env, nil
+}
+
+// ParseEnvFile parses a .env file into KEY=VALUE pairs.
+// Blank lines, # comments and an optional "export " prefix are ignored;
+// values may be single- or double-quoted.
+func ParseEnvFile(r io.Reader) ([]string, error) {
+	var env []string
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			// Strip trailing comments from unquoted values
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		env = append(env, key+"="+value)
+	}
+
+	return env, scanner.Err()
+}
@@ -0,0 +1,270 @@
+// Package core provides parsing of Go coverage profiles.
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoverState is the coverage state of a single source line
+type CoverState int
+
+const (
+	CoverNone      CoverState = iota // Not instrumented
+	CoverCovered                     // Executed at least once
+	CoverUncovered                   // Instrumented but never executed
+)
+
+// CoverBlock is a block of statements from a coverage profile
+type CoverBlock struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+// FileCoverage holds the coverage blocks of a single file
+type FileCoverage struct {
+	Name    string // Name in the profile, e.g. "example.com/m/pkg/file.go"
+	Path    string // Absolute local path, empty if outside the project
+	Package string // Import path of the containing package
+	Blocks  []CoverBlock
+}
+
+// Statements returns the number of covered and total statements
+func (f *FileCoverage) Statements() (covered, total int) {
+	for _, b := range f.Blocks {
+		total += b.NumStmt
+		if b.Count > 0 {
+			covered += b.NumStmt
+		}
+	}
+	return covered, total
+}
+
+// Percent returns the statement coverage percentage of the file
+func (f *FileCoverage) Percent() float64 {
+	return percent(f.Statements())
+}
+
+// Lines returns the coverage state of every instrumented line.
+// A line touched by any executed block counts as covered.
+func (f *FileCoverage) Lines() map[int]CoverState {
+	lines := make(map[int]CoverState)
+	for _, b := range f.Blocks {
+		for line := b.StartLine; line <= b.EndLine; line++ {
+			if b.Count > 0 {
+				lines[line] = CoverCovered
+			} else if lines[line] != CoverCovered {
+				lines[line] = CoverUncovered
+			}
+		}
+	}
+	return lines
+}
+
+// PackageCoverage summarizes coverage of a package
+type PackageCoverage struct {
+	ImportPath string
+	Covered    int
+	Total      int
+}
+
+// Percent returns the statement coverage percentage of the package
+func (p PackageCoverage) Percent() float64 {
+	return percent(p.Covered, p.Total)
+}
+
+// CoverageReport is a parsed coverage profile
+type CoverageReport struct {
+	Mode  string // set, count or atomic
+	Files []*FileCoverage
+	Tests *TestReport // Results of the test run that produced the profile
+}
+
+// Packages returns per-package coverage sorted by import path
+func (r *CoverageReport) Packages() []PackageCoverage {
+	byPkg := make(map[string]*PackageCoverage)
+	for _, f := range r.Files {
+		pkg, ok := byPkg[f.Package]
+		if !ok {
+			pkg = &PackageCoverage{ImportPath: f.Package}
+			byPkg[f.Package] = pkg
+		}
+		covered, total := f.Statements()
+		pkg.Covered += covered
+		pkg.Total += total
+	}
+
+	pkgs := make([]PackageCoverage, 0, len(byPkg))
+	for _, pkg := range byPkg {
+		pkgs = append(pkgs, *pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].ImportPath < pkgs[j].ImportPath
+	})
+	return pkgs
+}
+
+// Percent returns the total statement coverage percentage
+func (r *CoverageReport) Percent() float64 {
+	var covered, total int
+	for _, f := range r.Files {
+		c, t := f.Statements()
+		covered += c
+		total += t
+	}
+	return percent(covered, total)
+}
+
+// File returns the coverage of the file at the given local path
+func (r *CoverageReport) File(path string) *FileCoverage {
+	if r == nil {
+		return nil
+	}
+	for _, f := range r.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// ParseCoverProfile parses a coverage profile written by go test -coverprofile.
+// Profile file names inside module modulePath are resolved against root.
+func ParseCoverProfile(r io.Reader, modulePath, root string) (*CoverageReport, error) {
+	report := &CoverageReport{}
+	files := make(map[string]*FileCoverage)
+
+	// Blocks may repeat across test binaries; merge them by position
+	type blockKey struct {
+		name                                 string
+		startLine, startCol, endLine, endCol int
+	}
+	blocks := make(map[blockKey]int) // index into the file's Blocks
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if mode, ok := strings.CutPrefix(line, "mode: "); ok {
+			report.Mode = mode
+			continue
+		}
+
+		name, block, err := parseCoverLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("coverage profile line %d: %w", lineNum, err)
+		}
+
+		file, ok := files[name]
+		if !ok {
+			file = &FileCoverage{
+				Name:    name,
+				Path:    resolveModuleFile(name, modulePath, root),
+				Package: path.Dir(name),
+			}
+			files[name] = file
+			report.Files = append(report.Files, file)
+		}
+
+		key := blockKey{name, block.StartLine, block.StartCol, block.EndLine, block.EndCol}
+		if i, ok := blocks[key]; ok {
+			if report.Mode == "set" {
+				file.Blocks[i].Count = max(file.Blocks[i].Count, block.Count)
+			} else {
+				file.Blocks[i].Count += block.Count
+			}
+			continue
+		}
+		blocks[key] = len(file.Blocks)
+		file.Blocks = append(file.Blocks, block)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Name < report.Files[j].Name
+	})
+	return report, nil
+}
+
+// parseCoverLine parses "name.go:line.col,line.col numStmt count"
+func parseCoverLine(line string) (string, CoverBlock, error) {
+	var block CoverBlock
+
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 {
+		return "", block, fmt.Errorf("missing file name in %q", line)
+	}
+	name, rest := line[:colon], line[colon+1:]
+
+	if _, err := fmt.Sscanf(rest, "%d.%d,%d.%d %d %d",
+		&block.StartLine, &block.StartCol, &block.EndLine, &block.EndCol,
+		&block.NumStmt, &block.Count); err != nil {
+		return "", block, fmt.Errorf("malformed block %q: %w", rest, err)
+	}
+
+	return name, block, nil
+}
+
+// resolveModuleFile maps an import-path-style file name to a local path
+func resolveModuleFile(name, modulePath, root string) string {
+	if modulePath == "" {
+		return ""
+	}
+	rel, ok := strings.CutPrefix(name, modulePath+"/")
+	if !ok {
+		return ""
+	}
+	return filepath.Join(root, filepath.FromSlash(rel))
+}
+
+// ReadModulePath returns the module path declared in dir/go.mod
+func ReadModulePath(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "module"); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			modulePath := strings.TrimSpace(rest)
+			// Strip a trailing comment and optional quotes
+			if i := strings.Index(modulePath, "//"); i >= 0 {
+				modulePath = strings.TrimSpace(modulePath[:i])
+			}
+			if unquoted, err := strconv.Unquote(modulePath); err == nil {
+				modulePath = unquoted
+			}
+			return modulePath, nil
+		}
+	}
+
+	return "", fmt.Errorf("no module directive in %s", filepath.Join(dir, "go.mod"))
+}
+
+// percent returns covered/total as a percentage, 0 if total is 0
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) * 100 / float64(total)
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCoverProfile(t *testing.T) {
+	root := filepath.FromSlash("/work/m")
+	profile := `mode: count
+example.com/m/main.go:5.13,7.2 2 1
+example.com/m/pkg/util.go:3.20,5.2 1 0
+example.com/m/pkg/util.go:7.20,9.16 2 3
+example.com/m/pkg/util.go:9.16,11.3 1 0
+
+example.com/mod/x.go:1.1,2.2 1 1
+golang.org/x/dep/dep.go:1.1,2.2 4 0
+example.com/m/pkg/util.go:3.20,5.2 1 2
+`
+	report, err := ParseCoverProfile(strings.NewReader(profile), "example.com/m", root)
+	if err != nil {
+		t.Fatalf("ParseCoverProfile: %v", err)
+	}
+	if report.Mode != "count" {
+		t.Errorf("Mode = %q, want count", report.Mode)
+	}
+
+	type file struct {
+		Name, Path, Package string
+		Blocks              int
+	}
+	var files []file
+	for _, f := range report.Files {
+		files = append(files, file{f.Name, f.Path, f.Package, len(f.Blocks)})
+	}
+	// Only files of the module map to local paths; a module whose path
+	// merely starts with the same letters does not
+	want := []file{
+		{"example.com/m/main.go", filepath.Join(root, "main.go"), "example.com/m", 1},
+		{"example.com/m/pkg/util.go", filepath.Join(root, "pkg", "util.go"), "example.com/m/pkg", 3},
+		{"example.com/mod/x.go", "", "example.com/mod", 1},
+		{"golang.org/x/dep/dep.go", "", "golang.org/x/dep", 1},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("files = %+v, want %+v", files, want)
+	}
+
+	// The block repeated by a second test binary is merged, adding counts
+	util := report.File(filepath.Join(root, "pkg", "util.go"))
+	if util == nil {
+		t.Fatal("File(util.go) = nil")
+	}
+	if want := (CoverBlock{StartLine: 3, StartCol: 20, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 2}); util.Blocks[0] != want {
+		t.Errorf("merged block = %+v, want %+v", util.Blocks[0], want)
+	}
+	if covered, total := util.Statements(); covered != 3 || total != 4 {
+		t.Errorf("Statements() = %d, %d; want 3, 4", covered, total)
+	}
+
+	// Line 9 ends a covered block and starts an uncovered one
+	wantLines := map[int]CoverState{
+		3: CoverCovered, 4: CoverCovered, 5: CoverCovered,
+		7: CoverCovered, 8: CoverCovered, 9: CoverCovered,
+		10: CoverUncovered, 11: CoverUncovered,
+	}
+	if got := util.Lines(); !reflect.DeepEqual(got, wantLines) {
+		t.Errorf("Lines() = %v, want %v", got, wantLines)
+	}
+
+	wantPkgs := []PackageCoverage{
+		{ImportPath: "example.com/m", Covered: 2, Total: 2},
+		{ImportPath: "example.com/m/pkg", Covered: 3, Total: 4},
+		{ImportPath: "example.com/mod", Covered: 1, Total: 1},
+		{ImportPath: "golang.org/x/dep", Covered: 0, Total: 4},
+	}
+	if got := report.Packages(); !reflect.DeepEqual(got, wantPkgs) {
+		t.Errorf("Packages() = %+v, want %+v", got, wantPkgs)
+	}
+	if got, want := report.Percent(), float64(6)*100/11; got != want {
+		t.Errorf("Percent() = %v, want %v", got, want)
+	}
+	if report.File(filepath.Join(root, "missing.go")) != nil {
+		t.Error("File of a path not in the profile is not nil")
+	}
+}
+
+func TestParseCoverProfileSetMode(t *testing.T) {
+	// In set mode a block repeated across test binaries is covered if
+	// any of them ran it
+	profile := "mode: set\n" +
+		"example.com/m/a.go:1.1,3.2 2 1\n" +
+		"example.com/m/a.go:1.1,3.2 2 0\n" +
+		"example.com/m/a.go:1.1,3.2 2 1\n"
+	report, err := ParseCoverProfile(strings.NewReader(profile), "", "")
+	if err != nil {
+		t.Fatalf("ParseCoverProfile: %v", err)
+	}
+	f := report.Files[0]
+	if len(f.Blocks) != 1 || f.Blocks[0].Count != 1 {
+		t.Errorf("blocks = %+v, want one block with count 1", f.Blocks)
+	}
+	if f.Path != "" {
+		t.Errorf("Path = %q without a module path, want none", f.Path)
+	}
+	if got := f.Percent(); got != 100 {
+		t.Errorf("Percent() = %v, want 100", got)
+	}
+}
+
+func TestParseCoverProfileMalformed(t *testing.T) {
+	tests := []struct {
+		name, line string
+	}{
+		{"no file name", "1.1,2.2 1 1"},
+		{"missing count", "example.com/m/a.go:1.1,2.2 1"},
+		{"not a number", "example.com/m/a.go:1.x,2.2 1 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCoverProfile(strings.NewReader("mode: set\n"+tt.line+"\n"), "example.com/m", "/m")
+			if err == nil || !strings.Contains(err.Error(), "line 2") {
+				t.Errorf("ParseCoverProfile error = %v, want one for line 2", err)
+			}
+		})
+	}
+}
+
+func TestReadModulePath(t *testing.T) {
+	tests := []struct {
+		gomod, want string
+	}{
+		{"module example.com/m\n\ngo 1.25\n", "example.com/m"},
+		{"// comment\nmodule\texample.com/tab // trailing\n", "example.com/tab"},
+		{"module \"example.com/quoted\"\n", "example.com/quoted"},
+		{"modules are not here\n", ""},
+	}
+	for _, tt := range tests {
+		dir := writeFiles(t, map[string]string{"go.mod": tt.gomod})
+		got, err := ReadModulePath(dir)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ReadModulePath(%q) = %q, want an error", tt.gomod, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ReadModulePath(%q) = %q, %v; want %q", tt.gomod, got, err, tt.want)
+		}
+	}
+}
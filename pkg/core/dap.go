@@ -0,0 +1,274 @@
+// Package core provides a client for the Debug Adapter Protocol.
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxDAPMessageSize bounds a single protocol message
+const maxDAPMessageSize = 64 * 1024 * 1024 // 64MB
+
+// ErrDAPClosed is returned for requests made after the debug adapter
+// connection was closed
+var ErrDAPClosed = errors.New("debug adapter connection closed")
+
+// DAPMessage is a Debug Adapter Protocol request, response or event
+type DAPMessage struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"` // "request", "response" or "event"
+
+	// Requests and responses
+	Command   string          `json:"command,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	// Responses
+	RequestSeq int    `json:"request_seq,omitempty"`
+	Success    bool   `json:"success,omitempty"`
+	Message    string `json:"message,omitempty"`
+
+	// Events
+	Event string `json:"event,omitempty"`
+
+	// Responses and events
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// ReadDAPMessage reads one message framed by a Content-Length header
+func ReadDAPMessage(r *bufio.Reader) (*DAPMessage, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+
+	if length < 0 || length > maxDAPMessageSize {
+		return nil, fmt.Errorf("invalid Content-Length: %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	var msg DAPMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return &msg, nil
+}
+
+// WriteDAPMessage writes msg framed by a Content-Length header
+func WriteDAPMessage(w io.Writer, msg *DAPMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(data))
+	buf.Write(data)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// DAPClient sends requests to a debug adapter and matches them with
+// their responses. Events are passed to a callback on the goroutine
+// reading the connection, so the callback must not wait for responses.
+type DAPClient struct {
+	conn    io.ReadWriteCloser
+	onEvent func(*DAPMessage)
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	seq     int
+	pending map[int]chan *DAPMessage
+	err     error // Why the connection closed
+	done    chan struct{}
+}
+
+// NewDAPClient creates a client speaking DAP over conn and starts reading
+// from it. onEvent receives every event the adapter sends.
+func NewDAPClient(conn io.ReadWriteCloser, onEvent func(*DAPMessage)) *DAPClient {
+	c := &DAPClient{
+		conn:    conn,
+		onEvent: onEvent,
+		pending: make(map[int]chan *DAPMessage),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// readLoop dispatches responses and events until the connection closes
+func (c *DAPClient) readLoop() {
+	r := bufio.NewReader(c.conn)
+
+	var err error
+	for {
+		var msg *DAPMessage
+		if msg, err = ReadDAPMessage(r); err != nil {
+			break
+		}
+
+		switch msg.Type {
+		case "response":
+			c.mu.Lock()
+			ch, ok := c.pending[msg.RequestSeq]
+			delete(c.pending, msg.RequestSeq)
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case "event":
+			if c.onEvent != nil {
+				c.onEvent(msg)
+			}
+		}
+		// Reverse requests such as runInTerminal are never advertised
+	}
+
+	c.mu.Lock()
+	c.err = ErrDAPClosed
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.err = fmt.Errorf("%w: %v", ErrDAPClosed, err)
+	}
+	for seq, ch := range c.pending {
+		close(ch)
+		delete(c.pending, seq)
+	}
+	c.mu.Unlock()
+
+	close(c.done)
+}
+
+// Send sends a request without waiting. The returned channel receives the
+// response, or is closed without one if the connection closes first.
+func (c *DAPClient) Send(command string, args any) (<-chan *DAPMessage, error) {
+	var raw json.RawMessage
+	if args != nil {
+		var err error
+		if raw, err = json.Marshal(args); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.seq++
+	seq := c.seq
+	ch := make(chan *DAPMessage, 1)
+	c.pending[seq] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := WriteDAPMessage(c.conn, &DAPMessage{
+		Seq:       seq,
+		Type:      "request",
+		Command:   command,
+		Arguments: raw,
+	})
+	c.writeMu.Unlock()
+
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, seq)
+		c.mu.Unlock()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Call sends a request and waits for its response. The response body is
+// decoded into body unless it is nil.
+func (c *DAPClient) Call(ctx context.Context, command string, args, body any) error {
+	ch, err := c.Send(command, args)
+	if err != nil {
+		return err
+	}
+	return c.Wait(ctx, command, ch, body)
+}
+
+// Wait waits for the response to a request made with Send
+func (c *DAPClient) Wait(ctx context.Context, command string, ch <-chan *DAPMessage, body any) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp, ok := <-ch:
+		return c.result(command, resp, ok, body)
+	}
+}
+
+// result converts a response received from a Send channel into an error
+// and decodes its body
+func (c *DAPClient) result(command string, resp *DAPMessage, ok bool, body any) error {
+	if !ok {
+		return c.Err()
+	}
+
+	if !resp.Success {
+		// Adapters put the details into an optional error body
+		var failure struct {
+			Error *struct {
+				Format string `json:"format"`
+			} `json:"error"`
+		}
+		message := resp.Message
+		if json.Unmarshal(resp.Body, &failure) == nil && failure.Error != nil && failure.Error.Format != "" {
+			message = failure.Error.Format
+		}
+		return fmt.Errorf("%s: %s", command, message)
+	}
+
+	if body != nil && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, body); err != nil {
+			return fmt.Errorf("%s: invalid response: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// Err returns why the connection closed, or nil while it is open
+func (c *DAPClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Done returns a channel that is closed when the connection closes
+func (c *DAPClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection and waits for the reader to finish
+func (c *DAPClient) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
@@ -0,0 +1,948 @@
+// Package core provides debugging of Go programs with Delve over DAP.
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Debugger errors
+var (
+	ErrNotDebugging      = errors.New("no debug session")
+	ErrAlreadyDebugging  = errors.New("a debug session is already running")
+	ErrNotStopped        = errors.New("program is not stopped")
+	ErrNoBreakpoint      = errors.New("no breakpoint")
+	ErrDelveNotInstalled = errors.New("dlv not found (go install github.com/go-delve/delve/cmd/dlv@latest)")
+)
+
+const (
+	// DelveCommand is the Delve executable started by Launch
+	DelveCommand = "dlv"
+
+	// DebugStartTimeout is how long Launch waits for Delve to listen
+	DebugStartTimeout = 10 * time.Second
+
+	// DebugLaunchTimeout bounds a whole Launch from the IDE, which
+	// includes building the program
+	DebugLaunchTimeout = 2 * time.Minute
+
+	// DebugStackDepth is the number of frames StackTrace returns by default
+	DebugStackDepth = 50
+)
+
+// delveListenPrefix starts the line dlv dap prints once it accepts clients
+const delveListenPrefix = "DAP server listening at:"
+
+// DebugState is the state of the debugged program
+type DebugState string
+
+const (
+	DebugIdle    DebugState = "idle"    // No debug session
+	DebugRunning DebugState = "running" // Running until a breakpoint or step completes
+	DebugStopped DebugState = "stopped" // Paused; stack and variables can be inspected
+)
+
+// DebugCommand resumes a stopped program. The values are DAP request names.
+type DebugCommand string
+
+const (
+	DebugContinue DebugCommand = "continue"
+	DebugNext     DebugCommand = "next"    // Step over
+	DebugStepIn   DebugCommand = "stepIn"  // Step into calls
+	DebugStepOut  DebugCommand = "stepOut" // Run until the function returns
+)
+
+// DebugEventKind identifies a debug event
+type DebugEventKind string
+
+const (
+	DebugEventStopped DebugEventKind = "stopped"
+	DebugEventExited  DebugEventKind = "exited" // The session ended
+)
+
+// DebugEvent reports a change of the debugged program's state
+type DebugEvent struct {
+	Kind     DebugEventKind
+	Reason   string      // Why the program stopped, e.g. "breakpoint" or "step"
+	ThreadID int         // Goroutine that stopped
+	Frame    *StackFrame // Top frame of the stopped goroutine, if known
+	ExitCode int
+}
+
+// Breakpoint is a line breakpoint. Breakpoints persist across sessions.
+type Breakpoint struct {
+	File     string // Absolute path
+	Line     int
+	Verified bool   // Accepted by the debug adapter in the current session
+	Message  string // Why the adapter did not verify it
+}
+
+// DebugThread is a goroutine of the debugged program
+type DebugThread struct {
+	ID   int
+	Name string
+}
+
+// StackFrame is a frame of a goroutine's call stack
+type StackFrame struct {
+	ID     int
+	Name   string
+	File   string
+	Line   int
+	Column int
+}
+
+// Variable is a variable in scope of a stack frame
+type Variable struct {
+	Name      string
+	Value     string
+	Type      string
+	Reference int // Non-zero if the variable has children
+}
+
+// debugSession is a connection to a debug adapter for one program run
+type debugSession struct {
+	client      *DAPClient
+	out         OutputSink
+	events      chan DebugEvent
+	initialized chan struct{}
+	initOnce    sync.Once
+	stopAdapter func() // Stops dlv when it was started by Launch
+	done        chan struct{}
+
+	// Guarded by Debugger.mu
+	state    DebugState
+	threadID int
+	exitCode int
+	started  bool // The handshake completed
+}
+
+// Debugger runs one debug session at a time against Delve's DAP server,
+// either started by Launch or already listening for Connect. Breakpoints
+// can be set before a session starts.
+type Debugger struct {
+	logger Logger
+
+	mu          sync.Mutex
+	session     *debugSession
+	breakpoints map[string][]Breakpoint // By file, sorted by line
+	onEvent     func(DebugEvent)
+}
+
+// NewDebugger creates a debugger with no session
+func NewDebugger(logger Logger) *Debugger {
+	return &Debugger{
+		logger:      logger,
+		breakpoints: make(map[string][]Breakpoint),
+	}
+}
+
+// SetOnEvent sets a callback invoked from a background goroutine when the
+// program stops or the session ends. The callback may call the debugger.
+func (d *Debugger) SetOnEvent(callback func(DebugEvent)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onEvent = callback
+}
+
+// State returns the state of the debugged program
+func (d *Debugger) State() DebugState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.session == nil {
+		return DebugIdle
+	}
+	return d.session.state
+}
+
+// Launch starts "dlv dap" in the project and debugs the program of a run
+// configuration. It returns once the program runs; program and Delve
+// output is streamed to out.
+func (d *Debugger) Launch(ctx context.Context, project Project, config RunConfig, out OutputSink) error {
+	if !project.IsGoProject() {
+		return ErrNotGoProject
+	}
+	if d.State() != DebugIdle {
+		return ErrAlreadyDebugging
+	}
+	if _, err := exec.LookPath(DelveCommand); err != nil {
+		return ErrDelveNotInstalled
+	}
+
+	args, err := delveLaunchArgs(project, config)
+	if err != nil {
+		return err
+	}
+	out = sinkOrDiscard(out)
+
+	// dlv outlives ctx; it is stopped with the session
+	adapterCtx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(adapterCtx, DelveCommand, "dap", "--listen=127.0.0.1:0")
+	cmd.Dir = project.Path()
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = ProcessStopTimeout
+
+	addrCh := make(chan string, 1)
+	sink := OutputSinkFunc(func(line OutputLine) {
+		if addr, ok := strings.CutPrefix(line.Text, delveListenPrefix); ok {
+			select {
+			case addrCh <- strings.TrimSpace(addr):
+			default:
+			}
+			return
+		}
+		out.WriteLine(line)
+	})
+
+	wait, err := startCommand(cmd, sink)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("start %s: %w", DelveCommand, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- wait() }()
+	stopAdapter := func() {
+		cancel()
+		<-exited
+	}
+
+	if d.logger != nil {
+		d.logger.Info("Delve started",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "pid", Value: cmd.Process.Pid})
+	}
+
+	timeout := time.NewTimer(DebugStartTimeout)
+	defer timeout.Stop()
+
+	var addr string
+	select {
+	case addr = <-addrCh:
+	case err := <-exited:
+		cancel()
+		return fmt.Errorf("%s exited before listening: %v", DelveCommand, err)
+	case <-timeout.C:
+		stopAdapter()
+		return fmt.Errorf("%s did not start listening within %s", DelveCommand, DebugStartTimeout)
+	case <-ctx.Done():
+		stopAdapter()
+		return ctx.Err()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		stopAdapter()
+		return err
+	}
+
+	return d.start(ctx, conn, args, out, stopAdapter)
+}
+
+// Connect debugs the program of a run configuration through a debug
+// adapter already listening at addr, such as "dlv dap --listen=:2345"
+func (d *Debugger) Connect(ctx context.Context, addr string, project Project, config RunConfig, out OutputSink) error {
+	if d.State() != DebugIdle {
+		return ErrAlreadyDebugging
+	}
+
+	args, err := delveLaunchArgs(project, config)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return d.start(ctx, conn, args, sinkOrDiscard(out), nil)
+}
+
+// delveLaunchArgs returns the arguments of Delve's launch request for a
+// run configuration
+func delveLaunchArgs(project Project, config RunConfig) (map[string]any, error) {
+	// Delve adds these to its own environment
+	overrides, err := config.EnvOverrides(project.Path())
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string, len(overrides))
+	for _, kv := range overrides {
+		if key, value, ok := strings.Cut(kv, "="); ok && key != "" {
+			env[key] = value
+		}
+	}
+
+	// Delve splits buildFlags like a shell; quote values with spaces
+	var buildFlags []string
+	for _, flag := range config.BuildFlags() {
+		if strings.ContainsAny(flag, " \t\"'") {
+			flag = strconv.Quote(flag)
+		}
+		buildFlags = append(buildFlags, flag)
+	}
+
+	// Keep Delve's binary out of the project so watchers ignore it
+	name := config.Name
+	if name == "" {
+		name = project.Name()
+	}
+
+	args := map[string]any{
+		"request":     "launch",
+		"mode":        "debug",
+		"program":     filepath.Join(project.Path(), config.PackageOrDefault()),
+		"output":      filepath.Join(os.TempDir(), "gox-debug-"+filepath.Base(name)),
+		"cwd":         config.Dir(project.Path()),
+		"env":         env,
+		"stopOnEntry": false,
+	}
+	if len(config.Args) > 0 {
+		args["args"] = config.Args
+	}
+	if len(buildFlags) > 0 {
+		args["buildFlags"] = strings.Join(buildFlags, " ")
+	}
+	return args, nil
+}
+
+// start performs the DAP handshake on conn: initialize, launch, send the
+// breakpoints once the adapter is initialized, then configurationDone
+func (d *Debugger) start(ctx context.Context, conn io.ReadWriteCloser, launchArgs map[string]any, out OutputSink, stopAdapter func()) error {
+	s := &debugSession{
+		out:         out,
+		events:      make(chan DebugEvent, 64),
+		initialized: make(chan struct{}),
+		stopAdapter: stopAdapter,
+		done:        make(chan struct{}),
+		state:       DebugRunning,
+	}
+	s.client = NewDAPClient(conn, func(msg *DAPMessage) {
+		d.handleEvent(s, msg)
+	})
+
+	d.mu.Lock()
+	if d.session != nil {
+		d.mu.Unlock()
+		s.client.Close()
+		if stopAdapter != nil {
+			stopAdapter()
+		}
+		return ErrAlreadyDebugging
+	}
+	d.session = s
+	d.mu.Unlock()
+
+	go d.dispatch(s)
+
+	// A failed start still ends the session through dispatch
+	fail := func(err error) error {
+		s.client.Close()
+		<-s.done
+		return err
+	}
+
+	initArgs := map[string]any{
+		"clientID":        "gox",
+		"clientName":      "GoX IDE",
+		"adapterID":       "go",
+		"pathFormat":      "path",
+		"linesStartAt1":   true,
+		"columnsStartAt1": true,
+	}
+	if err := s.client.Call(ctx, "initialize", initArgs, nil); err != nil {
+		return fail(err)
+	}
+
+	launch, err := s.client.Send("launch", launchArgs)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Adapters may answer launch before or after the initialized event
+	launched := false
+	select {
+	case <-s.initialized:
+	case resp, ok := <-launch:
+		if err := s.client.result("launch", resp, ok, nil); err != nil {
+			return fail(err)
+		}
+		launched = true
+		select {
+		case <-s.initialized:
+		case <-s.client.Done():
+			return fail(s.client.Err())
+		case <-ctx.Done():
+			return fail(ctx.Err())
+		}
+	case <-s.client.Done():
+		return fail(s.client.Err())
+	case <-ctx.Done():
+		return fail(ctx.Err())
+	}
+
+	d.mu.Lock()
+	files := make([]string, 0, len(d.breakpoints))
+	for file := range d.breakpoints {
+		files = append(files, file)
+	}
+	d.mu.Unlock()
+	for _, file := range files {
+		if err := d.syncBreakpoints(ctx, s, file); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := s.client.Call(ctx, "configurationDone", nil, nil); err != nil {
+		return fail(err)
+	}
+	if !launched {
+		if err := s.client.Wait(ctx, "launch", launch, nil); err != nil {
+			return fail(err)
+		}
+	}
+
+	d.mu.Lock()
+	s.started = true
+	d.mu.Unlock()
+
+	if d.logger != nil {
+		d.logger.Info("Debug session started", Field{Key: "program", Value: launchArgs["program"]})
+	}
+	return nil
+}
+
+// handleEvent updates the session for an adapter event. It runs on the
+// client's reading goroutine, so anything that needs requests is queued
+// for dispatch.
+func (d *Debugger) handleEvent(s *debugSession, msg *DAPMessage) {
+	switch msg.Event {
+	case "initialized":
+		s.initOnce.Do(func() { close(s.initialized) })
+
+	case "output":
+		var body struct {
+			Category string `json:"category"`
+			Output   string `json:"output"`
+		}
+		if json.Unmarshal(msg.Body, &body) != nil || body.Category == "telemetry" {
+			return
+		}
+		stream := StreamStdout
+		if body.Category == "stderr" {
+			stream = StreamStderr
+		}
+		for _, text := range strings.Split(strings.TrimSuffix(body.Output, "\n"), "\n") {
+			s.out.WriteLine(OutputLine{Stream: stream, Text: strings.TrimSuffix(text, "\r"), Time: time.Now()})
+		}
+
+	case "stopped":
+		var body struct {
+			Reason   string `json:"reason"`
+			ThreadID int    `json:"threadId"`
+		}
+		json.Unmarshal(msg.Body, &body)
+
+		d.mu.Lock()
+		s.state = DebugStopped
+		if body.ThreadID != 0 {
+			s.threadID = body.ThreadID
+		}
+		threadID := s.threadID
+		d.mu.Unlock()
+
+		s.events <- DebugEvent{Kind: DebugEventStopped, Reason: body.Reason, ThreadID: threadID}
+
+	case "continued":
+		d.mu.Lock()
+		s.state = DebugRunning
+		d.mu.Unlock()
+
+	case "exited":
+		var body struct {
+			ExitCode int `json:"exitCode"`
+		}
+		json.Unmarshal(msg.Body, &body)
+
+		d.mu.Lock()
+		s.exitCode = body.ExitCode
+		d.mu.Unlock()
+
+	case "terminated":
+		s.events <- DebugEvent{Kind: DebugEventExited}
+	}
+}
+
+// dispatch delivers the queued events of a session to the event callback
+// and ends the session when the program terminates or the connection
+// closes
+func (d *Debugger) dispatch(s *debugSession) {
+	defer close(s.done)
+
+	for {
+		select {
+		case ev := <-s.events:
+			if d.deliver(s, ev) {
+				return
+			}
+		case <-s.client.Done():
+			// Deliver what arrived before the connection closed
+			for {
+				select {
+				case ev := <-s.events:
+					if d.deliver(s, ev) {
+						return
+					}
+				default:
+					d.deliver(s, DebugEvent{Kind: DebugEventExited})
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver completes an event and passes it to the callback. It reports
+// whether the event ended the session.
+func (d *Debugger) deliver(s *debugSession, ev DebugEvent) bool {
+	switch ev.Kind {
+	case DebugEventStopped:
+		ctx, cancel := context.WithTimeout(context.Background(), DebugStartTimeout)
+		if frames, err := d.stackTrace(ctx, s, ev.ThreadID, 1); err == nil && len(frames) > 0 {
+			ev.Frame = &frames[0]
+		}
+		cancel()
+	case DebugEventExited:
+		d.end(s)
+		d.mu.Lock()
+		ev.ExitCode = s.exitCode
+		d.mu.Unlock()
+	}
+
+	// A session that failed to start is reported by the starting call
+	d.mu.Lock()
+	callback := d.onEvent
+	if !s.started {
+		callback = nil
+	}
+	d.mu.Unlock()
+	if callback != nil {
+		callback(ev)
+	}
+	return ev.Kind == DebugEventExited
+}
+
+// end disconnects from the adapter, stops Delve if it was launched and
+// clears the session
+func (d *Debugger) end(s *debugSession) {
+	select {
+	case <-s.client.Done():
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), ProcessStopTimeout)
+		s.client.Call(ctx, "disconnect", map[string]any{"terminateDebuggee": true}, nil)
+		cancel()
+	}
+	s.client.Close()
+	if s.stopAdapter != nil {
+		s.stopAdapter()
+	}
+
+	d.mu.Lock()
+	if d.session == s {
+		d.session = nil
+	}
+	for file, bps := range d.breakpoints {
+		for i := range bps {
+			bps[i].Verified = false
+		}
+		d.breakpoints[file] = bps
+	}
+	d.mu.Unlock()
+
+	if d.logger != nil {
+		d.logger.Info("Debug session ended")
+	}
+}
+
+// Stop terminates the debugged program and waits for the session to end
+func (d *Debugger) Stop() error {
+	s, err := d.current()
+	if err != nil {
+		return err
+	}
+
+	// Closing the connection makes dispatch end the session
+	ctx, cancel := context.WithTimeout(context.Background(), ProcessStopTimeout)
+	s.client.Call(ctx, "disconnect", map[string]any{"terminateDebuggee": true}, nil)
+	cancel()
+	s.client.Close()
+	<-s.done
+
+	return nil
+}
+
+// current returns the active session
+func (d *Debugger) current() (*debugSession, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.session == nil {
+		return nil, ErrNotDebugging
+	}
+	return d.session, nil
+}
+
+// stopped returns the active session and its stopped goroutine
+func (d *Debugger) stopped() (*debugSession, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.session == nil {
+		return nil, 0, ErrNotDebugging
+	}
+	if d.session.state != DebugStopped {
+		return nil, 0, ErrNotStopped
+	}
+	return d.session, d.session.threadID, nil
+}
+
+// Resume continues or steps the stopped program. It returns once the
+// adapter has accepted the command; the next stop is reported as an event.
+func (d *Debugger) Resume(ctx context.Context, command DebugCommand) error {
+	s, threadID, err := d.stopped()
+	if err != nil {
+		return err
+	}
+
+	// The stopped event may arrive before the response
+	d.mu.Lock()
+	s.state = DebugRunning
+	d.mu.Unlock()
+
+	if err := s.client.Call(ctx, string(command), map[string]any{"threadId": threadID}, nil); err != nil {
+		d.mu.Lock()
+		if s.state == DebugRunning {
+			s.state = DebugStopped
+		}
+		d.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Pause interrupts the running program
+func (d *Debugger) Pause(ctx context.Context) error {
+	s, err := d.current()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	threadID := s.threadID
+	d.mu.Unlock()
+
+	return s.client.Call(ctx, "pause", map[string]any{"threadId": threadID}, nil)
+}
+
+// Goroutines returns the goroutines of the debugged program
+func (d *Debugger) Goroutines(ctx context.Context) ([]DebugThread, error) {
+	s, err := d.current()
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Threads []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"threads"`
+	}
+	if err := s.client.Call(ctx, "threads", nil, &body); err != nil {
+		return nil, err
+	}
+
+	threads := make([]DebugThread, 0, len(body.Threads))
+	for _, t := range body.Threads {
+		threads = append(threads, DebugThread{ID: t.ID, Name: t.Name})
+	}
+	return threads, nil
+}
+
+// CurrentGoroutine returns the goroutine that last stopped
+func (d *Debugger) CurrentGoroutine() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.session == nil {
+		return 0
+	}
+	return d.session.threadID
+}
+
+// StackTrace returns up to DebugStackDepth frames of a goroutine of the
+// stopped program. A threadID of 0 selects the goroutine that stopped.
+func (d *Debugger) StackTrace(ctx context.Context, threadID int) ([]StackFrame, error) {
+	s, stoppedID, err := d.stopped()
+	if err != nil {
+		return nil, err
+	}
+	if threadID == 0 {
+		threadID = stoppedID
+	}
+	return d.stackTrace(ctx, s, threadID, DebugStackDepth)
+}
+
+// stackTrace requests the top frames of a goroutine
+func (d *Debugger) stackTrace(ctx context.Context, s *debugSession, threadID, levels int) ([]StackFrame, error) {
+	args := map[string]any{"threadId": threadID, "startFrame": 0, "levels": levels}
+
+	var body struct {
+		StackFrames []struct {
+			ID     int    `json:"id"`
+			Name   string `json:"name"`
+			Line   int    `json:"line"`
+			Column int    `json:"column"`
+			Source *struct {
+				Path string `json:"path"`
+			} `json:"source"`
+		} `json:"stackFrames"`
+	}
+	if err := s.client.Call(ctx, "stackTrace", args, &body); err != nil {
+		return nil, err
+	}
+
+	frames := make([]StackFrame, 0, len(body.StackFrames))
+	for _, f := range body.StackFrames {
+		frame := StackFrame{ID: f.ID, Name: f.Name, Line: f.Line, Column: f.Column}
+		if f.Source != nil {
+			frame.File = f.Source.Path
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// Locals returns the variables in the inexpensive scopes (arguments and
+// locals) of a frame. A frameID of 0 selects the top frame of the
+// goroutine that stopped.
+func (d *Debugger) Locals(ctx context.Context, frameID int) ([]Variable, error) {
+	s, threadID, err := d.stopped()
+	if err != nil {
+		return nil, err
+	}
+
+	if frameID == 0 {
+		frames, err := d.stackTrace(ctx, s, threadID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(frames) == 0 {
+			return nil, fmt.Errorf("goroutine %d has no frames", threadID)
+		}
+		frameID = frames[0].ID
+	}
+
+	var scopes struct {
+		Scopes []struct {
+			Name               string `json:"name"`
+			VariablesReference int    `json:"variablesReference"`
+			Expensive          bool   `json:"expensive"`
+		} `json:"scopes"`
+	}
+	if err := s.client.Call(ctx, "scopes", map[string]any{"frameId": frameID}, &scopes); err != nil {
+		return nil, err
+	}
+
+	var vars []Variable
+	for _, scope := range scopes.Scopes {
+		if scope.Expensive || scope.VariablesReference == 0 {
+			continue
+		}
+		scopeVars, err := d.variables(ctx, s, scope.VariablesReference)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, scopeVars...)
+	}
+	return vars, nil
+}
+
+// Variables returns the children of a variable with a non-zero Reference
+func (d *Debugger) Variables(ctx context.Context, reference int) ([]Variable, error) {
+	s, _, err := d.stopped()
+	if err != nil {
+		return nil, err
+	}
+	return d.variables(ctx, s, reference)
+}
+
+// variables requests the variables of a scope or structured variable
+func (d *Debugger) variables(ctx context.Context, s *debugSession, reference int) ([]Variable, error) {
+	var body struct {
+		Variables []struct {
+			Name               string `json:"name"`
+			Value              string `json:"value"`
+			Type               string `json:"type"`
+			VariablesReference int    `json:"variablesReference"`
+		} `json:"variables"`
+	}
+	if err := s.client.Call(ctx, "variables", map[string]any{"variablesReference": reference}, &body); err != nil {
+		return nil, err
+	}
+
+	vars := make([]Variable, 0, len(body.Variables))
+	for _, v := range body.Variables {
+		vars = append(vars, Variable{Name: v.Name, Value: v.Value, Type: v.Type, Reference: v.VariablesReference})
+	}
+	return vars, nil
+}
+
+// Breakpoints returns all breakpoints ordered by file and line
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	files := make([]string, 0, len(d.breakpoints))
+	for file := range d.breakpoints {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var bps []Breakpoint
+	for _, file := range files {
+		bps = append(bps, d.breakpoints[file]...)
+	}
+	return bps
+}
+
+// SetBreakpoint adds a breakpoint at an absolute file path and line,
+// sending it to the adapter if a session is running
+func (d *Debugger) SetBreakpoint(ctx context.Context, file string, line int) (Breakpoint, error) {
+	if line < 1 {
+		return Breakpoint{}, fmt.Errorf("invalid line %d", line)
+	}
+
+	d.mu.Lock()
+	bps := d.breakpoints[file]
+	i := sort.Search(len(bps), func(i int) bool { return bps[i].Line >= line })
+	if i == len(bps) || bps[i].Line != line {
+		bps = append(bps, Breakpoint{})
+		copy(bps[i+1:], bps[i:])
+		bps[i] = Breakpoint{File: file, Line: line}
+		d.breakpoints[file] = bps
+	}
+	s := d.session
+	d.mu.Unlock()
+
+	if s != nil {
+		if err := d.syncBreakpoints(ctx, s, file); err != nil {
+			return Breakpoint{}, err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, bp := range d.breakpoints[file] {
+		if bp.Line == line {
+			return bp, nil
+		}
+	}
+	return Breakpoint{File: file, Line: line}, nil
+}
+
+// ClearBreakpoint removes the breakpoint at a file and line
+func (d *Debugger) ClearBreakpoint(ctx context.Context, file string, line int) error {
+	d.mu.Lock()
+	bps := d.breakpoints[file]
+	i := sort.Search(len(bps), func(i int) bool { return bps[i].Line >= line })
+	if i == len(bps) || bps[i].Line != line {
+		d.mu.Unlock()
+		return fmt.Errorf("%w at %s:%d", ErrNoBreakpoint, file, line)
+	}
+	bps = append(bps[:i], bps[i+1:]...)
+	// An empty entry stays until it has been synced so the adapter
+	// clears the file too
+	d.breakpoints[file] = bps
+	s := d.session
+	d.mu.Unlock()
+
+	var err error
+	if s != nil {
+		err = d.syncBreakpoints(ctx, s, file)
+	}
+
+	d.mu.Lock()
+	if len(d.breakpoints[file]) == 0 {
+		delete(d.breakpoints, file)
+	}
+	d.mu.Unlock()
+
+	return err
+}
+
+// ToggleBreakpoint sets a breakpoint at a file and line, or clears the one
+// that is there. It reports whether a breakpoint was set.
+func (d *Debugger) ToggleBreakpoint(ctx context.Context, file string, line int) (bool, error) {
+	err := d.ClearBreakpoint(ctx, file, line)
+	if errors.Is(err, ErrNoBreakpoint) {
+		_, err = d.SetBreakpoint(ctx, file, line)
+		return true, err
+	}
+	return false, err
+}
+
+// syncBreakpoints sends the breakpoints of a file to the adapter, which
+// replaces all previous breakpoints in that file, and records whether
+// they were verified
+func (d *Debugger) syncBreakpoints(ctx context.Context, s *debugSession, file string) error {
+	d.mu.Lock()
+	lines := make([]map[string]any, 0, len(d.breakpoints[file]))
+	for _, bp := range d.breakpoints[file] {
+		lines = append(lines, map[string]any{"line": bp.Line})
+	}
+	d.mu.Unlock()
+
+	args := map[string]any{
+		"source":      map[string]any{"path": file, "name": filepath.Base(file)},
+		"breakpoints": lines,
+	}
+	var body struct {
+		Breakpoints []struct {
+			Verified bool   `json:"verified"`
+			Line     int    `json:"line"`
+			Message  string `json:"message"`
+		} `json:"breakpoints"`
+	}
+	if err := s.client.Call(ctx, "setBreakpoints", args, &body); err != nil {
+		return err
+	}
+
+	// Results are in request order; a concurrent change syncs again
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	bps := d.breakpoints[file]
+	if len(bps) != len(body.Breakpoints) {
+		return nil
+	}
+	for i, result := range body.Breakpoints {
+		bps[i].Verified = result.Verified
+		bps[i].Message = result.Message
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAdapter is a debug adapter serving one session over a net.Pipe.
+// The program stops at the first breakpoint and exits when continued.
+type fakeAdapter struct {
+	t          *testing.T
+	conn       net.Conn
+	r          *bufio.Reader
+	seq        int
+	file       string // Source of the stopped frame
+	failLaunch string // Error the launch request fails with, if set
+	done       chan struct{}
+
+	mu       sync.Mutex
+	commands []string
+}
+
+// newFakeAdapter starts an adapter and returns it with the client end of
+// its connection
+func newFakeAdapter(t *testing.T, file string) (*fakeAdapter, net.Conn) {
+	client, server := net.Pipe()
+	a := &fakeAdapter{
+		t:    t,
+		conn: server,
+		r:    bufio.NewReader(server),
+		file: file,
+		done: make(chan struct{}),
+	}
+	return a, client
+}
+
+// Commands returns the requests received so far, in order
+func (a *fakeAdapter) Commands() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.commands...)
+}
+
+// send writes a message with the next sequence number
+func (a *fakeAdapter) send(msg *DAPMessage) {
+	a.seq++
+	msg.Seq = a.seq
+	if err := WriteDAPMessage(a.conn, msg); err != nil {
+		a.t.Errorf("adapter write %s%s: %v", msg.Command, msg.Event, err)
+	}
+}
+
+// respond answers a request successfully with a body
+func (a *fakeAdapter) respond(req *DAPMessage, body any) {
+	a.send(&DAPMessage{Type: "response", Command: req.Command, RequestSeq: req.Seq, Success: true, Body: a.marshal(body)})
+}
+
+// fail answers a request with an error
+func (a *fakeAdapter) fail(req *DAPMessage, format string) {
+	body := map[string]any{"error": map[string]any{"id": 3000, "format": format}}
+	a.send(&DAPMessage{Type: "response", Command: req.Command, RequestSeq: req.Seq, Message: "failed", Body: a.marshal(body)})
+}
+
+// event sends an event with a body
+func (a *fakeAdapter) event(name string, body any) {
+	a.send(&DAPMessage{Type: "event", Event: name, Body: a.marshal(body)})
+}
+
+func (a *fakeAdapter) marshal(body any) json.RawMessage {
+	if body == nil {
+		return nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		a.t.Errorf("marshal %v: %v", body, err)
+	}
+	return data
+}
+
+// serve answers requests until the client disconnects
+func (a *fakeAdapter) serve() {
+	defer close(a.done)
+	defer a.conn.Close()
+
+	for {
+		req, err := ReadDAPMessage(a.r)
+		if err != nil {
+			return
+		}
+		if req.Type != "request" {
+			a.t.Errorf("adapter received %q message, want request", req.Type)
+			continue
+		}
+
+		a.mu.Lock()
+		a.commands = append(a.commands, req.Command)
+		a.mu.Unlock()
+
+		var args map[string]any
+		json.Unmarshal(req.Arguments, &args)
+
+		switch req.Command {
+		case "initialize":
+			a.respond(req, map[string]any{"supportsConfigurationDoneRequest": true})
+
+		case "launch":
+			if a.failLaunch != "" {
+				a.fail(req, a.failLaunch)
+				continue
+			}
+			a.event("initialized", nil)
+			a.respond(req, nil)
+
+		case "setBreakpoints":
+			var bps []map[string]any
+			for _, bp := range args["breakpoints"].([]any) {
+				line := bp.(map[string]any)["line"]
+				bps = append(bps, map[string]any{"verified": true, "line": line})
+			}
+			a.respond(req, map[string]any{"breakpoints": bps})
+
+		case "configurationDone":
+			a.respond(req, nil)
+			a.event("output", map[string]any{"category": "stdout", "output": "hello\n"})
+			a.event("stopped", map[string]any{"reason": "breakpoint", "threadId": 1})
+
+		case "stackTrace":
+			frames := []map[string]any{
+				{"id": 1000, "name": "main.main", "line": 7, "column": 2, "source": map[string]any{"path": a.file}},
+				{"id": 1001, "name": "runtime.main", "line": 283, "column": 0},
+			}
+			if levels := int(args["levels"].(float64)); levels > 0 && levels < len(frames) {
+				frames = frames[:levels]
+			}
+			a.respond(req, map[string]any{"stackFrames": frames, "totalFrames": 2})
+
+		case "scopes":
+			a.respond(req, map[string]any{"scopes": []map[string]any{
+				{"name": "Locals", "variablesReference": 1, "expensive": false},
+				{"name": "Registers", "variablesReference": 2, "expensive": true},
+			}})
+
+		case "variables":
+			if args["variablesReference"] != float64(1) {
+				a.fail(req, "unknown variables reference")
+				continue
+			}
+			a.respond(req, map[string]any{"variables": []map[string]any{
+				{"name": "x", "value": "42", "type": "int", "variablesReference": 0},
+				{"name": "s", "value": "[]int len: 2, cap: 2", "type": "[]int", "variablesReference": 3},
+			}})
+
+		case "continue":
+			a.respond(req, map[string]any{"allThreadsContinued": true})
+			a.event("continued", map[string]any{"threadId": 1})
+			a.event("exited", map[string]any{"exitCode": 3})
+			a.event("terminated", nil)
+
+		case "disconnect":
+			a.respond(req, nil)
+			return
+
+		default:
+			a.fail(req, "unsupported request "+req.Command)
+		}
+	}
+}
+
+// waitEvent waits for the next debug event
+func waitEvent(t *testing.T, events <-chan DebugEvent) DebugEvent {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a debug event")
+		return DebugEvent{}
+	}
+}
+
+func TestDebuggerSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const file = "/src/hello/main.go"
+	adapter, conn := newFakeAdapter(t, file)
+	go adapter.serve()
+
+	d := NewDebugger(nil)
+	events := make(chan DebugEvent, 4)
+	d.SetOnEvent(func(ev DebugEvent) { events <- ev })
+
+	// Breakpoints set before the session are sent during the handshake
+	if _, err := d.SetBreakpoint(ctx, file, 7); err != nil {
+		t.Fatalf("SetBreakpoint: %v", err)
+	}
+
+	var mu sync.Mutex
+	var output []string
+	out := OutputSinkFunc(func(line OutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		output = append(output, line.Text)
+	})
+
+	if err := d.start(ctx, conn, map[string]any{"request": "launch", "program": "/src/hello"}, out, nil); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	ev := waitEvent(t, events)
+	if ev.Kind != DebugEventStopped || ev.Reason != "breakpoint" || ev.ThreadID != 1 {
+		t.Fatalf("event = %+v, want stopped at breakpoint on thread 1", ev)
+	}
+	if ev.Frame == nil || ev.Frame.File != file || ev.Frame.Line != 7 {
+		t.Fatalf("stopped frame = %+v, want %s:7", ev.Frame, file)
+	}
+	if state := d.State(); state != DebugStopped {
+		t.Fatalf("State() = %s, want %s", state, DebugStopped)
+	}
+	if bps := d.Breakpoints(); len(bps) != 1 || !bps[0].Verified {
+		t.Fatalf("Breakpoints() = %+v, want one verified breakpoint", bps)
+	}
+
+	frames, err := d.StackTrace(ctx, 0)
+	if err != nil {
+		t.Fatalf("StackTrace: %v", err)
+	}
+	if len(frames) != 2 || frames[0].Name != "main.main" || frames[1].Name != "runtime.main" {
+		t.Fatalf("StackTrace() = %+v, want main.main, runtime.main", frames)
+	}
+
+	vars, err := d.Locals(ctx, 0)
+	if err != nil {
+		t.Fatalf("Locals: %v", err)
+	}
+	want := []Variable{
+		{Name: "x", Value: "42", Type: "int"},
+		{Name: "s", Value: "[]int len: 2, cap: 2", Type: "[]int", Reference: 3},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Fatalf("Locals() = %+v, want %+v", vars, want)
+	}
+
+	if err := d.Resume(ctx, DebugContinue); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+
+	ev = waitEvent(t, events)
+	if ev.Kind != DebugEventExited || ev.ExitCode != 3 {
+		t.Fatalf("event = %+v, want exited with code 3", ev)
+	}
+	if state := d.State(); state != DebugIdle {
+		t.Fatalf("State() after exit = %s, want %s", state, DebugIdle)
+	}
+	if bps := d.Breakpoints(); len(bps) != 1 || bps[0].Verified {
+		t.Fatalf("Breakpoints() after exit = %+v, want one unverified breakpoint", bps)
+	}
+
+	select {
+	case <-adapter.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter was not disconnected")
+	}
+
+	wantCommands := []string{
+		"initialize", "launch", "setBreakpoints", "configurationDone",
+		"stackTrace", // Top frame of the stopped event
+		"stackTrace", // StackTrace
+		"stackTrace", // Top frame for Locals
+		"scopes", "variables",
+		"continue", "disconnect",
+	}
+	if got := adapter.Commands(); !reflect.DeepEqual(got, wantCommands) {
+		t.Errorf("adapter commands = %v, want %v", got, wantCommands)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(output, []string{"hello"}) {
+		t.Errorf("output = %q, want [hello]", output)
+	}
+}
+
+func TestDebuggerLaunchFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	adapter, conn := newFakeAdapter(t, "/src/hello/main.go")
+	adapter.failLaunch = "could not build program"
+	go adapter.serve()
+
+	d := NewDebugger(nil)
+	d.SetOnEvent(func(ev DebugEvent) {
+		t.Errorf("unexpected event %+v for a session that failed to start", ev)
+	})
+
+	err := d.start(ctx, conn, map[string]any{"request": "launch"}, sinkOrDiscard(nil), nil)
+	if err == nil || !strings.Contains(err.Error(), "could not build program") {
+		t.Fatalf("start error = %v, want the adapter's launch error", err)
+	}
+	if state := d.State(); state != DebugIdle {
+		t.Fatalf("State() = %s, want %s", state, DebugIdle)
+	}
+
+	select {
+	case <-adapter.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter connection was not closed")
+	}
+}
+
+func TestDebuggerStartCancelled(t *testing.T) {
+	// An adapter that never answers leaves start to the context
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		r := bufio.NewReader(server)
+		for {
+			if _, err := ReadDAPMessage(r); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	d := NewDebugger(nil)
+	if err := d.start(ctx, client, map[string]any{"request": "launch"}, sinkOrDiscard(nil), nil); err != context.DeadlineExceeded {
+		t.Fatalf("start error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if state := d.State(); state != DebugIdle {
+		t.Fatalf("State() = %s, want %s", state, DebugIdle)
+	}
+}
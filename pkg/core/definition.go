@@ -0,0 +1,474 @@
+// Package core provides go to definition by type-checking with go/types.
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Definition is the declaration an identifier refers to
+type Definition struct {
+	Name    string
+	Kind    string // "func", "method", "var", "field", "const", "type", "package", "label" or "builtin"
+	Package string // Import path of the declaring package; empty for builtins
+	Recv    string // Receiver type of a method
+	File    string
+	Line    int
+	Column  int
+}
+
+// QualifiedName returns the name with its package path and receiver
+// type, e.g. "gox-ide/pkg/core.GoBuilder.Build"
+func (d Definition) QualifiedName() string {
+	name := d.Name
+	if d.Recv != "" {
+		name = d.Recv + "." + name
+	}
+	switch {
+	case d.Kind == "package":
+		return d.Package
+	case d.Package != "":
+		return d.Package + "." + name
+	}
+	return name
+}
+
+// FindDefinition resolves the identifier at a 1-based line and byte column
+// of a Go file and returns its declaration. The file's package is
+// type-checked from source; its dependencies are imported from the export
+// data go list -export builds, or from source where there is none, so
+// identifiers resolve into local packages, GOROOT and the module cache.
+// src replaces the file's content on disk, e.g. with unsaved edits; nil
+// reads the file.
+func FindDefinition(ctx context.Context, file string, src []byte, line, col int) (*Definition, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		if src, err = os.ReadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Dir(file)
+	test := strings.HasSuffix(file, "_test.go")
+	graph, err := listDefinitionPackages(ctx, dir, test)
+	if err != nil {
+		return nil, err
+	}
+	goroot, err := goEnv(ctx, dir, "GOROOT")
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	target, err := parser.ParseFile(fset, file, src, parser.SkipObjectResolution)
+	if target == nil {
+		return nil, err
+	}
+	ident, importSpec := identAt(fset, target, line, col)
+
+	// Following an import path needs no type-checking
+	if importSpec != nil {
+		path, _ := strconv.Unquote(importSpec.Path.Value)
+		return packageDefinition(resolveTestVariant(graph, path, dir), path)
+	}
+	if ident == nil {
+		return nil, fmt.Errorf("no identifier at %s:%d:%d", filepath.Base(file), line, col)
+	}
+
+	// Type-check the package, or the external test package, of the file
+	path, names := packageFiles(graph, dir, file)
+	if path == "" {
+		path = target.Name.Name
+	}
+	files := []*ast.File{target}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if path == file {
+			continue
+		}
+		if f, _ := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution); f != nil && f.Name.Name == target.Name.Name {
+			files = append(files, f)
+		}
+	}
+
+	var typeErrs []error
+	conf := types.Config{
+		Importer:    newDefinitionImporter(fset, graph, dir),
+		FakeImportC: true,
+		Error:       func(err error) { typeErrs = append(typeErrs, err) },
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf.Check(path, fset, files, info)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	obj := info.Uses[ident]
+	if obj == nil {
+		obj = info.Defs[ident]
+	}
+	if obj == nil {
+		if len(typeErrs) > 0 {
+			return nil, fmt.Errorf("cannot resolve %s: %w", ident.Name, typeErrs[0])
+		}
+		return nil, fmt.Errorf("no definition found for %s", ident.Name)
+	}
+
+	return objectDefinition(fset, goroot, obj)
+}
+
+// listDefinitionPackages lists the package in dir and its dependencies
+// with their export data, building it if needed. Tests add the test
+// variants of packages.
+func listDefinitionPackages(ctx context.Context, dir string, test bool) (*PackageGraph, error) {
+	args := []string{"list", "-e", "-export", "-deps", "-json=ImportPath,Name,Dir,Export,GoFiles,CgoFiles,TestGoFiles,XTestGoFiles,DepOnly,ForTest"}
+	if test {
+		args = append(args, "-test")
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, ".")...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return ParsePackageGraph(bytes.NewReader(data))
+}
+
+// goEnv returns the value of a go env variable
+func goEnv(ctx context.Context, dir, name string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "env", name)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// packageFiles returns the import path of the package type-checked with
+// file and the names of its files: the package's files, plus the internal
+// test files for a test file, or the external test files for one of those
+func packageFiles(graph *PackageGraph, dir, file string) (string, []string) {
+	var pkg *GoPackage
+	for _, p := range graph.Packages {
+		if p.Dir != dir || p.ForTest != "" || strings.Contains(p.ImportPath, " ") {
+			continue
+		}
+		// The generated main package of the tests shares the directory
+		if tested, ok := strings.CutSuffix(p.ImportPath, ".test"); ok && graph.Packages[tested] != nil {
+			continue
+		}
+		pkg = p
+		break
+	}
+	if pkg == nil {
+		return "", nil
+	}
+
+	name := filepath.Base(file)
+	if slices.Contains(pkg.XTestGoFiles, name) {
+		return pkg.ImportPath + "_test", pkg.XTestGoFiles
+	}
+	files := slices.Concat(pkg.GoFiles, pkg.CgoFiles)
+	if strings.HasSuffix(name, "_test.go") {
+		files = append(files, pkg.TestGoFiles...)
+	}
+	return pkg.ImportPath, files
+}
+
+// resolveTestVariant returns the package imported by path from dir,
+// preferring the variant recompiled for the tests of the package in dir
+func resolveTestVariant(graph *PackageGraph, path, dir string) *GoPackage {
+	for _, pkg := range graph.Packages {
+		if pkg.ForTest != "" && strings.HasPrefix(pkg.ImportPath, path+" [") {
+			if forTest, ok := graph.Packages[pkg.ForTest]; ok && forTest.Dir == dir {
+				return pkg
+			}
+		}
+	}
+	return graph.Packages[path]
+}
+
+// definitionImporter imports packages from export data, type-checking
+// from source those that have none, e.g. because they do not compile
+type definitionImporter struct {
+	fset    *token.FileSet
+	graph   *PackageGraph
+	dir     string
+	gc      types.Importer
+	checked map[string]*types.Package
+}
+
+// newDefinitionImporter creates an importer for the packages listed in
+// graph, as imported from dir
+func newDefinitionImporter(fset *token.FileSet, graph *PackageGraph, dir string) *definitionImporter {
+	im := &definitionImporter{
+		fset:    fset,
+		graph:   graph,
+		dir:     dir,
+		checked: make(map[string]*types.Package),
+	}
+	im.gc = importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		pkg := resolveTestVariant(graph, path, dir)
+		if pkg == nil || pkg.Export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(pkg.Export)
+	})
+	return im
+}
+
+// Import implements types.Importer
+func (im *definitionImporter) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if pkg, ok := im.checked[path]; ok {
+		return pkg, nil
+	}
+
+	listed := resolveTestVariant(im.graph, path, im.dir)
+	if listed == nil || listed.Export != "" || listed.Dir == "" {
+		return im.gc.Import(path)
+	}
+
+	var files []*ast.File
+	for _, name := range slices.Concat(listed.GoFiles, listed.CgoFiles) {
+		if f, _ := parser.ParseFile(im.fset, filepath.Join(listed.Dir, name), nil, parser.SkipObjectResolution); f != nil {
+			files = append(files, f)
+		}
+	}
+	conf := types.Config{
+		Importer:    im,
+		FakeImportC: true,
+		Error:       func(error) {}, // Keep going, the declarations are what matter
+	}
+	pkg, _ := conf.Check(path, im.fset, files, nil)
+	im.checked[path] = pkg
+	return pkg, nil
+}
+
+// identAt returns the identifier at a 1-based line and byte column, which
+// may also be just past its end, or else the import spec whose path is
+// there
+func identAt(fset *token.FileSet, f *ast.File, line, col int) (*ast.Ident, *ast.ImportSpec) {
+	tf := fset.File(f.Pos())
+	if tf == nil || line < 1 || line > tf.LineCount() {
+		return nil, nil
+	}
+	start := tf.LineStart(line)
+	end := tf.Pos(tf.Size())
+	if line < tf.LineCount() {
+		end = tf.LineStart(line + 1)
+	}
+	pos := start + token.Pos(max(col-1, 0))
+	if pos >= end {
+		return nil, nil
+	}
+
+	for _, spec := range f.Imports {
+		if spec.Path.Pos() <= pos && pos < spec.Path.End() {
+			return nil, spec
+		}
+	}
+
+	// Prefer the identifier under the cursor to one ending there
+	var ident, before *ast.Ident
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ident != nil || n == nil || n.Pos() > pos || n.End() < pos {
+			return false
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			if pos < id.End() {
+				ident = id
+			} else {
+				before = id
+			}
+		}
+		return true
+	})
+	if ident == nil {
+		ident = before
+	}
+	return ident, nil
+}
+
+// objectDefinition returns where a type-checked object is declared
+func objectDefinition(fset *token.FileSet, goroot string, obj types.Object) (*Definition, error) {
+	def := &Definition{Name: obj.Name()}
+	if obj.Pkg() != nil {
+		def.Package = obj.Pkg().Path()
+	}
+
+	switch obj := obj.(type) {
+	case *types.Func:
+		def.Kind = "func"
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			def.Kind = "method"
+			recv := sig.Recv().Type()
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			if named, ok := recv.(*types.Named); ok {
+				def.Recv = named.Obj().Name()
+			}
+		}
+	case *types.Var:
+		def.Kind = "var"
+		if obj.IsField() {
+			def.Kind = "field"
+		}
+	case *types.Const:
+		def.Kind = "const"
+	case *types.TypeName:
+		def.Kind = "type"
+	case *types.PkgName:
+		def.Kind = "package"
+		def.Package = obj.Imported().Path()
+	case *types.Label:
+		def.Kind = "label"
+	default:
+		def.Kind = "builtin"
+	}
+
+	// Predeclared identifiers and the unsafe package are documented in
+	// GOROOT
+	if !obj.Pos().IsValid() {
+		file := filepath.Join(goroot, "src", "builtin", "builtin.go")
+		if obj.Pkg() == types.Unsafe {
+			file = filepath.Join(goroot, "src", "unsafe", "unsafe.go")
+		}
+		return declaredIn(def, file)
+	}
+
+	pos := fset.Position(obj.Pos())
+	def.File = pos.Filename
+	if rest, ok := strings.CutPrefix(def.File, "$GOROOT"); ok {
+		def.File = filepath.Join(goroot, filepath.FromSlash(rest))
+	}
+	def.Line = pos.Line
+	def.Column = pos.Column
+
+	// Export data records lines only
+	if def.Column <= 1 {
+		def.Column = nameColumn(def.File, def.Line, def.Name)
+	}
+	return def, nil
+}
+
+// declaredIn locates a declaration named like def in a documentation file
+// of GOROOT, such as builtin.go
+func declaredIn(def *Definition, file string) (*Definition, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is predeclared: %w", def.Name, err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments|parser.SkipObjectResolution)
+	if f == nil {
+		return nil, err
+	}
+
+	_, symbols := fileSymbols(fset, f)
+	for _, sym := range symbols {
+		if sym.Name == def.Name {
+			def.File = file
+			def.Line = sym.Line
+			def.Column = sym.Column
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is predeclared", def.Name)
+}
+
+// packageDefinition returns the package clause of an imported package,
+// in the file with the package doc if there is one
+func packageDefinition(pkg *GoPackage, path string) (*Definition, error) {
+	if pkg == nil || pkg.Dir == "" {
+		return nil, fmt.Errorf("package %s not found", path)
+	}
+
+	var def *Definition
+	for _, name := range slices.Concat(pkg.GoFiles, pkg.CgoFiles) {
+		file := filepath.Join(pkg.Dir, name)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, file, nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		pos := fset.Position(f.Name.Pos())
+		candidate := &Definition{Name: f.Name.Name, Kind: "package", Package: path, File: file, Line: pos.Line, Column: pos.Column}
+		if f.Doc != nil {
+			return candidate, nil
+		}
+		if def == nil {
+			def = candidate
+		}
+	}
+	if def == nil {
+		return nil, fmt.Errorf("package %s has no Go files", path)
+	}
+	return def, nil
+}
+
+// nameColumn returns the 1-based byte column of the first occurrence of
+// name as a whole identifier on a line of a file, or 1
+func nameColumn(file string, line int, name string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 1
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if n < line {
+			continue
+		}
+		text := scanner.Text()
+		for i := 0; i <= len(text)-len(name); {
+			j := strings.Index(text[i:], name)
+			if j < 0 {
+				break
+			}
+			j += i
+			end := j + len(name)
+			if (j == 0 || !isIdentByte(text[j-1])) && (end == len(text) || !isIdentByte(text[end])) {
+				return j + 1
+			}
+			i = j + 1
+		}
+		break
+	}
+	return 1
+}
+
+// isIdentByte reports whether an ASCII byte can be part of an identifier;
+// bytes of multi-byte runes count as such
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
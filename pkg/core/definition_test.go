@@ -0,0 +1,239 @@
+package core
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// definitionDir is a small module to resolve identifiers in
+var definitionDir = filepath.Join("testdata", "definition")
+
+// positionOf returns the 1-based line and byte column of the first
+// occurrence of needle in a file of definitionDir, plus offset bytes
+func positionOf(t *testing.T, name, needle string, offset int) (string, int, int) {
+	t.Helper()
+	file, err := filepath.Abs(filepath.Join(definitionDir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := strings.Index(string(src), needle)
+	if i < 0 {
+		t.Fatalf("%q not found in %s", needle, name)
+	}
+	i += offset
+	line := strings.Count(string(src[:i]), "\n") + 1
+	col := i - strings.LastIndex(string(src[:i]), "\n")
+	return file, line, col
+}
+
+func TestFindDefinition(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	goroot, err := goEnv(context.Background(), ".", "GOROOT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := filepath.Abs(definitionDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		file   string // In definitionDir
+		needle string // Text at the cursor
+		offset int    // Bytes into needle
+		want   Definition
+		at     string // Text at the definition; "" skips checking it
+		in     string // File of the definition in definitionDir, if checked
+	}{
+		{
+			name: "local variable", file: "definition.go", needle: "name + string", offset: 2,
+			want: Definition{Name: "name", Kind: "var", Package: "example.com/definition"},
+			at:   "name := strings",
+		},
+		{
+			name: "cursor just past an identifier", file: "definition.go", needle: "name + string", offset: 4,
+			want: Definition{Name: "name", Kind: "var", Package: "example.com/definition"},
+			at:   "name := strings",
+		},
+		{
+			name: "method", file: "definition.go", needle: "Area()))", offset: 0,
+			want: Definition{Name: "Area", Kind: "method", Package: "example.com/definition", Recv: "Shape"},
+			at:   "Area() int {",
+		},
+		{
+			name: "field", file: "definition.go", needle: "Width * s.Width", offset: 0,
+			want: Definition{Name: "Width", Kind: "field", Package: "example.com/definition"},
+			at:   "Width int",
+		},
+		{
+			name: "function of another package", file: "definition.go", needle: "sub.Exported", offset: 6,
+			want: Definition{Name: "Exported", Kind: "func", Package: "example.com/definition/sub"},
+			at:   "Exported() {}",
+		},
+		{
+			name: "GOROOT function", file: "definition.go", needle: "ToUpper", offset: 3,
+			want: Definition{Name: "ToUpper", Kind: "func", Package: "strings"},
+			at:   "ToUpper(s string) string",
+		},
+		{
+			name: "builtin", file: "definition.go", needle: "len(name)", offset: 1,
+			want: Definition{Name: "len", Kind: "builtin"},
+			at:   "len(v Type) int",
+		},
+		{
+			name: "import path", file: "definition.go", needle: `"example.com/definition/sub"`, offset: 5,
+			want: Definition{Name: "sub", Kind: "package", Package: "example.com/definition/sub"},
+			at:   "sub\n", // In the file with the package doc
+			in:   "sub/doc.go",
+		},
+		{
+			name: "internal test file", file: "definition_test.go", needle: "Area(); got", offset: 0,
+			want: Definition{Name: "Area", Kind: "method", Package: "example.com/definition", Recv: "Shape"},
+			at:   "Area() int {",
+		},
+		{
+			name: "external test package", file: "example_test.go", needle: "Describe(&", offset: 0,
+			want: Definition{Name: "Describe", Kind: "func", Package: "example.com/definition"},
+			at:   "Describe(s *Shape)",
+		},
+		{
+			name: "package name in an external test", file: "example_test.go", needle: "definition.Describe", offset: 0,
+			want: Definition{Name: "definition", Kind: "package", Package: "example.com/definition"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, line, col := positionOf(t, tt.file, tt.needle, tt.offset)
+			def, err := FindDefinition(context.Background(), file, nil, line, col)
+			if err != nil {
+				t.Fatalf("FindDefinition(%s:%d:%d): %v", tt.file, line, col, err)
+			}
+
+			got := Definition{Name: def.Name, Kind: def.Kind, Package: def.Package, Recv: def.Recv}
+			if got != tt.want {
+				t.Errorf("FindDefinition() = %+v, want %+v", got, tt.want)
+			}
+			if tt.at == "" {
+				return
+			}
+
+			src, err := os.ReadFile(def.File)
+			if err != nil {
+				t.Fatalf("definition file: %v", err)
+			}
+			lines := strings.SplitAfter(string(src), "\n")
+			if def.Line < 1 || def.Line > len(lines) || def.Column < 1 || def.Column > len(lines[def.Line-1]) {
+				t.Fatalf("definition at %s:%d:%d is out of range", def.File, def.Line, def.Column)
+			}
+			if text := lines[def.Line-1][def.Column-1:]; !strings.HasPrefix(text, tt.at) {
+				t.Errorf("definition at %s:%d:%d is %q, want %q", def.File, def.Line, def.Column, text, tt.at)
+			}
+			switch {
+			case tt.in != "":
+				if want := filepath.Join(dir, filepath.FromSlash(tt.in)); def.File != want {
+					t.Errorf("definition in %s, want %s", def.File, want)
+				}
+			case tt.want.Package == "strings" || tt.want.Kind == "builtin":
+				if !strings.HasPrefix(def.File, filepath.Join(goroot, "src")+string(filepath.Separator)) {
+					t.Errorf("definition in %s, want GOROOT %s", def.File, goroot)
+				}
+			case !strings.HasPrefix(def.File, dir+string(filepath.Separator)):
+				t.Errorf("definition in %s, want the test module %s", def.File, dir)
+			}
+		})
+	}
+}
+
+func TestFindDefinitionUnsaved(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	file, line, col := positionOf(t, "definition.go", "name + string", 0)
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unsaved line above moves both the cursor and the definition
+	edited := strings.Replace(string(src), "// Describe describes", "// Unsaved\n// Describe describes", 1)
+	def, err := FindDefinition(context.Background(), file, []byte(edited), line+1, col)
+	if err != nil {
+		t.Fatalf("FindDefinition: %v", err)
+	}
+	_, wantLine, wantCol := positionOf(t, "definition.go", "name := strings", 0)
+	if def.Name != "name" || def.Line != wantLine+1 || def.Column != wantCol {
+		t.Errorf("FindDefinition() = %s at %d:%d, want name at %d:%d", def.Name, def.Line, def.Column, wantLine+1, wantCol)
+	}
+
+	if _, err := FindDefinition(context.Background(), file, nil, 1, 1); err == nil {
+		t.Error("FindDefinition of the package keyword succeeded, want an error")
+	}
+}
+
+func TestIdentAt(t *testing.T) {
+	src := "package p\n\nimport \"fmt\"\n\nfunc f(ab int) {\n\tfmt.Println(ab)\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line, col int
+		ident     string // "" for none
+		importing bool
+	}{
+		{1, 9, "p", false},
+		{3, 9, "", true},     // Inside the import path
+		{5, 8, "ab", false},  // Start of a parameter
+		{5, 10, "ab", false}, // Just past its end
+		{6, 2, "fmt", false}, // Package name of a selector
+		{6, 5, "fmt", false}, // The dot after it
+		{6, 6, "Println", false},
+		{6, 15, "ab", false},
+		{2, 1, "", false},  // Blank line
+		{5, 40, "", false}, // Past the end of the line
+		{0, 1, "", false},  // Out of range
+		{100, 1, "", false},
+	}
+	for _, tt := range tests {
+		ident, spec := identAt(fset, f, tt.line, tt.col)
+		var name string
+		if ident != nil {
+			name = ident.Name
+		}
+		if name != tt.ident || (spec != nil) != tt.importing {
+			t.Errorf("identAt(%d:%d) = %q, import %t; want %q, import %t", tt.line, tt.col, name, spec != nil, tt.ident, tt.importing)
+		}
+	}
+}
+
+func TestDefinitionQualifiedName(t *testing.T) {
+	tests := []struct {
+		def  Definition
+		want string
+	}{
+		{Definition{Name: "Build", Kind: "method", Package: "gox-ide/pkg/core", Recv: "GoBuilder"}, "gox-ide/pkg/core.GoBuilder.Build"},
+		{Definition{Name: "ToUpper", Kind: "func", Package: "strings"}, "strings.ToUpper"},
+		{Definition{Name: "core", Kind: "package", Package: "gox-ide/pkg/core"}, "gox-ide/pkg/core"},
+		{Definition{Name: "len", Kind: "builtin"}, "len"},
+	}
+	for _, tt := range tests {
+		if got := tt.def.QualifiedName(); got != tt.want {
+			t.Errorf("QualifiedName(%+v) = %q, want %q", tt.def, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package core provides compiler diagnostic parsing for the IDE.
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity represents the severity of a diagnostic
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+// String returns the string representation of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic represents a single compiler or tool finding
+type Diagnostic struct {
+	File     string // Absolute path to the file
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+	Package  string // Import path reported in the "# pkg" header, if any
+	Source   string // Tools that reported it, e.g. "vet"; empty for the compiler
+}
+
+// String formats the diagnostic in the conventional file:line:col form
+func (d Diagnostic) String() string {
+	if d.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+// diagnosticPattern matches "file.go:line[:col]: message"
+var diagnosticPattern = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// ParseDiagnostics parses go tool output into diagnostics.
+// Relative file names are resolved against dir, the directory the
+// command was run in.
+func ParseDiagnostics(output, dir string) []Diagnostic {
+	var (
+		diags   []Diagnostic
+		pkg     string
+		scanner = bufio.NewScanner(strings.NewReader(output))
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Package header emitted before each failing package, e.g.
+		// "# example.com/p", "# example.com/p [example.com/p.test]" for
+		// its test variant, or "# [example.com/p]" from vet
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			pkg, _, _ = strings.Cut(strings.TrimSpace(header), " ")
+			pkg = strings.TrimSuffix(strings.TrimPrefix(pkg, "["), "]")
+			continue
+		}
+
+		// Indented lines continue the previous message (e.g. have/want)
+		if strings.HasPrefix(line, "\t") && len(diags) > 0 {
+			last := &diags[len(diags)-1]
+			last.Message += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		severity := SeverityError
+		if trimmed, ok := strings.CutPrefix(line, "vet: "); ok {
+			line = trimmed
+			severity = SeverityWarning
+		}
+
+		match := diagnosticPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		file := match[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+
+		lineNum, _ := strconv.Atoi(match[2])
+		col, _ := strconv.Atoi(match[3])
+
+		diags = append(diags, Diagnostic{
+			File:     filepath.Clean(file),
+			Line:     lineNum,
+			Column:   col,
+			Severity: severity,
+			Message:  match[4],
+			Package:  pkg,
+		})
+	}
+
+	return diags
+}
+
+// CountDiagnostics returns the number of errors and warnings in diags
+func CountDiagnostics(diags []Diagnostic) (errs, warnings int) {
+	for _, d := range diags {
+		switch d.Severity {
+		case SeverityError:
+			errs++
+		case SeverityWarning:
+			warnings++
+		}
+	}
+	return errs, warnings
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	dir := filepath.FromSlash("/work/p")
+	abs := filepath.Join(dir, "abs", "x.go")
+
+	tests := []struct {
+		name   string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			name: "compiler errors of a package run from the module root",
+			output: "# example.com/p/sub\n" +
+				"sub/s.go:4:6: declared and not used: x\n" +
+				"sub/s.go:5:9: undefined: y\n",
+			want: []Diagnostic{
+				{File: filepath.Join(dir, "sub", "s.go"), Line: 4, Column: 6, Message: "declared and not used: x", Package: "example.com/p/sub"},
+				{File: filepath.Join(dir, "sub", "s.go"), Line: 5, Column: 9, Message: "undefined: y", Package: "example.com/p/sub"},
+			},
+		},
+		{
+			name: "paths relative to the package directory",
+			output: "# example.com/p\n" +
+				"./main.go:7:2: undefined: fmt.Printlnx\n" +
+				"../q/q.go:1:1: expected 'package', found 'EOF'\n",
+			want: []Diagnostic{
+				{File: filepath.Join(dir, "main.go"), Line: 7, Column: 2, Message: "undefined: fmt.Printlnx", Package: "example.com/p"},
+				{File: filepath.FromSlash("/work/q/q.go"), Line: 1, Column: 1, Message: "expected 'package', found 'EOF'", Package: "example.com/p"},
+			},
+		},
+		{
+			name:   "absolute path without a column",
+			output: abs + ":12: missing return\n",
+			want: []Diagnostic{
+				{File: abs, Line: 12, Message: "missing return"},
+			},
+		},
+		{
+			name: "indented lines continue the message",
+			output: "# example.com/p\n" +
+				"./main.go:9:10: cannot use t (variable of type T) as I value in assignment: T does not implement I (wrong type for method M)\n" +
+				"\t\thave M() int\n" +
+				"\t\twant M() string\n",
+			want: []Diagnostic{
+				{
+					File: filepath.Join(dir, "main.go"), Line: 9, Column: 10, Package: "example.com/p",
+					Message: "cannot use t (variable of type T) as I value in assignment: T does not implement I (wrong type for method M)\nhave M() int\nwant M() string",
+				},
+			},
+		},
+		{
+			name: "vet findings",
+			output: "# example.com/p\n" +
+				"# [example.com/p]\n" +
+				"./main.go:6:14: fmt.Printf format %d has arg \"x\" of wrong type string\n",
+			want: []Diagnostic{
+				{File: filepath.Join(dir, "main.go"), Line: 6, Column: 14, Message: "fmt.Printf format %d has arg \"x\" of wrong type string", Package: "example.com/p"},
+			},
+		},
+		{
+			name: "test variant of a package",
+			output: "# example.com/p [example.com/p.test]\n" +
+				"./main_test.go:5:2: undefined: helper\n",
+			want: []Diagnostic{
+				{File: filepath.Join(dir, "main_test.go"), Line: 5, Column: 2, Message: "undefined: helper", Package: "example.com/p"},
+			},
+		},
+		{
+			name: "vet prefix is a warning",
+			output: "# example.com/p/sub\n" +
+				"vet: sub/s.go:4:14: cannot use \"s\" (untyped string constant) as int value in variable declaration\n" +
+				"sub/s.go:5:9: undefined: y\n",
+			want: []Diagnostic{
+				{File: filepath.Join(dir, "sub", "s.go"), Line: 4, Column: 14, Severity: SeverityWarning, Message: "cannot use \"s\" (untyped string constant) as int value in variable declaration", Package: "example.com/p/sub"},
+				{File: filepath.Join(dir, "sub", "s.go"), Line: 5, Column: 9, Message: "undefined: y", Package: "example.com/p/sub"},
+			},
+		},
+		{
+			name: "other output is skipped",
+			output: "go: downloading example.com/dep v1.0.0\n" +
+				"\tunrelated indented line\n" +
+				"--- FAIL: TestX (0.00s)\n" +
+				"README.md:3: not Go\n" +
+				"FAIL\texample.com/p [build failed]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDiagnostics(tt.output, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDiagnostics() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountDiagnostics(t *testing.T) {
+	diags := []Diagnostic{
+		{Severity: SeverityError},
+		{Severity: SeverityWarning},
+		{Severity: SeverityError},
+		{Severity: SeverityInfo},
+	}
+	if errs, warnings := CountDiagnostics(diags); errs != 2 || warnings != 1 {
+		t.Errorf("CountDiagnostics() = %d, %d; want 2, 1", errs, warnings)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		diag Diagnostic
+		want string
+	}{
+		{Diagnostic{File: "main.go", Line: 3, Column: 7, Message: "undefined: x"}, "main.go:3:7: undefined: x"},
+		{Diagnostic{File: "main.go", Line: 3, Message: "missing return"}, "main.go:3: missing return"},
+	}
+	for _, tt := range tests {
+		if got := tt.diag.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
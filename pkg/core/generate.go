@@ -0,0 +1,240 @@
+// Package core provides discovery and execution of go:generate directives.
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// generatePrefix starts a go:generate directive at the beginning of a
+// line, followed by a space or a tab
+const generatePrefix = "//go:generate"
+
+// maxGeneratedHeaderSize bounds how much of a file is read looking for the
+// generated code marker
+const maxGeneratedHeaderSize = 64 * 1024 // 64KB
+
+// generatedCodePattern matches the marker of generated files, see
+// https://go.dev/s/generatedcode
+var generatedCodePattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// GenerateDirective is a //go:generate line of a Go file
+type GenerateDirective struct {
+	File    string // Absolute path
+	RelPath string // Path relative to the project root
+	Line    int
+	Package string // Pattern of the file's package in its module, e.g. "./cmd/x"
+	Command string // The command after "//go:generate "
+
+	text string // The line as written, without trailing spaces
+}
+
+// Text returns the directive as written in the source
+func (d GenerateDirective) Text() string {
+	if d.text == "" {
+		return generatePrefix + " " + d.Command
+	}
+	return d.text
+}
+
+// GenerateOptions selects the directives Generate runs: one directive,
+// one package's, or all of the project's
+type GenerateOptions struct {
+	Package   string             // Package pattern; defaults to "./..."
+	Directive *GenerateDirective // Run only this directive
+}
+
+// FindGenerateDirectives returns the go:generate directives of the
+// project's Go files in file and line order. Like go generate it skips
+// testdata directories and those starting with "_".
+func FindGenerateDirectives(ctx context.Context, project Project, opts WalkOptions) ([]GenerateDirective, error) {
+	files, err := project.WalkFiles(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var directives []GenerateDirective
+	for _, file := range files {
+		if file.Language != "go" {
+			continue
+		}
+		found, err := fileGenerateDirectives(ctx, project, file.Path, file.RelPath)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, found...)
+	}
+	return directives, nil
+}
+
+// FileGenerateDirectives returns the go:generate directives of one Go
+// file of the project, e.g. to update those found by
+// FindGenerateDirectives after the file is saved. A removed file has
+// none.
+func FileGenerateDirectives(ctx context.Context, project Project, path string) ([]GenerateDirective, error) {
+	rel, err := filepath.Rel(project.Path(), path)
+	if err != nil {
+		return nil, err
+	}
+	directives, err := fileGenerateDirectives(ctx, project, path, rel)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return directives, err
+}
+
+// ReplaceGenerateDirectives returns directives with those of file
+// replaced by found, keeping the file and line order of
+// FindGenerateDirectives. directives is not modified.
+func ReplaceGenerateDirectives(directives []GenerateDirective, file string, found []GenerateDirective) []GenerateDirective {
+	merged := make([]GenerateDirective, 0, len(directives)+len(found))
+	for _, d := range directives {
+		if d.File != file {
+			merged = append(merged, d)
+		}
+	}
+	merged = append(merged, found...)
+
+	// The walk lists each directory's entries by name before descending
+	elems := func(d GenerateDirective) []string {
+		return strings.Split(filepath.ToSlash(d.RelPath), "/")
+	}
+	slices.SortStableFunc(merged, func(a, b GenerateDirective) int {
+		if c := slices.Compare(elems(a), elems(b)); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return merged
+}
+
+// fileGenerateDirectives reads the directives of one Go file and sets
+// their package pattern
+func fileGenerateDirectives(ctx context.Context, project Project, path, rel string) ([]GenerateDirective, error) {
+	if isIgnoredPackageDir(filepath.Dir(rel)) {
+		return nil, nil
+	}
+
+	found, err := scanGenerateDirectives(path)
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+
+	// Patterns are relative to the module owning the file
+	pkg, err := PackagePatternForFile(project.ForModule(ctx, path).Path(), path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		found[i].RelPath = rel
+		found[i].Package = pkg
+	}
+	return found, nil
+}
+
+// scanGenerateDirectives reads the directives of one file
+func scanGenerateDirectives(path string) ([]GenerateDirective, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(content, []byte(generatePrefix)) {
+		return nil, nil
+	}
+
+	var directives []GenerateDirective
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		// Like go generate, a space or a tab separates the command
+		rest, ok := strings.CutPrefix(line, generatePrefix)
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		directives = append(directives, GenerateDirective{
+			File:    path,
+			Line:    i + 1,
+			Command: strings.TrimLeft(rest, " \t"),
+			text:    line,
+		})
+	}
+	return directives, nil
+}
+
+// isIgnoredPackageDir reports whether the go command ignores a directory
+// relative to the project root when matching "./..."
+func isIgnoredPackageDir(rel string) bool {
+	for _, elem := range strings.Split(filepath.ToSlash(rel), "/") {
+		if elem == "testdata" || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGeneratedFile reports whether a Go file carries the
+// "// Code generated ... DO NOT EDIT." marker before its package clause
+func IsGeneratedFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 4096), maxGeneratedHeaderSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if generatedCodePattern.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
+
+// Generate runs go generate for the selected directives, streaming output
+// to out. A single directive is selected with -run, so identical
+// directives in the same file run together.
+func (b *GoBuilder) Generate(ctx context.Context, project Project, opts GenerateOptions, out OutputSink) error {
+	if !project.IsGoProject() {
+		return ErrNotGoProject
+	}
+
+	var cmd *exec.Cmd
+	if d := opts.Directive; d != nil {
+		if d.File == "" {
+			return errors.New("generate: directive has no file")
+		}
+		cmd = exec.CommandContext(ctx, "go", "generate", "-x", "-run", "^"+regexp.QuoteMeta(d.Text())+"$", filepath.Base(d.File))
+		cmd.Dir = filepath.Dir(d.File)
+	} else {
+		pkg := opts.Package
+		if pkg == "" {
+			pkg = "./..."
+		}
+		cmd = exec.CommandContext(ctx, "go", "generate", "-x", pkg)
+		cmd.Dir = project.Path()
+	}
+
+	if b.logger != nil {
+		b.logger.Info("Running go generate",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "args", Value: strings.Join(cmd.Args[1:], " ")})
+	}
+
+	if err := runCommand(cmd, out); err != nil {
+		return fmt.Errorf("go generate: %w", err)
+	}
+	return nil
+}
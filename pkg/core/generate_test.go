@@ -0,0 +1,204 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanGenerateDirectives(t *testing.T) {
+	src := "" +
+		"package main\n" +
+		"\n" +
+		"//go:generate stringer -type=Kind\n" +
+		"//go:generate\tgo run gen.go  \n" +
+		"//go:generate  protoc --go_out=. x.proto\r\n" +
+		"//go:generate\n" +
+		"//go:generate   \n" +
+		"//go:generated not a directive\n" +
+		"\t//go:generate indented\n" +
+		"// go:generate spaced\n" +
+		"/*\n" +
+		"//go:generate in a block comment\n" +
+		"*/\n"
+	path := filepath.Join(writeFiles(t, map[string]string{"main.go": src}), "main.go")
+
+	got, err := scanGenerateDirectives(path)
+	if err != nil {
+		t.Fatalf("scanGenerateDirectives: %v", err)
+	}
+
+	type directive struct {
+		Line    int
+		Command string
+		Text    string
+	}
+	var directives []directive
+	for _, d := range got {
+		if d.File != path {
+			t.Errorf("directive at line %d has file %q, want %q", d.Line, d.File, path)
+		}
+		directives = append(directives, directive{d.Line, d.Command, d.Text()})
+	}
+	// Like go generate, block comments are not parsed
+	want := []directive{
+		{3, "stringer -type=Kind", "//go:generate stringer -type=Kind"},
+		{4, "go run gen.go", "//go:generate\tgo run gen.go"},
+		{5, "protoc --go_out=. x.proto", "//go:generate  protoc --go_out=. x.proto"},
+		{12, "in a block comment", "//go:generate in a block comment"},
+	}
+	if !reflect.DeepEqual(directives, want) {
+		t.Errorf("scanGenerateDirectives() = %+v, want %+v", directives, want)
+	}
+
+	none := filepath.Join(filepath.Dir(path), "none.go")
+	if err := os.WriteFile(none, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := scanGenerateDirectives(none); err != nil || got != nil {
+		t.Errorf("scanGenerateDirectives of a file without directives = %v, %v; want none", got, err)
+	}
+}
+
+func TestGenerateDirectiveText(t *testing.T) {
+	d := GenerateDirective{Command: "go run gen.go"}
+	if got, want := d.Text(), "//go:generate go run gen.go"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestIsGeneratedFile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{"marker", "// Code generated by stringer; DO NOT EDIT.\n\npackage main\n", true},
+		{"marker after the build constraint", "//go:build linux\n\n// Code generated by go generate. DO NOT EDIT.\n\npackage main\n", true},
+		{"windows line endings", "// Code generated by protoc-gen-go. DO NOT EDIT.\r\npackage pb\r\n", true},
+		{"marker without a package clause", "// Code generated by hand. DO NOT EDIT.\n", true},
+		{"marker after the package clause", "package main\n\n// Code generated by stringer; DO NOT EDIT.\n", false},
+		{"missing period", "// Code generated by stringer; DO NOT EDIT\npackage main\n", false},
+		{"text after the marker", "// Code generated by x. DO NOT EDIT. Really.\npackage main\n", false},
+		{"indented", " // Code generated by x. DO NOT EDIT.\npackage main\n", false},
+		{"in a block comment", "/*\nCode generated by x. DO NOT EDIT.\n*/\npackage main\n", false},
+		{"header too long", "// " + strings.Repeat("x", maxGeneratedHeaderSize) + "\n// Code generated by x. DO NOT EDIT.\npackage main\n", false},
+		{"handwritten", "// Package main does things.\npackage main\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(writeFiles(t, map[string]string{"x.go": tt.src}), "x.go")
+			if got := IsGeneratedFile(path); got != tt.want {
+				t.Errorf("IsGeneratedFile() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	if IsGeneratedFile(filepath.Join(t.TempDir(), "missing.go")) {
+		t.Error("IsGeneratedFile of a missing file = true, want false")
+	}
+}
+
+// generateTree is a project with directives in several packages, a
+// nested module and directories go generate skips
+var generateTree = map[string]string{
+	"go.mod":               "module example.com/g\n",
+	"main.go":              "package main\n\n//go:generate echo root\n",
+	"a/a.go":               "package a\n\n//go:generate echo a1\n//go:generate\techo a2\n",
+	"a/b/b.go":             "package b\n//go:generate echo b\n",
+	"a-b/c.go":             "package ab\n//go:generate echo a-b\n",
+	"tools/go.mod":         "module example.com/tools\n",
+	"tools/gen/gen.go":     "package gen\n//go:generate echo tools\n",
+	"testdata/x.go":        "package x\n//go:generate echo testdata\n",
+	"_skip/y.go":           "package y\n//go:generate echo skipped\n",
+	"notes.txt":            "//go:generate echo text\n",
+	"a/generate_test.go":   "package a\n",
+	"tools/gen/README.txt": "",
+}
+
+// directiveSummary returns "relpath:line package command" for each directive
+func directiveSummary(directives []GenerateDirective) []string {
+	var lines []string
+	for _, d := range directives {
+		lines = append(lines, fmt.Sprintf("%s:%d %s %s", filepath.ToSlash(d.RelPath), d.Line, d.Package, d.Command))
+	}
+	return lines
+}
+
+func TestFindGenerateDirectives(t *testing.T) {
+	root := writeFiles(t, generateTree)
+	project := NewGoProject(root, testFS{})
+
+	directives, err := FindGenerateDirectives(context.Background(), project, DefaultWalkOptions())
+	if err != nil {
+		t.Fatalf("FindGenerateDirectives: %v", err)
+	}
+	want := []string{
+		"a/a.go:3 ./a echo a1",
+		"a/a.go:4 ./a echo a2",
+		"a/b/b.go:2 ./a/b echo b",
+		"a-b/c.go:2 ./a-b echo a-b",
+		"main.go:3 . echo root",
+		"tools/gen/gen.go:2 ./gen echo tools",
+	}
+	if got := directiveSummary(directives); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindGenerateDirectives() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateGenerateDirectives(t *testing.T) {
+	root := writeFiles(t, generateTree)
+	project := NewGoProject(root, testFS{})
+	ctx := context.Background()
+
+	directives, err := FindGenerateDirectives(ctx, project, DefaultWalkOptions())
+	if err != nil {
+		t.Fatalf("FindGenerateDirectives: %v", err)
+	}
+
+	update := func(rel, src string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if src == "" {
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		found, err := FileGenerateDirectives(ctx, project, path)
+		if err != nil {
+			t.Fatalf("FileGenerateDirectives(%s): %v", rel, err)
+		}
+		directives = ReplaceGenerateDirectives(directives, path, found)
+	}
+
+	update("a/a.go", "package a\n//go:generate echo a3\n")
+	update("a/b/b.go", "")
+	update("a/z.go", "package a\n//go:generate echo z\n")
+	update("testdata/x.go", "package x\n//go:generate echo still skipped\n")
+
+	// Directives stay in the order a new scan finds them
+	want := []string{
+		"a/a.go:2 ./a echo a3",
+		"a/z.go:2 ./a echo z",
+		"a-b/c.go:2 ./a-b echo a-b",
+		"main.go:3 . echo root",
+		"tools/gen/gen.go:2 ./gen echo tools",
+	}
+	if got := directiveSummary(directives); !reflect.DeepEqual(got, want) {
+		t.Errorf("updated directives = %q, want %q", got, want)
+	}
+
+	scanned, err := FindGenerateDirectives(ctx, project, DefaultWalkOptions())
+	if err != nil {
+		t.Fatalf("FindGenerateDirectives: %v", err)
+	}
+	if !reflect.DeepEqual(scanned, directives) {
+		t.Errorf("rescan = %q, want the updated directives", directiveSummary(scanned))
+	}
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFS is a FileSystem over the OS whose listings of the directories in
+// fail return errors
+type testFS struct {
+	fail map[string]error
+}
+
+func (f testFS) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (f testFS) WriteFile(path string, data []byte) error {
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (f testFS) ListFiles(path string) ([]FileInfo, error) {
+	if err := f.fail[path]; err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileInfo
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, testFileInfo(filepath.Join(path, entry.Name()), entry.Name(), info))
+	}
+	return files, nil
+}
+
+func (f testFS) WalkDir(root string, fn func(FileInfo) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		return fn(testFileInfo(path, rel, info))
+	})
+}
+
+func (f testFS) Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// testFileInfo describes a file like the OS file system does
+func testFileInfo(path, rel string, info fs.FileInfo) FileInfo {
+	return FileInfo{
+		Name:     info.Name(),
+		Path:     path,
+		RelPath:  rel,
+		IsDir:    info.IsDir(),
+		Size:     info.Size(),
+		ModTime:  info.ModTime().Unix(),
+		Language: GetLanguageForFile(info.Name()),
+	}
+}
+
+// writeFiles creates files under a new temporary directory and returns
+// it. Names are slash-separated; a name ending in "/" is an empty
+// directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
@@ -0,0 +1,217 @@
+// Package core provides gitignore-style exclusion of project files.
+package core
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IgnoreFile lists IDE-only exclusions, with the syntax of .gitignore
+const IgnoreFile = ".goxignore"
+
+// ignoreFileNames are read in every directory; rules of later files
+// take precedence
+var ignoreFileNames = []string{".gitignore", IgnoreFile}
+
+// defaultIgnorePatterns hide dotfiles, vendor and node_modules
+// directories. They apply before the root ignore files, which may negate
+// them, e.g. "!.github/".
+var defaultIgnorePatterns = []string{".*", "vendor/", "node_modules/"}
+
+// ignoreRule is one pattern of an ignore file
+type ignoreRule struct {
+	base    string // Directory of the ignore file, relative to the root
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher decides which files of a tree are hidden from project
+// listings. It follows gitignore semantics: patterns of nested
+// .gitignore and .goxignore files apply to their directory, later and
+// deeper patterns override earlier ones, "!" negates and a trailing "/"
+// matches directories only. Ignore files are read as directories are
+// first visited; a matcher reflects the files at that time. It is safe
+// for concurrent use.
+type IgnoreMatcher struct {
+	root     string
+	readFile func(path string) ([]byte, error)
+
+	mu    sync.Mutex
+	rules map[string][]ignoreRule // By directory relative to root
+}
+
+// NewIgnoreMatcher creates a matcher for the tree at root, reading ignore
+// files with readFile
+func NewIgnoreMatcher(root string, readFile func(path string) ([]byte, error)) *IgnoreMatcher {
+	return &IgnoreMatcher{
+		root:     root,
+		readFile: readFile,
+		rules:    make(map[string][]ignoreRule),
+	}
+}
+
+// Ignored reports whether a file or directory is hidden. Callers walking
+// the tree skip ignored directories, so like git a file cannot be
+// re-included when a parent directory is excluded.
+func (m *IgnoreMatcher) Ignored(path string, isDir bool) bool {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	ignored := false
+	dir := "."
+	for {
+		for _, rule := range m.dirRules(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(strings.TrimPrefix(rel, rule.base+"/")) {
+				ignored = !rule.negate
+			}
+		}
+
+		next, _, ok := strings.Cut(strings.TrimPrefix(rel, dir+"/"), "/")
+		if !ok {
+			return ignored
+		}
+		if dir == "." {
+			dir = next
+		} else {
+			dir += "/" + next
+		}
+	}
+}
+
+// dirRules returns the rules of the ignore files in a directory relative
+// to the root, reading them on first use
+func (m *IgnoreMatcher) dirRules(dir string) []ignoreRule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []ignoreRule
+	if dir == "." {
+		rules = parseIgnoreRules(".", defaultIgnorePatterns)
+	}
+	for _, name := range ignoreFileNames {
+		data, err := m.readFile(filepath.Join(m.root, filepath.FromSlash(dir), name))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, parseIgnoreRules(dir, strings.Split(string(data), "\n"))...)
+	}
+
+	m.rules[dir] = rules
+	return rules
+}
+
+// parseIgnoreRules compiles the lines of an ignore file in dir, skipping
+// blank lines, comments and invalid patterns
+func parseIgnoreRules(dir string, lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		rule, ok := parseIgnoreRule(line)
+		if !ok {
+			continue
+		}
+		rule.base = dir
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// parseIgnoreRule compiles one line of an ignore file
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	if pattern, ok := strings.CutPrefix(line, "!"); ok {
+		rule.negate = true
+		line = pattern
+	}
+	if pattern, ok := strings.CutSuffix(line, "/"); ok {
+		rule.dirOnly = true
+		line = pattern
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A slash other than a trailing one anchors the pattern to the
+	// directory of the ignore file; otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	translateIgnorePattern(&expr, line)
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// translateIgnorePattern writes the regular expression of a gitignore
+// glob: "*" and "?" stop at slashes, "**" spans directories and
+// "[...]" is a character class
+func translateIgnorePattern(expr *strings.Builder, pattern string) {
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			// Zero or more directories
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+			// Everything inside the directory
+			expr.WriteString(".+")
+			i++
+		case c == '*':
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ignoreCheck is a path expected to be ignored or not
+type ignoreCheck struct {
+	path    string // Slash-separated, relative to the root
+	isDir   bool
+	ignored bool
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string // Ignore files
+		checks []ignoreCheck
+	}{
+		{
+			name:  "defaults hide dotfiles, vendor and node_modules",
+			files: map[string]string{},
+			checks: []ignoreCheck{
+				{".git", true, true},
+				{".env", false, true},
+				{"pkg/.cache", true, true},
+				{"vendor", true, true},
+				{"web/node_modules", true, true},
+				{"vendor.go", false, false},
+				{"main.go", false, false},
+			},
+		},
+		{
+			name:  "negation after an exclude",
+			files: map[string]string{".gitignore": "*.log\n!keep.log\n"},
+			checks: []ignoreCheck{
+				{"debug.log", false, true},
+				{"logs/app.log", false, true},
+				{"keep.log", false, false},
+				{"logs/keep.log", false, false},
+			},
+		},
+		{
+			name:  "later patterns override earlier ones",
+			files: map[string]string{".gitignore": "!keep.log\n*.log\n"},
+			checks: []ignoreCheck{
+				{"keep.log", false, true},
+			},
+		},
+		{
+			name:  "trailing slash matches directories only",
+			files: map[string]string{".gitignore": "build/\n"},
+			checks: []ignoreCheck{
+				{"build", true, true},
+				{"cmd/build", true, true},
+				{"build", false, false},
+				{"cmd/build", false, false},
+			},
+		},
+		{
+			name:  "unanchored patterns match at any depth",
+			files: map[string]string{".gitignore": "*.pb.go\ntmp\n"},
+			checks: []ignoreCheck{
+				{"api.pb.go", false, true},
+				{"api/v1/api.pb.go", false, true},
+				{"tmp", true, true},
+				{"a/b/tmp", false, true},
+				{"tmpl", false, false},
+			},
+		},
+		{
+			name:  "leading or middle slash anchors to the ignore file",
+			files: map[string]string{".gitignore": "/bin\ndocs/*.html\n"},
+			checks: []ignoreCheck{
+				{"bin", true, true},
+				{"cmd/bin", true, false},
+				{"docs/index.html", false, true},
+				{"site/docs/index.html", false, false},
+				{"docs/api/index.html", false, false},
+			},
+		},
+		{
+			name:  "double star between directories",
+			files: map[string]string{".gitignore": "a/**/b\n"},
+			checks: []ignoreCheck{
+				{"a/b", false, true},
+				{"a/x/b", false, true},
+				{"a/x/y/b", true, true},
+				{"c/a/b", false, false},
+				{"a/bc", false, false},
+			},
+		},
+		{
+			name:  "leading double star",
+			files: map[string]string{".gitignore": "**/gen\n"},
+			checks: []ignoreCheck{
+				{"gen", true, true},
+				{"x/y/gen", true, true},
+				{"x/generated", true, false},
+			},
+		},
+		{
+			name:  "trailing double star matches everything inside",
+			files: map[string]string{".gitignore": "out/**\n"},
+			checks: []ignoreCheck{
+				{"out", true, false},
+				{"out/a", false, true},
+				{"out/a/b", false, true},
+				{"x/out/a", false, false},
+			},
+		},
+		{
+			name:  "double star alone matches everything",
+			files: map[string]string{".gitignore": "**\n!main.go\n"},
+			checks: []ignoreCheck{
+				{"a", false, true},
+				{"x/y", true, true},
+				{"main.go", false, false},
+			},
+		},
+		{
+			name:  "single character wildcards and classes",
+			files: map[string]string{".gitignore": "file?.txt\n[abc].go\nz[!0-9].go\n"},
+			checks: []ignoreCheck{
+				{"file1.txt", false, true},
+				{"file10.txt", false, false},
+				{"docs/file2.txt", false, true},
+				{"b.go", false, true},
+				{"d.go", false, false},
+				{"zx.go", false, true},
+				{"z1.go", false, false},
+			},
+		},
+		{
+			name: "escapes",
+			files: map[string]string{".gitignore": "" +
+				"\\#notes\n" +
+				"\\!important\n" +
+				"my\\ file\n" +
+				"space\\ \n" +
+				"trailing   \n" +
+				"# a comment\n" +
+				"\n",
+			},
+			checks: []ignoreCheck{
+				{"#notes", false, true},
+				{"!important", false, true},
+				{"important", false, false},
+				{"my file", false, true},
+				{"space ", false, true},
+				{"space", false, false},
+				{"trailing", false, true},
+				{"# a comment", false, false},
+			},
+		},
+		{
+			name: "nested ignore files apply below their directory",
+			files: map[string]string{
+				".gitignore":     "*.tmp\n",
+				"sub/.gitignore": "*.gen\n/local\n!keep.tmp\n",
+			},
+			checks: []ignoreCheck{
+				{"a.gen", false, false},
+				{"sub/a.gen", false, true},
+				{"sub/deep/a.gen", false, true},
+				{"sub/local", true, true},
+				{"sub/deep/local", true, false},
+				{"local", true, false},
+				{"sub/x.tmp", false, true},
+				{"sub/keep.tmp", false, false},
+				{"keep.tmp", false, true},
+			},
+		},
+		{
+			name: ".goxignore overrides .gitignore in the same directory",
+			files: map[string]string{
+				"sub/.gitignore": "*.gen\n",
+				"sub/.goxignore": "!x.gen\ndist/\n",
+			},
+			checks: []ignoreCheck{
+				{"sub/a.gen", false, true},
+				{"sub/x.gen", false, false},
+				{"sub/dist", true, true},
+			},
+		},
+		{
+			name:  "root ignore files re-include over the defaults",
+			files: map[string]string{".gitignore": "!.github/\n!.golangci.yml\n"},
+			checks: []ignoreCheck{
+				{".github", true, false},
+				{".github", false, true},
+				{".golangci.yml", false, false},
+				{".git", true, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeFiles(t, tt.files)
+			m := NewIgnoreMatcher(root, os.ReadFile)
+			for _, check := range tt.checks {
+				path := filepath.Join(root, filepath.FromSlash(check.path))
+				if got := m.Ignored(path, check.isDir); got != check.ignored {
+					t.Errorf("Ignored(%q, dir=%t) = %t, want %t", check.path, check.isDir, got, check.ignored)
+				}
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherOutsideRoot(t *testing.T) {
+	root := writeFiles(t, map[string]string{".gitignore": "*\n"})
+	m := NewIgnoreMatcher(root, os.ReadFile)
+
+	for _, path := range []string{root, filepath.Dir(root), filepath.Join(filepath.Dir(root), "other")} {
+		if m.Ignored(path, true) {
+			t.Errorf("Ignored(%q) = true for a path that is not below the root", path)
+		}
+	}
+}
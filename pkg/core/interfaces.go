@@ -4,6 +4,7 @@ package core
 import (
 	"context"
 	"io"
+	"os/exec"
 )
 
 // Project represents a Go project with its metadata and operations
@@ -20,8 +21,30 @@ type Project interface {
 	// Files returns all files in the project
 	Files() ([]FileInfo, error)
 
+	// WalkFiles lists the files in the project with cancellation, bounds
+	// and progress reporting
+	WalkFiles(ctx context.Context, opts WalkOptions) ([]FileInfo, error)
+
 	// FileTree returns the project structure as a tree
 	FileTree() (TreeNode, error)
+
+	// ListDir returns the visible entries of one directory
+	ListDir(dir string) ([]FileInfo, error)
+
+	// Ignored reports whether listings of the project skip a file, by
+	// itself or through one of its directories
+	Ignored(path string) bool
+
+	// Workspace returns the modules of the project
+	Workspace(ctx context.Context) (*Workspace, error)
+
+	// ForModule returns the project of the module owning a file, which
+	// commands on that file operate on
+	ForModule(ctx context.Context, file string) Project
+
+	// FilesChanged tells the project that files were added, modified or
+	// removed, so that state derived from them is reloaded
+	FilesChanged(paths []string)
 }
 
 // FileInfo represents information about a file in the project
@@ -33,6 +56,14 @@ type FileInfo struct {
 	Size     int64
 	ModTime  int64
 	Language string
+
+	// Generated is set for Go files marked "Code generated ... DO NOT EDIT."
+	// in directory listings, and in walks with WalkOptions.MarkGenerated
+	Generated bool
+
+	// BuildExcluded is set for source files whose build constraints or
+	// name suffix exclude them from a BuildContext
+	BuildExcluded bool
 }
 
 // TreeNode represents a node in the project tree
@@ -79,19 +110,50 @@ type Editor interface {
 	IsDirty() bool
 }
 
-// Builder provides build operations for Go projects
+// Builder provides build operations for Go projects.
+// Command output is streamed line by line to the given OutputSink;
+// a nil sink discards it.
 type Builder interface {
-	// Build builds the project
-	Build(ctx context.Context, project Project) error
+	// Build builds the project and returns any compiler diagnostics
+	Build(ctx context.Context, project Project, out OutputSink) ([]Diagnostic, error)
+
+	// Check runs the check pipeline and returns the merged findings
+	Check(ctx context.Context, project Project, config CheckConfig, out OutputSink) (*CheckReport, error)
+
+	// BuildMatrix cross-compiles the project for several platforms
+	BuildMatrix(ctx context.Context, project Project, opts MatrixOptions, out OutputSink) ([]MatrixResult, error)
+
+	// Run runs the program described by a run configuration
+	Run(ctx context.Context, project Project, config RunConfig, out OutputSink) error
 
-	// Run runs the project
-	Run(ctx context.Context, project Project) error
+	// PrepareRun builds the program of a run configuration and returns an
+	// unstarted command for it, plus a cleanup func removing the binary
+	PrepareRun(ctx context.Context, project Project, config RunConfig, out OutputSink) (*exec.Cmd, func(), error)
 
-	// Test runs tests for the project
-	Test(ctx context.Context, project Project) error
+	// Test runs the selected tests and returns the parsed results
+	Test(ctx context.Context, project Project, opts TestOptions, out OutputSink) (*TestReport, error)
 
-	// Clean cleans build artifacts
-	Clean(ctx context.Context, project Project) error
+	// Cover runs the selected tests with coverage and returns the profile
+	Cover(ctx context.Context, project Project, opts TestOptions, out OutputSink) (*CoverageReport, error)
+
+	// Bench runs the selected benchmarks and returns the parsed results
+	Bench(ctx context.Context, project Project, opts BenchOptions, out OutputSink) (*BenchRun, error)
+
+	// Profile runs tests, benchmarks or a program with CPU or memory
+	// profiling and returns the profile
+	Profile(ctx context.Context, project Project, opts ProfileOptions, out OutputSink) (*Profile, error)
+
+	// Generate runs go:generate directives
+	Generate(ctx context.Context, project Project, opts GenerateOptions, out OutputSink) error
+
+	// RunTask runs a Makefile target or project script
+	RunTask(ctx context.Context, project Project, task Task, args []string, out OutputSink) error
+
+	// PlanClean lists what Clean would remove without removing anything
+	PlanClean(ctx context.Context, project Project, config CleanConfig) (*CleanPlan, error)
+
+	// Clean removes the configured build artifacts and runs go clean
+	Clean(ctx context.Context, project Project, config CleanConfig, out OutputSink) (*CleanPlan, error)
 }
 
 // UI represents the user interface layer
@@ -204,4 +266,85 @@ type Renderer interface {
 
 	// RenderError renders an error
 	RenderError(w io.Writer, err error) error
+
+	// RenderDiagnostics renders a numbered list of diagnostics
+	RenderDiagnostics(w io.Writer, root string, diags []Diagnostic) error
+
+	// RenderTestReport renders a test summary table with failures expanded
+	RenderTestReport(w io.Writer, report *TestReport) error
+
+	// RenderTestFailures renders only the failing tests with their output
+	RenderTestFailures(w io.Writer, report *TestReport) error
+
+	// RenderCoverage renders per-package and per-file coverage percentages
+	RenderCoverage(w io.Writer, report *CoverageReport) error
+
+	// RenderBenchRun renders the results of a benchmark run
+	RenderBenchRun(w io.Writer, run *BenchRun) error
+
+	// RenderBenchRuns renders a list of stored benchmark runs
+	RenderBenchRuns(w io.Writer, runs []*BenchRun) error
+
+	// RenderBenchComparison renders the deltas between two benchmark runs
+	RenderBenchComparison(w io.Writer, base, head *BenchRun, deltas []BenchDelta) error
+
+	// RenderCheckReport renders the status of each check step
+	RenderCheckReport(w io.Writer, report *CheckReport) error
+
+	// RenderMatrixResults renders the per-target results of a matrix build
+	RenderMatrixResults(w io.Writer, root string, results []MatrixResult) error
+
+	// RenderCleanPlan renders the paths and caches a clean removes
+	RenderCleanPlan(w io.Writer, root string, plan *CleanPlan, dryRun bool) error
+
+	// RenderBreakpoints renders the debugger's breakpoints
+	RenderBreakpoints(w io.Writer, root string, breakpoints []Breakpoint) error
+
+	// RenderStackTrace renders the call stack of a stopped goroutine
+	RenderStackTrace(w io.Writer, root string, frames []StackFrame) error
+
+	// RenderVariables renders variables of a stack frame
+	RenderVariables(w io.Writer, vars []Variable) error
+
+	// RenderGoroutines renders the goroutines of the debugged program,
+	// marking the one that stopped
+	RenderGoroutines(w io.Writer, threads []DebugThread, current int) error
+
+	// RenderProfile renders the costliest functions of a profile
+	RenderProfile(w io.Writer, root string, report *ProfileReport, entries []ProfileEntry) error
+
+	// RenderGenerateDirectives renders a numbered list of go:generate
+	// directives
+	RenderGenerateDirectives(w io.Writer, directives []GenerateDirective) error
+
+	// RenderSymbols renders a numbered list of symbol search results
+	RenderSymbols(w io.Writer, root string, matches []SymbolMatch) error
+
+	// RenderWorkspace renders the modules of a workspace, marking the
+	// one in the active directory
+	RenderWorkspace(w io.Writer, ws *Workspace, active string) error
+
+	// RenderModules renders the dependencies of the main module with
+	// their versions
+	RenderModules(w io.Writer, graph *ModuleGraph) error
+
+	// RenderModuleRequirements renders the modules requiring a module
+	RenderModuleRequirements(w io.Writer, graph *ModuleGraph, path string, reqs []ModuleRequirement) error
+
+	// RenderModuleWhy renders the import chain explaining why a module is
+	// needed
+	RenderModuleWhy(w io.Writer, why *ModuleWhy) error
+
+	// RenderTasks renders the discovered project tasks
+	RenderTasks(w io.Writer, tasks []Task) error
+
+	// RenderProcesses renders a table of background processes
+	RenderProcesses(w io.Writer, processes []*Process) error
+
+	// RenderProcessLogs renders the retained output of a process
+	RenderProcessLogs(w io.Writer, process *Process) error
+
+	// SetCoverage sets the coverage used to annotate lines in RenderFile;
+	// nil disables annotation
+	SetCoverage(report *CoverageReport)
 }
@@ -0,0 +1,239 @@
+// Package core provides a lazily loaded file tree for large projects.
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// lazyRoot is a top-level directory of a LazyTree and the project it
+// belongs to
+type lazyRoot struct {
+	file    FileInfo
+	project Project
+}
+
+// LazyTree is a project file tree whose directories are read on first
+// use and cached until invalidated. Every module is a root of its own:
+// the modules of a go.work file are read from it, and otherwise nested
+// modules are found as their parent directories are listed, so nothing
+// is walked up front. It is safe for concurrent use, so directories can
+// load in the background.
+type LazyTree struct {
+	project Project
+
+	mu        sync.Mutex
+	loaded    bool                  // The go.work file has been read
+	workspace bool                  // Modules come from go.work rather than listings
+	modules   map[string]lazyRoot   // Module roots other than the project, by directory
+	dirs      map[string][]FileInfo // Entries by directory path
+	gen       int                   // Incremented by invalidation to drop loads in flight
+}
+
+// NewLazyTree creates a tree for the project; nothing is read until used
+func NewLazyTree(project Project) *LazyTree {
+	return &LazyTree{
+		project: project,
+		modules: make(map[string]lazyRoot),
+		dirs:    make(map[string][]FileInfo),
+	}
+}
+
+// Roots returns the project directory followed by the directories of the
+// modules found so far, sorted by directory and named after their module
+// paths. Only the project directory and its go.work file are read; call
+// Roots again after listing directories to pick up the modules found.
+func (t *LazyTree) Roots(ctx context.Context) ([]FileInfo, error) {
+	root := t.project.Path()
+
+	t.mu.Lock()
+	loaded, gen := t.loaded, t.gen
+	t.mu.Unlock()
+
+	if !loaded {
+		// A workspace lists its modules in go.work, which is read without
+		// walking the tree
+		var modules []lazyRoot
+		_, err := os.Stat(filepath.Join(root, "go.work"))
+		workspace := err == nil
+		if workspace {
+			ws, err := t.project.Workspace(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, mod := range ws.Modules {
+				if mod.Dir != root {
+					modules = append(modules, t.moduleRoot(ctx, mod))
+				}
+			}
+		}
+
+		t.mu.Lock()
+		if t.gen == gen {
+			t.loaded, t.workspace = true, workspace
+			for _, mod := range modules {
+				t.modules[mod.file.Path] = mod
+			}
+		}
+		t.mu.Unlock()
+	}
+
+	if _, err := t.Children(ctx, root); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	files := []FileInfo{{Name: t.project.Name(), Path: root, RelPath: ".", IsDir: true}}
+	dirs := make([]string, 0, len(t.modules))
+	for dir := range t.modules {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	for _, dir := range dirs {
+		files = append(files, t.modules[dir].file)
+	}
+	return files, nil
+}
+
+// Children returns the entries of a directory, reading it on first use.
+// The directory is read without holding the lock. Without a go.work
+// file, subdirectories holding a go.mod file outside testdata become
+// module roots; module roots are left out of their parent's entries.
+func (t *LazyTree) Children(ctx context.Context, dir string) ([]FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if entries, ok := t.dirs[dir]; ok {
+		t.mu.Unlock()
+		return entries, nil
+	}
+	owner, gen, detect := t.owner(dir), t.gen, !t.workspace
+	t.mu.Unlock()
+
+	entries, err := owner.ListDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []lazyRoot
+	if detect {
+		for _, entry := range entries {
+			if !entry.IsDir || t.inTestdata(entry.Path) {
+				continue
+			}
+			if data, err := os.ReadFile(filepath.Join(entry.Path, "go.mod")); err == nil {
+				mod := WorkspaceModule{Path: ParseModulePath(data), Dir: entry.Path}
+				found = append(found, t.moduleRoot(ctx, mod))
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.gen == gen {
+		for _, mod := range found {
+			t.modules[mod.file.Path] = mod
+		}
+	}
+	visible := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if _, ok := t.modules[entry.Path]; !ok || !entry.IsDir {
+			visible = append(visible, entry)
+		}
+	}
+	if t.gen == gen {
+		t.dirs[dir] = visible
+	}
+	return visible, nil
+}
+
+// IsModule reports whether dir is the directory of a module other than
+// the project itself, as far as the tree has been read
+func (t *LazyTree) IsModule(dir string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.modules[dir]
+	return ok
+}
+
+// Cached returns the entries of a directory if they have been read
+func (t *LazyTree) Cached(dir string) ([]FileInfo, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, ok := t.dirs[dir]
+	return entries, ok
+}
+
+// Invalidate drops the cached entries of directories, which are read
+// again on next use
+func (t *LazyTree) Invalidate(dirs ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, dir := range dirs {
+		delete(t.dirs, dir)
+	}
+	t.gen++
+}
+
+// Reset drops every cached directory and the modules found, which are
+// found again from the go.work file or as directories are listed
+func (t *LazyTree) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.loaded, t.workspace = false, false
+	t.modules = make(map[string]lazyRoot)
+	t.dirs = make(map[string][]FileInfo)
+	t.gen++
+}
+
+// moduleRoot returns the root of a module, named after its module path
+func (t *LazyTree) moduleRoot(ctx context.Context, mod WorkspaceModule) lazyRoot {
+	name := mod.Path
+	if name == "" {
+		name = filepath.Base(mod.Dir)
+	}
+	return lazyRoot{
+		file:    FileInfo{Name: name, Path: mod.Dir, RelPath: ".", IsDir: true},
+		project: t.project.ForModule(ctx, mod.Dir),
+	}
+}
+
+// inTestdata reports whether a path of the project is inside a testdata
+// directory, whose modules the go command ignores
+func (t *LazyTree) inTestdata(path string) bool {
+	rel, err := filepath.Rel(t.project.Path(), path)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(strings.Split(rel, string(filepath.Separator)), "testdata")
+}
+
+// owner returns the project of the root containing dir; t.mu must be held
+func (t *LazyTree) owner(dir string) Project {
+	var best lazyRoot
+	for _, root := range t.modules {
+		if dir != root.file.Path && !strings.HasPrefix(dir, root.file.Path+string(filepath.Separator)) {
+			continue
+		}
+		if len(root.file.Path) > len(best.file.Path) {
+			best = root
+		}
+	}
+	if best.project == nil {
+		return t.project
+	}
+	return best.project
+}
@@ -0,0 +1,217 @@
+// Package core provides cross-compilation of a project for several platforms.
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MatrixOutputDir is the default output directory of matrix builds,
+// relative to the project root. Clean removes it.
+const MatrixOutputDir = "dist"
+
+// BuildTarget is a GOOS/GOARCH pair to compile for
+type BuildTarget struct {
+	GOOS   string
+	GOARCH string
+}
+
+// DefaultBuildTargets mirrors the platforms of the Makefile's build-all target
+var DefaultBuildTargets = []BuildTarget{
+	{GOOS: "linux", GOARCH: "amd64"},
+	{GOOS: "windows", GOARCH: "amd64"},
+	{GOOS: "darwin", GOARCH: "amd64"},
+	{GOOS: "darwin", GOARCH: "arm64"},
+}
+
+// String returns the target as "os/arch"
+func (t BuildTarget) String() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+// ParseBuildTarget parses an "os/arch" target
+func ParseBuildTarget(s string) (BuildTarget, error) {
+	goos, goarch, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return BuildTarget{}, fmt.Errorf("invalid build target %q, expected os/arch", s)
+	}
+	return BuildTarget{GOOS: goos, GOARCH: goarch}, nil
+}
+
+// ParseBuildTargets parses a comma-separated list of "os/arch" targets
+func ParseBuildTargets(s string) ([]BuildTarget, error) {
+	var targets []BuildTarget
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		target, err := ParseBuildTarget(part)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// MatrixOptions selects what a matrix build compiles and for which targets
+type MatrixOptions struct {
+	Targets  []BuildTarget // Defaults to DefaultBuildTargets
+	Package  string        // Package to build; defaults to "."
+	Tags     []string
+	LDFlags  string
+	OutDir   string // Relative to the project root; defaults to MatrixOutputDir
+	Parallel int    // Concurrent builds; defaults to the number of CPUs
+}
+
+// MatrixResult is the outcome of building one target
+type MatrixResult struct {
+	Target      BuildTarget
+	Output      string // Path of the binary
+	Size        int64
+	Duration    time.Duration
+	Err         error
+	Diagnostics []Diagnostic
+}
+
+// BuildMatrix cross-compiles the project for every target with a bounded
+// pool of concurrent builds, writing dist/<name>-<os>-<arch> binaries.
+// Output lines are prefixed with their target. Results are returned in
+// target order; the error reports how many targets failed.
+func (b *GoBuilder) BuildMatrix(ctx context.Context, project Project, opts MatrixOptions, out OutputSink) ([]MatrixResult, error) {
+	if !project.IsGoProject() {
+		return nil, ErrNotGoProject
+	}
+
+	targets := opts.Targets
+	if len(targets) == 0 {
+		targets = DefaultBuildTargets
+	}
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+	outDir := opts.OutDir
+	if outDir == "" {
+		outDir = MatrixOutputDir
+	}
+	outDir = filepath.Join(project.Path(), outDir)
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if b.logger != nil {
+		b.logger.Info("Building matrix",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "targets", Value: len(targets)})
+	}
+
+	out = sinkOrDiscard(out)
+	results := make([]MatrixResult, len(targets))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(parallel, len(targets)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = b.buildTarget(ctx, project, opts, targets[i], outDir, out)
+			}
+		}()
+	}
+
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d targets failed", failed, len(targets))
+	}
+	return results, nil
+}
+
+// buildTarget builds a single matrix target
+func (b *GoBuilder) buildTarget(ctx context.Context, project Project, opts MatrixOptions, target BuildTarget, outDir string, out OutputSink) MatrixResult {
+	result := MatrixResult{Target: target}
+	start := time.Now()
+
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "."
+	}
+	name := project.Name()
+	if pkg != "." {
+		name = filepath.Base(pkg)
+	}
+	name = fmt.Sprintf("%s-%s-%s", name, target.GOOS, target.GOARCH)
+	switch target.GOOS {
+	case "windows":
+		name += ".exe"
+	case "js", "wasip1":
+		name += ".wasm"
+	}
+	result.Output = filepath.Join(outDir, name)
+
+	config := RunConfig{Tags: opts.Tags, LDFlags: opts.LDFlags}
+	args := append([]string{"build", "-o", result.Output}, config.BuildFlags()...)
+	args = append(args, pkg)
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = project.Path()
+	cmd.Env = append(os.Environ(), "GOOS="+target.GOOS, "GOARCH="+target.GOARCH)
+
+	// Prefix output with the target and collect stderr for diagnostics
+	var (
+		mu     sync.Mutex
+		stderr strings.Builder
+	)
+	prefix := "[" + target.String() + "] "
+	sink := OutputSinkFunc(func(line OutputLine) {
+		if line.Stream == StreamStderr {
+			mu.Lock()
+			stderr.WriteString(line.Text)
+			stderr.WriteByte('\n')
+			mu.Unlock()
+		}
+		line.Text = prefix + line.Text
+		out.WriteLine(line)
+	})
+
+	result.Err = runCommand(cmd, sink)
+	result.Duration = time.Since(start)
+	result.Diagnostics = ParseDiagnostics(stderr.String(), project.Path())
+
+	if result.Err == nil {
+		if info, err := os.Stat(result.Output); err == nil {
+			result.Size = info.Size()
+		} else {
+			result.Err = err
+		}
+	} else if errors.Is(ctx.Err(), context.Canceled) {
+		result.Err = ctx.Err()
+	} else if len(result.Diagnostics) == 0 && stderr.Len() > 0 {
+		// Errors such as an unsupported target are not diagnostics
+		first, _, _ := strings.Cut(stderr.String(), "\n")
+		result.Err = fmt.Errorf("%w: %s", result.Err, first)
+	}
+
+	return result
+}
@@ -0,0 +1,260 @@
+// Package core provides the module dependency graph of a project.
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrModuleNotFound is returned for a module that is not in the build list
+var ErrModuleNotFound = errors.New("module not in the build list")
+
+// GoModule is a module as reported by go list -m -json
+type GoModule struct {
+	Path      string
+	Version   string
+	Main      bool
+	Indirect  bool // Only required for the packages of other modules
+	Dir       string
+	GoVersion string
+	Replace   *GoModule
+}
+
+// ID returns "path@version", or the path of the main module, as used by
+// go mod graph
+func (m *GoModule) ID() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// ModuleRequirement is an edge of the module graph: From requires To
+type ModuleRequirement struct {
+	From string // "path@version", or the main module path
+	To   string // "path@version"
+}
+
+// ModuleGraph is the build list of a module and its requirement graph
+type ModuleGraph struct {
+	Modules      []*GoModule // Main module first, then sorted by path
+	Requirements []ModuleRequirement
+
+	byPath map[string]*GoModule
+}
+
+// ModuleWhy explains why a module is needed, as reported by go mod why
+type ModuleWhy struct {
+	Module    string
+	Packages  []string // Shortest import chain from a main package
+	NotNeeded bool     // The main module's packages do not import it
+}
+
+// LoadModuleGraph lists the build list of the module in dir with
+// go list -m -json all and its requirements with go mod graph
+func LoadModuleGraph(ctx context.Context, dir string) (*ModuleGraph, error) {
+	list, err := goOutput(ctx, dir, "list", "-m", "-json", "all")
+	if err != nil {
+		return nil, err
+	}
+	graph, err := goOutput(ctx, dir, "mod", "graph")
+	if err != nil {
+		return nil, err
+	}
+	return ParseModuleGraph(bytes.NewReader(list), bytes.NewReader(graph))
+}
+
+// ParseModuleGraph parses the output of go list -m -json all and of
+// go mod graph
+func ParseModuleGraph(list, graph io.Reader) (*ModuleGraph, error) {
+	g := &ModuleGraph{byPath: make(map[string]*GoModule)}
+
+	dec := json.NewDecoder(list)
+	for {
+		var mod GoModule
+		if err := dec.Decode(&mod); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list -m output: %w", err)
+		}
+		g.Modules = append(g.Modules, &mod)
+		g.byPath[mod.Path] = &mod
+	}
+	sort.SliceStable(g.Modules, func(i, j int) bool {
+		a, b := g.Modules[i], g.Modules[j]
+		if a.Main != b.Main {
+			return a.Main
+		}
+		return a.Path < b.Path
+	})
+
+	scanner := bufio.NewScanner(graph)
+	for scanner.Scan() {
+		from, to, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		to = strings.TrimSpace(to)
+		if !ok || isToolchainModule(from) || isToolchainModule(to) {
+			continue
+		}
+		g.Requirements = append(g.Requirements, ModuleRequirement{From: from, To: to})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("go mod graph output: %w", err)
+	}
+
+	return g, nil
+}
+
+// Main returns the main module, or nil
+func (g *ModuleGraph) Main() *GoModule {
+	if len(g.Modules) > 0 && g.Modules[0].Main {
+		return g.Modules[0]
+	}
+	return nil
+}
+
+// Module returns the module with the given path from the build list
+func (g *ModuleGraph) Module(path string) (*GoModule, bool) {
+	mod, ok := g.byPath[modulePath(path)]
+	return mod, ok
+}
+
+// Dependencies returns the modules of the build list other than the main
+// module, direct dependencies first
+func (g *ModuleGraph) Dependencies() []*GoModule {
+	var direct, indirect []*GoModule
+	for _, mod := range g.Modules {
+		switch {
+		case mod.Main:
+		case mod.Indirect:
+			indirect = append(indirect, mod)
+		default:
+			direct = append(direct, mod)
+		}
+	}
+	return append(direct, indirect...)
+}
+
+// RequiredBy returns the requirements on any version of a module, sorted
+// by requiring module. Requirements from modules not in the build list,
+// i.e. versions that lost to a newer one, are included.
+func (g *ModuleGraph) RequiredBy(path string) []ModuleRequirement {
+	path = modulePath(path)
+
+	var reqs []ModuleRequirement
+	for _, req := range g.Requirements {
+		if modulePath(req.To) == path {
+			reqs = append(reqs, req)
+		}
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].From < reqs[j].From
+	})
+	return reqs
+}
+
+// Selected reports whether a "path@version" node of the graph is the
+// version in the build list
+func (g *ModuleGraph) Selected(id string) bool {
+	mod, ok := g.byPath[modulePath(id)]
+	return ok && mod.ID() == id
+}
+
+// WriteDOT writes the requirement graph in Graphviz DOT format. Versions
+// not in the build list are drawn dashed.
+func (g *ModuleGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "digraph modules {\n")
+	fmt.Fprint(bw, "\trankdir=LR;\n")
+	fmt.Fprint(bw, "\tnode [shape=box];\n")
+
+	nodes := make(map[string]bool)
+	for _, req := range g.Requirements {
+		nodes[req.From] = true
+		nodes[req.To] = true
+	}
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		switch mod, _ := g.Module(id); {
+		case mod != nil && mod.Main && mod.ID() == id:
+			fmt.Fprintf(bw, "\t%s [style=bold];\n", strconv.Quote(id))
+		case !g.Selected(id):
+			fmt.Fprintf(bw, "\t%s [style=dashed];\n", strconv.Quote(id))
+		}
+	}
+	for _, req := range g.Requirements {
+		fmt.Fprintf(bw, "\t%s -> %s;\n", strconv.Quote(req.From), strconv.Quote(req.To))
+	}
+
+	fmt.Fprint(bw, "}\n")
+	return bw.Flush()
+}
+
+// WhyModule runs go mod why -m for a module in the project at dir
+func WhyModule(ctx context.Context, dir, path string) (*ModuleWhy, error) {
+	path = modulePath(path)
+	output, err := goOutput(ctx, dir, "mod", "why", "-m", path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseModuleWhy(path, bytes.NewReader(output))
+}
+
+// ParseModuleWhy parses the output of go mod why -m for one module
+func ParseModuleWhy(path string, r io.Reader) (*ModuleWhy, error) {
+	why := &ModuleWhy{Module: path}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "# "):
+		case strings.HasPrefix(line, "("):
+			// "(main module does not need module x)"
+			why.NotNeeded = true
+		default:
+			why.Packages = append(why.Packages, line)
+		}
+	}
+	return why, scanner.Err()
+}
+
+// isToolchainModule reports whether a graph node is one of the go and
+// toolchain requirements listed by go mod graph since Go 1.21
+func isToolchainModule(id string) bool {
+	path := modulePath(id)
+	return path == "go" || path == "toolchain"
+}
+
+// modulePath strips the version from "path@version"
+func modulePath(id string) string {
+	path, _, _ := strings.Cut(id, "@")
+	return path
+}
+
+// goOutput runs a go command in dir and returns its standard output
+func goOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
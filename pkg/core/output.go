@@ -0,0 +1,236 @@
+// Package core provides streaming of command output to UI sinks.
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// maxOutputLineSize bounds a single line of command output
+const maxOutputLineSize = 1024 * 1024 // 1MB
+
+// OutputStream identifies which stream a line was written to
+type OutputStream int
+
+const (
+	StreamStdout OutputStream = iota
+	StreamStderr
+)
+
+// String returns the string representation of the stream
+func (s OutputStream) String() string {
+	switch s {
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// OutputLine is a single line of command output
+type OutputLine struct {
+	Stream OutputStream
+	Text   string
+	Time   time.Time
+}
+
+// OutputSink receives command output line by line.
+// Implementations must be safe for concurrent use since stdout and
+// stderr are delivered from separate goroutines.
+type OutputSink interface {
+	WriteLine(line OutputLine)
+}
+
+// OutputSinkFunc adapts a function to the OutputSink interface
+type OutputSinkFunc func(line OutputLine)
+
+// WriteLine calls f(line)
+func (f OutputSinkFunc) WriteLine(line OutputLine) {
+	f(line)
+}
+
+// ChannelSink delivers output lines on a channel.
+// The caller owns the channel and is responsible for draining it.
+type ChannelSink chan OutputLine
+
+// WriteLine sends the line on the channel
+func (c ChannelSink) WriteLine(line OutputLine) {
+	c <- line
+}
+
+// WriterSink writes output lines to a pair of writers
+type WriterSink struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewWriterSink creates a sink writing stdout and stderr lines to the given writers.
+// A nil stderr writer sends both streams to stdout.
+func NewWriterSink(stdout, stderr io.Writer) *WriterSink {
+	if stderr == nil {
+		stderr = stdout
+	}
+
+	return &WriterSink{
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+// WriteLine writes the line to the writer for its stream
+func (s *WriterSink) WriteLine(line OutputLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.stdout
+	if line.Stream == StreamStderr {
+		w = s.stderr
+	}
+	fmt.Fprintln(w, line.Text)
+}
+
+// RingSink keeps the most recent output lines in memory
+type RingSink struct {
+	mu    sync.Mutex
+	lines []OutputLine
+	next  int // Index of the oldest line once the buffer is full
+	full  bool
+}
+
+// NewRingSink creates a sink retaining at most capacity lines
+func NewRingSink(capacity int) *RingSink {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &RingSink{
+		lines: make([]OutputLine, 0, capacity),
+	}
+}
+
+// WriteLine stores the line, evicting the oldest one when full
+func (r *RingSink) WriteLine(line OutputLine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		r.lines = append(r.lines, line)
+		r.full = len(r.lines) == cap(r.lines)
+		return
+	}
+
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+}
+
+// Lines returns a copy of the retained lines, oldest first
+func (r *RingSink) Lines() []OutputLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := make([]OutputLine, 0, len(r.lines))
+	lines = append(lines, r.lines[r.next:]...)
+	return append(lines, r.lines[:r.next]...)
+}
+
+// multiSink duplicates output to several sinks
+type multiSink []OutputSink
+
+func (m multiSink) WriteLine(line OutputLine) {
+	for _, sink := range m {
+		sink.WriteLine(line)
+	}
+}
+
+// MultiSink returns a sink that writes every line to each non-nil sink
+func MultiSink(sinks ...OutputSink) OutputSink {
+	var m multiSink
+	for _, sink := range sinks {
+		if sink != nil {
+			m = append(m, sink)
+		}
+	}
+	return m
+}
+
+// discardSink drops all output
+type discardSink struct{}
+
+func (discardSink) WriteLine(OutputLine) {}
+
+// sinkOrDiscard returns out, or a sink that drops everything if out is nil
+func sinkOrDiscard(out OutputSink) OutputSink {
+	if out == nil {
+		return discardSink{}
+	}
+	return out
+}
+
+// runCommand runs cmd, streaming its stdout and stderr to out line by line
+func runCommand(cmd *exec.Cmd, out OutputSink) error {
+	wait, err := startCommand(cmd, out)
+	if err != nil {
+		return err
+	}
+	return wait()
+}
+
+// startCommand starts cmd, streaming its stdout and stderr to out line by
+// line. The returned function waits for the output to drain and the
+// command to exit.
+func startCommand(cmd *exec.Cmd, out OutputSink) (func() error, error) {
+	out = sinkOrDiscard(out)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// Both pipes must be drained before Wait closes them
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streamLines(stdout, StreamStdout, out)
+	}()
+	go func() {
+		defer wg.Done()
+		streamLines(stderr, StreamStderr, out)
+	}()
+
+	return func() error {
+		wg.Wait()
+		return cmd.Wait()
+	}, nil
+}
+
+// streamLines reads r line by line and forwards each line to out
+func streamLines(r io.Reader, stream OutputStream, out OutputSink) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputLineSize)
+
+	for scanner.Scan() {
+		out.WriteLine(OutputLine{
+			Stream: stream,
+			Text:   scanner.Text(),
+			Time:   time.Now(),
+		})
+	}
+
+	// Keep draining after an overlong line so the process never blocks
+	io.Copy(io.Discard, r)
+}
@@ -0,0 +1,241 @@
+// Package core provides the package import graph of a project.
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GoPackage is a package as reported by go list -json
+type GoPackage struct {
+	ImportPath   string
+	Name         string
+	Dir          string
+	Standard     bool
+	DepOnly      bool   // Only a dependency of the listed packages
+	ForTest      string // Package whose tests this variant is recompiled for
+	Export       string // Export data file, with go list -export
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// PackageGraph is the import graph of the packages in a module and their
+// dependencies
+type PackageGraph struct {
+	Packages map[string]*GoPackage
+
+	byDir   map[string]*GoPackage
+	reverse map[string][]string // Import path → packages importing it, tests included
+}
+
+// LoadPackageGraph lists the packages matched by ./... in dir and all
+// their dependencies
+func LoadPackageGraph(ctx context.Context, dir string) (*PackageGraph, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-json", "-deps", "-e", "./...")
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return ParsePackageGraph(bytes.NewReader(data))
+}
+
+// ParsePackageGraph parses the concatenated JSON objects printed by
+// go list -json
+func ParsePackageGraph(r io.Reader) (*PackageGraph, error) {
+	graph := &PackageGraph{
+		Packages: make(map[string]*GoPackage),
+		byDir:    make(map[string]*GoPackage),
+		reverse:  make(map[string][]string),
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var pkg GoPackage
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list output: %w", err)
+		}
+
+		graph.Packages[pkg.ImportPath] = &pkg
+		if pkg.Dir != "" {
+			graph.byDir[pkg.Dir] = &pkg
+		}
+	}
+
+	for _, pkg := range graph.Packages {
+		seen := make(map[string]bool)
+		for _, imports := range [][]string{pkg.Imports, pkg.TestImports, pkg.XTestImports} {
+			for _, imp := range imports {
+				if imp == pkg.ImportPath || seen[imp] {
+					continue
+				}
+				seen[imp] = true
+				graph.reverse[imp] = append(graph.reverse[imp], pkg.ImportPath)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// Local returns the import paths of the packages matched by ./..., sorted
+func (g *PackageGraph) Local() []string {
+	var paths []string
+	for path, pkg := range g.Packages {
+		if !pkg.DepOnly {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// PackageForFile returns the package containing a file. Files in a
+// testdata directory belong to the package owning that directory.
+func (g *PackageGraph) PackageForFile(file string) (*GoPackage, bool) {
+	dir := filepath.Dir(file)
+	if i := strings.Index(filepath.ToSlash(file), "/testdata/"); i >= 0 {
+		dir = filepath.FromSlash(filepath.ToSlash(file)[:i])
+	}
+
+	pkg, ok := g.byDir[dir]
+	return pkg, ok
+}
+
+// ImportedBy returns the import paths of the packages that import path
+// directly, from regular code or tests
+func (g *PackageGraph) ImportedBy(path string) []string {
+	return g.reverse[path]
+}
+
+// Affected returns the local packages that are, or transitively import,
+// one of the given packages, sorted
+func (g *PackageGraph) Affected(paths []string) []string {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), paths...)
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		queue = append(queue, g.reverse[path]...)
+	}
+
+	var affected []string
+	for path := range seen {
+		if pkg, ok := g.Packages[path]; ok && !pkg.DepOnly {
+			affected = append(affected, path)
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+// AffectedByFiles returns the local packages affected by changed files:
+// the packages containing them and their reverse dependencies. A change
+// to go.mod or go.sum affects every local package.
+func (g *PackageGraph) AffectedByFiles(files []string) []string {
+	var changed []string
+	for _, file := range files {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return g.Local()
+		}
+		if pkg, ok := g.PackageForFile(file); ok {
+			changed = append(changed, pkg.ImportPath)
+		}
+	}
+	return g.Affected(changed)
+}
+
+// AffectedTestOptions returns options testing the packages affected by
+// changed files in the project at root. It returns false if no package
+// is affected.
+func AffectedTestOptions(ctx context.Context, root string, changed []string) (TestOptions, bool, error) {
+	if len(changed) == 0 {
+		return TestOptions{}, false, nil
+	}
+
+	graph, err := LoadPackageGraph(ctx, root)
+	if err != nil {
+		return TestOptions{}, false, err
+	}
+
+	affected := graph.AffectedByFiles(changed)
+	if len(affected) == 0 {
+		return TestOptions{}, false, nil
+	}
+	return TestOptions{Packages: affected}, true, nil
+}
+
+// ChangedFiles returns the absolute paths of files under dir that differ
+// from the last git commit, including untracked files
+func ChangedFiles(ctx context.Context, dir string) ([]string, error) {
+	// Paths are printed relative to dir and limited to it
+	modified, err := gitLines(ctx, dir, "diff", "--name-only", "--relative", "HEAD")
+	if err != nil {
+		// A repository without commits has nothing to diff against
+		modified, err = gitLines(ctx, dir, "ls-files", "--cached")
+		if err != nil {
+			return nil, err
+		}
+	}
+	untracked, err := gitLines(ctx, dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, rel := range append(modified, untracked...) {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// gitLines runs a git command in dir and returns its non-empty output lines
+func gitLines(ctx context.Context, dir string, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,477 @@
+// Package core provides parsing of pprof profiles.
+package core
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidProfile is returned for data that is not a pprof profile
+var ErrInvalidProfile = errors.New("invalid pprof profile")
+
+// ProfileValueType describes a sample value, e.g. "cpu" in "nanoseconds"
+type ProfileValueType struct {
+	Type string
+	Unit string
+}
+
+// ProfileFunction is a function referenced by profile locations
+type ProfileFunction struct {
+	Name      string
+	File      string
+	StartLine int64
+}
+
+// ProfileLine is a source line of a location
+type ProfileLine struct {
+	Function *ProfileFunction
+	Line     int64
+}
+
+// ProfileLocation is a program counter. Lines[0] is the innermost
+// function when calls were inlined.
+type ProfileLocation struct {
+	Lines []ProfileLine
+}
+
+// ProfileSample is a recorded stack with its values. Locations[0] is
+// the leaf; Values align with Profile.SampleTypes.
+type ProfileSample struct {
+	Locations []*ProfileLocation
+	Values    []int64
+}
+
+// Profile is a decoded pprof profile (profile.proto)
+type Profile struct {
+	SampleTypes       []ProfileValueType
+	Samples           []ProfileSample
+	DefaultSampleType string
+	PeriodType        ProfileValueType
+	Period            int64
+	DurationNanos     int64
+}
+
+// SampleIndex returns the index of the named sample type. An empty name
+// selects the profile's default sample type, or the last one.
+func (p *Profile) SampleIndex(name string) (int, error) {
+	if len(p.SampleTypes) == 0 {
+		return 0, fmt.Errorf("%w: no sample types", ErrInvalidProfile)
+	}
+	if name == "" {
+		name = p.DefaultSampleType
+	}
+	if name == "" {
+		return len(p.SampleTypes) - 1, nil
+	}
+
+	for i, st := range p.SampleTypes {
+		if st.Type == name {
+			return i, nil
+		}
+	}
+
+	names := make([]string, len(p.SampleTypes))
+	for i, st := range p.SampleTypes {
+		names[i] = st.Type
+	}
+	return 0, fmt.Errorf("unknown sample type %q (have %v)", name, names)
+}
+
+// ParseProfile decodes a pprof profile, gzip-compressed as written by the
+// runtime or uncompressed
+func ParseProfile(r io.Reader) (*Profile, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeProfile(data)
+}
+
+// Field numbers of profile.proto messages
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileDurationNanos     = 10
+	profilePeriodType        = 11
+	profilePeriod            = 12
+	profileDefaultSampleType = 14
+)
+
+// Messages with string table indexes, resolved once the table is read
+type (
+	rawValueType struct{ typ, unit int64 }
+	rawSample    struct {
+		locationIDs []uint64
+		values      []int64
+	}
+	rawLine     struct{ functionID, line uint64 }
+	rawLocation struct {
+		id    uint64
+		lines []rawLine
+	}
+	rawFunction struct {
+		id                        uint64
+		name, filename, startLine int64
+	}
+)
+
+// decodeProfile decodes an uncompressed profile.proto message
+func decodeProfile(data []byte) (*Profile, error) {
+	var (
+		sampleTypes       []rawValueType
+		samples           []rawSample
+		locations         []rawLocation
+		functions         []rawFunction
+		strs              []string
+		periodType        rawValueType
+		defaultSampleType int64
+		p                 = &Profile{}
+	)
+
+	err := decodeMessage(data, func(field int, b *protoBuffer, wire int) error {
+		switch field {
+		case profileSampleType, profilePeriodType:
+			msg, err := b.bytes(wire)
+			if err != nil {
+				return err
+			}
+			vt, err := decodeValueType(msg)
+			if err != nil {
+				return err
+			}
+			if field == profileSampleType {
+				sampleTypes = append(sampleTypes, vt)
+			} else {
+				periodType = vt
+			}
+		case profileSample:
+			msg, err := b.bytes(wire)
+			if err != nil {
+				return err
+			}
+			s, err := decodeSample(msg)
+			if err != nil {
+				return err
+			}
+			samples = append(samples, s)
+		case profileLocation:
+			msg, err := b.bytes(wire)
+			if err != nil {
+				return err
+			}
+			loc, err := decodeLocation(msg)
+			if err != nil {
+				return err
+			}
+			locations = append(locations, loc)
+		case profileFunction:
+			msg, err := b.bytes(wire)
+			if err != nil {
+				return err
+			}
+			fn, err := decodeFunction(msg)
+			if err != nil {
+				return err
+			}
+			functions = append(functions, fn)
+		case profileStringTable:
+			s, err := b.bytes(wire)
+			if err != nil {
+				return err
+			}
+			strs = append(strs, string(s))
+		case profileDurationNanos:
+			v, err := b.uint(wire)
+			p.DurationNanos = int64(v)
+			return err
+		case profilePeriod:
+			v, err := b.uint(wire)
+			p.Period = int64(v)
+			return err
+		case profileDefaultSampleType:
+			v, err := b.uint(wire)
+			defaultSampleType = int64(v)
+			return err
+		default:
+			return b.skip(wire)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(strs)) {
+			return ""
+		}
+		return strs[i]
+	}
+	valueType := func(vt rawValueType) ProfileValueType {
+		return ProfileValueType{Type: str(vt.typ), Unit: str(vt.unit)}
+	}
+
+	for _, st := range sampleTypes {
+		p.SampleTypes = append(p.SampleTypes, valueType(st))
+	}
+	p.PeriodType = valueType(periodType)
+	p.DefaultSampleType = str(defaultSampleType)
+
+	funcs := make(map[uint64]*ProfileFunction, len(functions))
+	for _, fn := range functions {
+		funcs[fn.id] = &ProfileFunction{Name: str(fn.name), File: str(fn.filename), StartLine: fn.startLine}
+	}
+
+	locs := make(map[uint64]*ProfileLocation, len(locations))
+	for _, loc := range locations {
+		l := &ProfileLocation{}
+		for _, line := range loc.lines {
+			fn := funcs[line.functionID]
+			if fn == nil {
+				fn = &ProfileFunction{Name: "?"}
+			}
+			l.Lines = append(l.Lines, ProfileLine{Function: fn, Line: int64(line.line)})
+		}
+		locs[loc.id] = l
+	}
+
+	for _, s := range samples {
+		if len(s.values) != len(p.SampleTypes) {
+			return nil, fmt.Errorf("%w: sample has %d values for %d types", ErrInvalidProfile, len(s.values), len(p.SampleTypes))
+		}
+		sample := ProfileSample{Values: s.values}
+		for _, id := range s.locationIDs {
+			loc, ok := locs[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown location %d", ErrInvalidProfile, id)
+			}
+			sample.Locations = append(sample.Locations, loc)
+		}
+		p.Samples = append(p.Samples, sample)
+	}
+
+	return p, nil
+}
+
+func decodeValueType(data []byte) (rawValueType, error) {
+	var vt rawValueType
+	err := decodeMessage(data, func(field int, b *protoBuffer, wire int) error {
+		v, err := b.uint(wire)
+		switch field {
+		case 1:
+			vt.typ = int64(v)
+		case 2:
+			vt.unit = int64(v)
+		}
+		return err
+	})
+	return vt, err
+}
+
+func decodeSample(data []byte) (rawSample, error) {
+	var s rawSample
+	err := decodeMessage(data, func(field int, b *protoBuffer, wire int) error {
+		switch field {
+		case 1:
+			return b.uints(wire, func(v uint64) { s.locationIDs = append(s.locationIDs, v) })
+		case 2:
+			return b.uints(wire, func(v uint64) { s.values = append(s.values, int64(v)) })
+		default:
+			return b.skip(wire)
+		}
+	})
+	return s, err
+}
+
+func decodeLocation(data []byte) (rawLocation, error) {
+	var loc rawLocation
+	err := decodeMessage(data, func(field int, b *protoBuffer, wire int) error {
+		switch field {
+		case 1:
+			v, err := b.uint(wire)
+			loc.id = v
+			return err
+		case 4:
+			msg, err := b.bytes(wire)
+			if err != nil {
+				return err
+			}
+			var line rawLine
+			err = decodeMessage(msg, func(field int, b *protoBuffer, wire int) error {
+				v, err := b.uint(wire)
+				switch field {
+				case 1:
+					line.functionID = v
+				case 2:
+					line.line = v
+				}
+				return err
+			})
+			loc.lines = append(loc.lines, line)
+			return err
+		default:
+			return b.skip(wire)
+		}
+	})
+	return loc, err
+}
+
+func decodeFunction(data []byte) (rawFunction, error) {
+	var fn rawFunction
+	err := decodeMessage(data, func(field int, b *protoBuffer, wire int) error {
+		v, err := b.uint(wire)
+		switch field {
+		case 1:
+			fn.id = v
+		case 2:
+			fn.name = int64(v)
+		case 4:
+			fn.filename = int64(v)
+		case 5:
+			fn.startLine = int64(v)
+		}
+		return err
+	})
+	return fn, err
+}
+
+// Protobuf wire types
+const (
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+	wire32Bit  = 5
+)
+
+// protoBuffer reads protobuf wire format
+type protoBuffer struct {
+	data []byte
+	pos  int
+}
+
+// decodeMessage calls fn for each field of a message. fn must consume
+// the field's value.
+func decodeMessage(data []byte, fn func(field int, b *protoBuffer, wire int) error) error {
+	b := &protoBuffer{data: data}
+	for b.pos < len(b.data) {
+		key, err := b.varint()
+		if err != nil {
+			return err
+		}
+		if err := fn(int(key>>3), b, int(key&7)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *protoBuffer) varint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if b.pos >= len(b.data) {
+			return 0, fmt.Errorf("%w: truncated varint", ErrInvalidProfile)
+		}
+		c := b.data[b.pos]
+		b.pos++
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: varint overflow", ErrInvalidProfile)
+}
+
+// uint reads a varint field
+func (b *protoBuffer) uint(wire int) (uint64, error) {
+	if wire != wireVarint {
+		return 0, b.skip(wire)
+	}
+	return b.varint()
+}
+
+// uints reads a repeated varint field, packed or not
+func (b *protoBuffer) uints(wire int, fn func(uint64)) error {
+	switch wire {
+	case wireVarint:
+		v, err := b.varint()
+		if err == nil {
+			fn(v)
+		}
+		return err
+	case wireBytes:
+		data, err := b.bytes(wire)
+		if err != nil {
+			return err
+		}
+		packed := &protoBuffer{data: data}
+		for packed.pos < len(packed.data) {
+			v, err := packed.varint()
+			if err != nil {
+				return err
+			}
+			fn(v)
+		}
+		return nil
+	default:
+		return b.skip(wire)
+	}
+}
+
+// bytes reads a length-delimited field
+func (b *protoBuffer) bytes(wire int) ([]byte, error) {
+	if wire != wireBytes {
+		return nil, fmt.Errorf("%w: wire type %d for a message", ErrInvalidProfile, wire)
+	}
+	n, err := b.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(b.data)-b.pos) {
+		return nil, fmt.Errorf("%w: truncated field", ErrInvalidProfile)
+	}
+	data := b.data[b.pos : b.pos+int(n)]
+	b.pos += int(n)
+	return data, nil
+}
+
+// skip skips a field's value
+func (b *protoBuffer) skip(wire int) error {
+	var n int
+	switch wire {
+	case wireVarint:
+		_, err := b.varint()
+		return err
+	case wireBytes:
+		_, err := b.bytes(wire)
+		return err
+	case wire64Bit:
+		n = 8
+	case wire32Bit:
+		n = 4
+	default:
+		return fmt.Errorf("%w: unsupported wire type %d", ErrInvalidProfile, wire)
+	}
+	if b.pos+n > len(b.data) {
+		return fmt.Errorf("%w: truncated field", ErrInvalidProfile)
+	}
+	b.pos += n
+	return nil
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+var profileSink [][]byte
+
+// allocateForProfile allocates enough to stand out in a heap profile
+//
+//go:noinline
+func allocateForProfile() {
+	for range 64 {
+		profileSink = append(profileSink, make([]byte, 64<<10))
+	}
+}
+
+// heapProfile returns a gzip-compressed heap profile recording the
+// allocations of allocateForProfile
+func heapProfile(t *testing.T) []byte {
+	t.Helper()
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+
+	allocateForProfile()
+	profileSink = nil
+	// The heap profile reports allocations as of the last collection
+	runtime.GC()
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseProfile(t *testing.T) {
+	compressed := heapProfile(t)
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"gzip": compressed, "uncompressed": raw} {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseProfile(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ParseProfile: %v", err)
+			}
+
+			var types []string
+			for _, st := range p.SampleTypes {
+				types = append(types, st.Type+"/"+st.Unit)
+			}
+			want := []string{"alloc_objects/count", "alloc_space/bytes", "inuse_objects/count", "inuse_space/bytes"}
+			if !reflect.DeepEqual(types, want) {
+				t.Fatalf("sample types = %q, want %q", types, want)
+			}
+			if p.PeriodType != (ProfileValueType{Type: "space", Unit: "bytes"}) {
+				t.Errorf("period type = %+v, want space/bytes", p.PeriodType)
+			}
+
+			index, err := p.SampleIndex(ProfileMem.DefaultSampleType())
+			if err != nil {
+				t.Fatalf("SampleIndex: %v", err)
+			}
+			report := p.Summarize(index)
+
+			var found *ProfileEntry
+			for i, e := range report.Entries {
+				if strings.HasSuffix(e.Function, ".allocateForProfile") {
+					found = &report.Entries[i]
+				}
+			}
+			if found == nil {
+				t.Fatalf("allocateForProfile not among %d entries", len(report.Entries))
+			}
+			if found.Flat < 64*64<<10 || found.Cum < found.Flat {
+				t.Errorf("allocateForProfile flat = %d, cum = %d; want at least %d allocated", found.Flat, found.Cum, 64*64<<10)
+			}
+			if !strings.HasSuffix(found.File, "pprof_test.go") || found.Line == 0 {
+				t.Errorf("allocateForProfile at %s:%d, want a line of pprof_test.go", found.File, found.Line)
+			}
+			if lines := report.FileLines(found.File); lines[int(found.Line)] < found.Flat {
+				t.Errorf("FileLines()[%d] = %d, want at least %d", found.Line, lines[int(found.Line)], found.Flat)
+			}
+		})
+	}
+}
+
+func TestParseProfileInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"text", []byte("not a profile")},
+		{"truncated varint", []byte{0x80}},
+		{"truncated field", []byte{0x0a, 0x05, 0x08}},
+		{"message with a varint wire type", []byte{0x10, 0x01}},
+		{
+			// sample_type {type: 0, unit: 0}, sample {location_id: 7, value: 1}
+			"unknown location",
+			[]byte{0x0a, 0x04, 0x08, 0x00, 0x10, 0x00, 0x12, 0x04, 0x08, 0x07, 0x10, 0x01},
+		},
+		{
+			// sample_type {type: 0, unit: 0}, sample {value: 1, value: 1}
+			"more values than sample types",
+			[]byte{0x0a, 0x04, 0x08, 0x00, 0x10, 0x00, 0x12, 0x04, 0x10, 0x01, 0x10, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseProfile(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalidProfile) {
+				t.Errorf("ParseProfile error = %v, want %v", err, ErrInvalidProfile)
+			}
+		})
+	}
+
+	// A profile without samples parses but has nothing to select
+	p, err := ParseProfile(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("ParseProfile of an empty profile: %v", err)
+	}
+	if _, err := p.SampleIndex(""); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("SampleIndex of an empty profile error = %v, want %v", err, ErrInvalidProfile)
+	}
+}
+
+func TestSummarizeRecursion(t *testing.T) {
+	var (
+		main = &ProfileFunction{Name: "main.main", File: "main.go", StartLine: 3}
+		fib  = &ProfileFunction{Name: "main.fib", File: "fib.go", StartLine: 10}
+		leaf = &ProfileFunction{Name: "main.leaf", File: "fib.go", StartLine: 20}
+	)
+	loc := func(fn *ProfileFunction, line int64) *ProfileLocation {
+		return &ProfileLocation{Lines: []ProfileLine{{Function: fn, Line: line}}}
+	}
+	// leaf inlined into fib at line 13: Lines[0] is the innermost
+	inlined := &ProfileLocation{Lines: []ProfileLine{{Function: leaf, Line: 21}, {Function: fib, Line: 13}}}
+
+	p := &Profile{
+		SampleTypes: []ProfileValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		Samples: []ProfileSample{
+			// fib -> fib -> leaf, recursing through the same line twice
+			{Locations: []*ProfileLocation{loc(leaf, 21), loc(fib, 12), loc(fib, 14), loc(fib, 14), loc(main, 5)}, Values: []int64{1, 10}},
+			// fib -> fib, busy in fib itself
+			{Locations: []*ProfileLocation{loc(fib, 11), loc(fib, 14), loc(main, 5)}, Values: []int64{1, 5}},
+			// fib with leaf inlined
+			{Locations: []*ProfileLocation{inlined, loc(fib, 14), loc(main, 5)}, Values: []int64{1, 3}},
+			// No cost of this sample type
+			{Locations: []*ProfileLocation{loc(main, 6)}, Values: []int64{1, 0}},
+		},
+	}
+
+	report := p.Summarize(1)
+	if report.Total != 18 {
+		t.Errorf("Total = %d, want 18", report.Total)
+	}
+	want := []ProfileEntry{
+		{Function: "main.leaf", File: "fib.go", Line: 21, Flat: 13, Cum: 13},
+		{Function: "main.fib", File: "fib.go", Line: 14, Flat: 5, Cum: 18},
+		{Function: "main.main", File: "main.go", Line: 5, Flat: 0, Cum: 18},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("Entries = %+v, want %+v", report.Entries, want)
+	}
+	if got, want := report.FileLines("fib.go"), map[int]int64{11: 5, 12: 10, 13: 3, 14: 18, 21: 13}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FileLines(fib.go) = %v, want %v", got, want)
+	}
+	if got := report.Top(1, true); len(got) != 1 || got[0].Function != "main.fib" {
+		t.Errorf("Top(1, cum) = %+v, want main.fib first", got)
+	}
+
+	if report := p.Summarize(2); report.Total != 0 || len(report.Entries) != 0 {
+		t.Errorf("Summarize of an unknown sample index = %+v, want an empty report", report)
+	}
+}
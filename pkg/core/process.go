@@ -0,0 +1,340 @@
+// Package core provides management of programs running in the background.
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ErrProcessNotFound is returned for an unknown process ID
+var ErrProcessNotFound = errors.New("process not found")
+
+const (
+	// ProcessLogLines is the number of output lines kept per process
+	ProcessLogLines = 1000
+
+	// ProcessStopTimeout is how long Stop waits after interrupting a
+	// process before killing it
+	ProcessStopTimeout = 5 * time.Second
+)
+
+// ProcessState is the lifecycle state of a managed process
+type ProcessState string
+
+const (
+	ProcessRunning ProcessState = "running"
+	ProcessExited  ProcessState = "exited"  // Exited on its own
+	ProcessStopped ProcessState = "stopped" // Stopped through the manager
+)
+
+// Process is a program started by a ProcessManager
+type Process struct {
+	ID      int
+	Config  RunConfig
+	PID     int
+	Started time.Time
+
+	project Project
+	logs    *RingSink
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu       sync.Mutex
+	state    ProcessState
+	stopping bool
+	exitCode int
+	err      error
+	ended    time.Time
+}
+
+// State returns the current state of the process
+func (p *Process) State() ProcessState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// ExitCode returns the exit status, or -1 while running or if the
+// process was terminated by a signal
+func (p *Process) ExitCode() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.exitCode
+}
+
+// Err returns the error the process exited with, if it exited on its own
+func (p *Process) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Ended returns when the process exited, or the zero time while running
+func (p *Process) Ended() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ended
+}
+
+// Uptime returns how long the process has been (or was) running
+func (p *Process) Uptime() time.Duration {
+	if ended := p.Ended(); !ended.IsZero() {
+		return ended.Sub(p.Started)
+	}
+	return time.Since(p.Started)
+}
+
+// Logs returns the most recent output of the process, including the build
+func (p *Process) Logs() []OutputLine {
+	return p.logs.Lines()
+}
+
+// Done returns a channel that is closed when the process exits
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// stop interrupts the process and waits for it to exit
+func (p *Process) stop() {
+	p.mu.Lock()
+	if p.state == ProcessRunning {
+		p.stopping = true
+	}
+	p.mu.Unlock()
+
+	p.cancel()
+	<-p.done
+}
+
+// ProcessManager runs programs in the background, tracks them by ID and
+// keeps their recent output
+type ProcessManager struct {
+	builder Builder
+	logger  Logger
+
+	mu        sync.Mutex
+	nextID    int
+	processes []*Process
+	onExit    func(*Process)
+}
+
+// NewProcessManager creates a process manager using builder to build programs
+func NewProcessManager(builder Builder, logger Logger) *ProcessManager {
+	return &ProcessManager{
+		builder: builder,
+		logger:  logger,
+	}
+}
+
+// SetOnExit sets a callback invoked from a background goroutine whenever
+// a process exits or is stopped
+func (m *ProcessManager) SetOnExit(callback func(*Process)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExit = callback
+}
+
+// Start builds the program of a run configuration and starts it in the
+// background. It returns once the program is running; build and program
+// output is streamed to out and kept in the process logs.
+// ctx only bounds the build, the program runs until it exits or is stopped.
+func (m *ProcessManager) Start(ctx context.Context, project Project, config RunConfig, out OutputSink) (*Process, error) {
+	return m.start(ctx, 0, project, config, out)
+}
+
+// start starts a process, reusing id if it is non-zero
+func (m *ProcessManager) start(ctx context.Context, id int, project Project, config RunConfig, out OutputSink) (*Process, error) {
+	if !project.IsGoProject() {
+		return nil, ErrNotGoProject
+	}
+
+	logs := NewRingSink(ProcessLogLines)
+	sink := MultiSink(logs, out)
+
+	// The program outlives ctx; cancelling procCtx is how it gets stopped
+	procCtx, cancel := context.WithCancel(context.Background())
+	stopBuild := context.AfterFunc(ctx, cancel)
+	cmd, cleanup, err := m.builder.PrepareRun(procCtx, project, config, sink)
+	stopBuild()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Ask the program to shut down first; exec kills it after WaitDelay
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = ProcessStopTimeout
+
+	wait, err := startCommand(cmd, sink)
+	if err != nil {
+		cancel()
+		cleanup()
+		return nil, err
+	}
+
+	p := &Process{
+		ID:       id,
+		Config:   config,
+		PID:      cmd.Process.Pid,
+		Started:  time.Now(),
+		project:  project,
+		logs:     logs,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		state:    ProcessRunning,
+		exitCode: -1,
+	}
+
+	m.mu.Lock()
+	if p.ID == 0 {
+		m.nextID++
+		p.ID = m.nextID
+		m.processes = append(m.processes, p)
+	} else {
+		m.replace(p)
+	}
+	m.mu.Unlock()
+
+	if m.logger != nil {
+		m.logger.Info("Process started",
+			Field{Key: "id", Value: p.ID},
+			Field{Key: "config", Value: config.Name},
+			Field{Key: "pid", Value: p.PID})
+	}
+
+	go m.wait(p, wait, cleanup)
+
+	return p, nil
+}
+
+// replace swaps the process with the same ID for p; m.mu must be held
+func (m *ProcessManager) replace(p *Process) {
+	for i, existing := range m.processes {
+		if existing.ID == p.ID {
+			m.processes[i] = p
+			return
+		}
+	}
+	m.processes = append(m.processes, p)
+}
+
+// wait records the exit of a process and notifies the exit callback
+func (m *ProcessManager) wait(p *Process, wait func() error, cleanup func()) {
+	err := wait()
+	cleanup()
+	p.cancel()
+
+	p.mu.Lock()
+	p.ended = time.Now()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		p.exitCode = 0
+	case errors.As(err, &exitErr):
+		p.exitCode = exitErr.ExitCode()
+	}
+	if p.stopping {
+		p.state = ProcessStopped
+	} else {
+		p.state = ProcessExited
+		p.err = err
+	}
+	p.mu.Unlock()
+	close(p.done)
+
+	if m.logger != nil {
+		m.logger.Info("Process exited",
+			Field{Key: "id", Value: p.ID},
+			Field{Key: "state", Value: string(p.State())},
+			Field{Key: "code", Value: p.ExitCode()})
+	}
+
+	m.mu.Lock()
+	onExit := m.onExit
+	m.mu.Unlock()
+	if onExit != nil {
+		onExit(p)
+	}
+}
+
+// Get returns the process with the given ID
+func (m *ProcessManager) Get(id int) (*Process, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.processes {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// List returns all known processes, running or not, ordered by ID
+func (m *ProcessManager) List() []*Process {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*Process(nil), m.processes...)
+}
+
+// Running returns the processes that are still running
+func (m *ProcessManager) Running() []*Process {
+	var running []*Process
+	for _, p := range m.List() {
+		if p.State() == ProcessRunning {
+			running = append(running, p)
+		}
+	}
+	return running
+}
+
+// Stop interrupts a running process, kills it if it has not exited after
+// ProcessStopTimeout, and waits for it to exit
+func (m *ProcessManager) Stop(id int) error {
+	p, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrProcessNotFound, id)
+	}
+	if p.State() != ProcessRunning {
+		return fmt.Errorf("process %d is not running", id)
+	}
+
+	p.stop()
+	return nil
+}
+
+// Restart stops a process if it is running and starts its run
+// configuration again under the same ID
+func (m *ProcessManager) Restart(ctx context.Context, id int, out OutputSink) (*Process, error) {
+	p, ok := m.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrProcessNotFound, id)
+	}
+
+	p.stop()
+	return m.start(ctx, id, p.project, p.Config, out)
+}
+
+// StopAll stops every running process, e.g. when the IDE exits
+func (m *ProcessManager) StopAll() {
+	var wg sync.WaitGroup
+	for _, p := range m.Running() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.stop()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,303 @@
+// Package core provides CPU and memory profiling of tests and programs.
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProfileKind selects what is profiled
+type ProfileKind string
+
+const (
+	ProfileCPU ProfileKind = "cpu"
+	ProfileMem ProfileKind = "mem"
+)
+
+// ParseProfileKind parses "cpu" or "mem"
+func ParseProfileKind(s string) (ProfileKind, error) {
+	switch kind := ProfileKind(s); kind {
+	case ProfileCPU, ProfileMem:
+		return kind, nil
+	}
+	return "", fmt.Errorf("unknown profile kind %q (want cpu or mem)", s)
+}
+
+// flag returns the go test flag writing the profile
+func (k ProfileKind) flag() string {
+	if k == ProfileMem {
+		return "-memprofile"
+	}
+	return "-cpuprofile"
+}
+
+// DefaultSampleType returns the sample type shown for the kind of
+// profile: CPU time, or all allocated bytes since in-use memory at the end
+// of a test run says little
+func (k ProfileKind) DefaultSampleType() string {
+	if k == ProfileMem {
+		return "alloc_space"
+	}
+	return ""
+}
+
+// ProfileOptions selects what to profile: the tests or benchmarks of one
+// package, or a run configuration's program
+type ProfileOptions struct {
+	Kind  ProfileKind
+	Test  TestOptions // Package defaults to "."
+	Bench string      // -bench regular expression; only benchmarks run
+	Run   *RunConfig  // Profile this program instead of tests
+}
+
+// ProfileEntry is the cost of one function
+type ProfileEntry struct {
+	Function string
+	File     string
+	Line     int64 // Costliest line of the function
+	Flat     int64 // Cost in the function itself
+	Cum      int64 // Cost in the function and its callees
+}
+
+// ProfileReport summarizes one sample type of a profile by function and
+// source line
+type ProfileReport struct {
+	SampleType ProfileValueType
+	Total      int64
+	Entries    []ProfileEntry // Sorted by flat cost
+
+	lines map[string]map[int]int64 // Cumulative cost by file and line
+}
+
+// Summarize aggregates the values at sampleIndex by function and line.
+// Recursive frames are counted once per sample for cumulative costs.
+func (p *Profile) Summarize(sampleIndex int) *ProfileReport {
+	report := &ProfileReport{
+		lines: make(map[string]map[int]int64),
+	}
+	if sampleIndex < 0 || sampleIndex >= len(p.SampleTypes) {
+		return report
+	}
+	report.SampleType = p.SampleTypes[sampleIndex]
+
+	type lineKey struct {
+		fn   *ProfileFunction
+		line int64
+	}
+	var (
+		entries   = make(map[*ProfileFunction]*ProfileEntry)
+		lineCosts = make(map[lineKey]int64)
+	)
+	entry := func(fn *ProfileFunction) *ProfileEntry {
+		e, ok := entries[fn]
+		if !ok {
+			e = &ProfileEntry{Function: fn.Name, File: fn.File, Line: fn.StartLine}
+			entries[fn] = e
+		}
+		return e
+	}
+
+	for _, sample := range p.Samples {
+		value := sample.Values[sampleIndex]
+		if value == 0 {
+			continue
+		}
+		report.Total += value
+
+		seenFuncs := make(map[*ProfileFunction]bool)
+		seenLines := make(map[lineKey]bool)
+		for i, loc := range sample.Locations {
+			for j, line := range loc.Lines {
+				if i == 0 && j == 0 {
+					entry(line.Function).Flat += value
+				}
+				if !seenFuncs[line.Function] {
+					seenFuncs[line.Function] = true
+					entry(line.Function).Cum += value
+				}
+
+				key := lineKey{line.Function, line.Line}
+				if seenLines[key] {
+					continue
+				}
+				seenLines[key] = true
+				lineCosts[key] += value
+				if file := line.Function.File; file != "" {
+					if report.lines[file] == nil {
+						report.lines[file] = make(map[int]int64)
+					}
+					report.lines[file][int(line.Line)] += value
+				}
+			}
+		}
+	}
+
+	// Link each function to its costliest line
+	best := make(map[*ProfileFunction]int64)
+	for key, cost := range lineCosts {
+		if cost > best[key.fn] {
+			best[key.fn] = cost
+			entry(key.fn).Line = key.line
+		}
+	}
+
+	for _, e := range entries {
+		report.Entries = append(report.Entries, *e)
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.Flat != b.Flat {
+			return a.Flat > b.Flat
+		}
+		if a.Cum != b.Cum {
+			return a.Cum > b.Cum
+		}
+		return a.Function < b.Function
+	})
+
+	return report
+}
+
+// Top returns the n costliest functions by flat or cumulative cost.
+// n <= 0 returns all of them.
+func (r *ProfileReport) Top(n int, byCum bool) []ProfileEntry {
+	entries := append([]ProfileEntry(nil), r.Entries...)
+	if byCum {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Cum > entries[j].Cum
+		})
+	}
+	if n > 0 && n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// FileLines returns the cumulative cost of each line of a file, or nil
+func (r *ProfileReport) FileLines(file string) map[int]int64 {
+	if r == nil {
+		return nil
+	}
+	return r.lines[file]
+}
+
+// Format formats a value of the report's sample type
+func (r *ProfileReport) Format(value int64) string {
+	return FormatProfileValue(value, r.SampleType.Unit)
+}
+
+// FormatProfileValue formats a sample value with its unit, e.g. "1.25s"
+// for nanoseconds or "3.50MB" for bytes
+func FormatProfileValue(value int64, unit string) string {
+	v := float64(value)
+	switch unit {
+	case "nanoseconds":
+		switch d := time.Duration(value); {
+		case d >= time.Second:
+			return fmt.Sprintf("%.2fs", v/float64(time.Second))
+		case d >= time.Millisecond:
+			return fmt.Sprintf("%.2fms", v/float64(time.Millisecond))
+		case d >= time.Microsecond:
+			return fmt.Sprintf("%.2fµs", v/float64(time.Microsecond))
+		}
+		return fmt.Sprintf("%dns", value)
+	case "bytes":
+		const unit = 1024
+		if value < unit {
+			return fmt.Sprintf("%dB", value)
+		}
+		div, exp := float64(unit), 0
+		for n := v / unit; n >= unit && exp < 4; n /= unit {
+			div *= unit
+			exp++
+		}
+		return fmt.Sprintf("%.2f%cB", v/div, "KMGTP"[exp])
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+// Profile runs the selected tests, benchmarks or program with CPU or
+// memory profiling, streaming output to out, and returns the profile.
+// A program must accept -cpuprofile or -memprofile flags itself.
+func (b *GoBuilder) Profile(ctx context.Context, project Project, opts ProfileOptions, out OutputSink) (*Profile, error) {
+	if !project.IsGoProject() {
+		return nil, ErrNotGoProject
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gox-profile-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	profilePath := filepath.Join(tmpDir, string(opts.Kind)+".pprof")
+
+	var cmd *exec.Cmd
+	if opts.Run != nil {
+		var cleanup func()
+		cmd, cleanup, err = b.PrepareRun(ctx, project, *opts.Run, out)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		// Flags must precede the program's positional arguments
+		cmd.Args = append([]string{cmd.Args[0], opts.Kind.flag() + "=" + profilePath}, cmd.Args[1:]...)
+	} else {
+		test := opts.Test
+		if test.Package == "" {
+			test.Package = "."
+		}
+		if len(test.Packages) > 0 || strings.Contains(test.Package, "...") {
+			return nil, fmt.Errorf("profiling needs a single package, not %q", test.PackagePattern())
+		}
+
+		// -o keeps the test binary, which go test leaves behind when
+		// profiling, out of the project
+		args := []string{"test", "-o", filepath.Join(tmpDir, "profile.test"), opts.Kind.flag(), profilePath}
+		if opts.Bench != "" {
+			if test.Run == "" {
+				test.Run = "^$"
+			}
+			args = append(args, "-bench", opts.Bench)
+		}
+		args = append(args, test.Args()...)
+		args = append(args, test.Package)
+
+		cmd = exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = project.Path()
+	}
+
+	if b.logger != nil {
+		b.logger.Info("Profiling project",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "kind", Value: string(opts.Kind)})
+	}
+
+	// A failing run may still have written the profile
+	runErr := runCommand(cmd, out)
+
+	f, err := os.Open(profilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("no profile was written (does the program accept %s?)", opts.Kind.flag())
+	}
+	if err != nil {
+		return nil, errors.Join(runErr, err)
+	}
+	defer f.Close()
+
+	profile, err := ParseProfile(f)
+	if err != nil {
+		return nil, errors.Join(runErr, err)
+	}
+	return profile, runErr
+}
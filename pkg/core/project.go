@@ -2,8 +2,12 @@
 package core
 
 import (
+	"context"
+	"fmt"
+	"hash/fnv"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // GoProject implements the Project interface for Go projects
@@ -11,14 +15,29 @@ type GoProject struct {
 	path string
 	name string
 	fs   FileSystem
+
+	// ignoreRoot is the directory whose ignore files apply: the project
+	// itself, or the workspace root of a module
+	ignoreRoot string
+
+	// Cached workspace or the error loading it, reloaded when the go.work
+	// file or a module's go.mod changes, and the module projects returned
+	// by ForModule
+	wsMu     sync.Mutex
+	ws       *Workspace
+	wsErr    error
+	wsLoaded bool
+	wsStamp  uint64
+	modules  map[string]*GoProject // By module directory
 }
 
 // NewGoProject creates a new Go project instance
 func NewGoProject(path string, fs FileSystem) *GoProject {
 	return &GoProject{
-		path: path,
-		name: filepath.Base(path),
-		fs:   fs,
+		path:       path,
+		name:       filepath.Base(path),
+		fs:         fs,
+		ignoreRoot: path,
 	}
 }
 
@@ -32,35 +51,204 @@ func (p *GoProject) Name() string {
 	return p.name
 }
 
-// IsGoProject returns true if this is a valid Go project
+// IsGoProject returns true if this is a valid Go project: a module, a
+// go.work workspace, or a tree containing modules. The tree is never
+// walked here; its modules count once the workspace has been loaded.
 func (p *GoProject) IsGoProject() bool {
-	return p.fs.Exists(filepath.Join(p.path, "go.mod"))
+	if p.fs.Exists(filepath.Join(p.path, "go.mod")) || p.fs.Exists(filepath.Join(p.path, "go.work")) {
+		return true
+	}
+
+	ws := p.loadedWorkspace()
+	return ws != nil && len(ws.Modules) > 0
 }
 
-// Files returns all files in the project
-func (p *GoProject) Files() ([]FileInfo, error) {
-	// Pre-allocate with estimated capacity for better performance
-	files := make([]FileInfo, 0, 64)
+// Workspace returns the modules used by the project's go.work file, or
+// else those found in the project tree. The workspace, or the error
+// loading it, is cached and shared, so it must not be modified; it is
+// reloaded after the go.work file or a module's go.mod changes, or
+// FilesChanged reports a new one. Cancelling ctx stops loading it, and a
+// cancelled load is tried again on next use.
+func (p *GoProject) Workspace(ctx context.Context) (*Workspace, error) {
+	p.wsMu.Lock()
+	defer p.wsMu.Unlock()
+
+	if p.wsLoaded && p.workspaceStamp(p.ws) == p.wsStamp {
+		return p.ws, p.wsErr
+	}
+
+	ws, err := LoadWorkspace(ctx, p.fs, p.path)
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	p.ws, p.wsErr, p.wsLoaded = ws, err, true
+	p.wsStamp = p.workspaceStamp(ws)
+	p.modules = nil
+	return ws, err
+}
+
+// loadedWorkspace returns the workspace if it is cached or read from a
+// go.work file, neither of which walks the tree, or else nil
+func (p *GoProject) loadedWorkspace() *Workspace {
+	if p.fs.Exists(filepath.Join(p.path, "go.work")) {
+		ws, _ := p.Workspace(context.Background())
+		return ws
+	}
+
+	p.wsMu.Lock()
+	defer p.wsMu.Unlock()
+	if p.wsLoaded && p.workspaceStamp(p.ws) == p.wsStamp {
+		return p.ws
+	}
+	return nil
+}
+
+// workspaceStamp hashes the files that decide a workspace's modules: the
+// go.work file and the go.mod files of the project and its modules
+func (p *GoProject) workspaceStamp(ws *Workspace) uint64 {
+	files := []string{filepath.Join(p.path, "go.work"), filepath.Join(p.path, "go.mod")}
+	if ws != nil {
+		for _, mod := range ws.Modules {
+			files = append(files, filepath.Join(mod.Dir, "go.mod"))
+		}
+	}
 
-	err := p.fs.WalkDir(p.path, func(info FileInfo) error {
-		// Skip hidden files and directories
-		if strings.HasPrefix(info.Name, ".") && info.Name != "." {
-			return nil
+	h := fnv.New64a()
+	for _, file := range files {
+		data, err := p.fs.ReadFile(file)
+		fmt.Fprintf(h, "%s %t %d\n", file, err == nil, len(data))
+		h.Write(data)
+	}
+	return h.Sum64()
+}
+
+// FilesChanged tells the project that files were added, modified or
+// removed. A go.work or go.mod file among them reloads the workspace,
+// which is how modules added inside the tree are found.
+func (p *GoProject) FilesChanged(paths []string) {
+	for _, path := range paths {
+		if name := filepath.Base(path); name != "go.mod" && name != "go.work" {
+			continue
 		}
 
-		// Skip vendor and node_modules
-		if info.IsDir && (info.Name == "vendor" || info.Name == "node_modules") {
-			return nil
+		p.wsMu.Lock()
+		modules := p.modules
+		p.ws, p.wsErr, p.wsLoaded = nil, nil, false
+		p.modules = nil
+		p.wsMu.Unlock()
+
+		for _, module := range modules {
+			module.FilesChanged(paths)
 		}
+		return
+	}
+}
 
-		if info.RelPath != "." && !info.IsDir {
-			files = append(files, info)
+// ForModule returns the project of the innermost module containing file,
+// found from the go.work file or else the nearest go.mod above file, so
+// no tree is walked. Files outside every module belong to the project
+// itself if it is a module, or else to its first module once the
+// workspace is loaded.
+func (p *GoProject) ForModule(ctx context.Context, file string) Project {
+	dir, ok := p.moduleDir(ctx, file)
+	if !ok {
+		ws := p.loadedWorkspace()
+		if p.fs.Exists(filepath.Join(p.path, "go.mod")) || ws == nil || len(ws.Modules) == 0 {
+			return p
 		}
+		dir = ws.Modules[0].Dir
+	}
+	if dir == p.path {
+		return p
+	}
 
-		return nil
-	})
+	// Module projects are kept with the workspace, so that their own
+	// caches are too
+	p.wsMu.Lock()
+	defer p.wsMu.Unlock()
+	if module, ok := p.modules[dir]; ok {
+		return module
+	}
+
+	module := NewGoProject(dir, p.fs)
+	if strings.HasPrefix(dir, p.ignoreRoot+string(filepath.Separator)) {
+		// The workspace's ignore files apply to its modules too
+		module.ignoreRoot = p.ignoreRoot
+	}
+	if p.modules == nil {
+		p.modules = make(map[string]*GoProject)
+	}
+	p.modules[dir] = module
+	return module
+}
+
+// moduleDir returns the directory of the innermost module containing
+// file: a module of the go.work file if there is one, or else the nearest
+// directory with a go.mod from file up to the project root
+func (p *GoProject) moduleDir(ctx context.Context, file string) (string, bool) {
+	if p.fs.Exists(filepath.Join(p.path, "go.work")) {
+		ws, err := p.Workspace(ctx)
+		if err != nil {
+			return "", false
+		}
+		mod, ok := ws.ModuleForFile(file)
+		return mod.Dir, ok
+	}
 
-	return files, err
+	for dir := file; dir == p.path || strings.HasPrefix(dir, p.path+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if p.fs.Exists(filepath.Join(dir, "go.mod")) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// ignoreMatcher returns a matcher reading the current ignore files
+func (p *GoProject) ignoreMatcher() *IgnoreMatcher {
+	return NewIgnoreMatcher(p.ignoreRoot, p.fs.ReadFile)
+}
+
+// Ignored reports whether listings of the project skip a file, by itself
+// or through one of its directories, reading the current ignore files
+func (p *GoProject) Ignored(path string) bool {
+	rel, err := filepath.Rel(p.path, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	ignore := p.ignoreMatcher()
+	dir := p.path
+	for _, name := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if name == "." {
+			break
+		}
+		dir = filepath.Join(dir, name)
+		if ignore.Ignored(dir, true) {
+			return true
+		}
+	}
+	return ignore.Ignored(path, false)
+}
+
+// Files returns all files in the project, failing past
+// DefaultWalkMaxFiles
+func (p *GoProject) Files() ([]FileInfo, error) {
+	return p.WalkFiles(context.Background(), DefaultWalkOptions())
+}
+
+// WalkFiles lists the files in the project concurrently, skipping ignored
+// files and directories
+func (p *GoProject) WalkFiles(ctx context.Context, opts WalkOptions) ([]FileInfo, error) {
+	ignore := p.ignoreMatcher()
+	return Walk(ctx, p.fs, p.path, opts, func(info *FileInfo) bool {
+		if ignore.Ignored(info.Path, info.IsDir) {
+			return false
+		}
+		if opts.MarkGenerated {
+			markGenerated(info)
+		}
+		return true
+	})
 }
 
 // FileTree returns the project structure as a tree
@@ -75,21 +263,28 @@ func (p *GoProject) FileTree() (TreeNode, error) {
 		Level: 0,
 	}
 
-	err := p.buildTree(&root, p.path, 0)
+	// Nested workspace modules are trees of their own
+	nested := make(map[string]bool)
+	if ws, err := p.Workspace(context.Background()); err == nil {
+		for _, mod := range ws.Modules {
+			nested[mod.Dir] = mod.Dir != p.path
+		}
+	}
+
+	err := p.buildTree(&root, p.path, 0, nested, p.ignoreMatcher())
 	return root, err
 }
 
-func (p *GoProject) buildTree(node *TreeNode, path string, level int) error {
-	entries, err := p.fs.ListFiles(path)
+func (p *GoProject) buildTree(node *TreeNode, path string, level int, nested map[string]bool, ignore *IgnoreMatcher) error {
+	entries, err := p.listDir(path, ignore)
 	if err != nil {
 		return err
 	}
 
-	// Filter visible entries with pre-allocation for performance
+	// Filter nested modules with pre-allocation for performance
 	visibleEntries := make([]FileInfo, 0, len(entries)) // Pre-allocate capacity
 	for _, entry := range entries {
-		if !strings.HasPrefix(entry.Name, ".") &&
-			entry.Name != "vendor" && entry.Name != "node_modules" {
+		if !nested[entry.Path] {
 			visibleEntries = append(visibleEntries, entry)
 		}
 	}
@@ -102,7 +297,7 @@ func (p *GoProject) buildTree(node *TreeNode, path string, level int) error {
 		}
 
 		if entry.IsDir {
-			err := p.buildTree(&child, entry.Path, level+1)
+			err := p.buildTree(&child, entry.Path, level+1, nested, ignore)
 			if err != nil {
 				return err
 			}
@@ -114,6 +309,41 @@ func (p *GoProject) buildTree(node *TreeNode, path string, level int) error {
 	return nil
 }
 
+// ListDir returns the entries of one directory of the project that are
+// not ignored, with paths relative to the project. Unlike FileTree it
+// keeps nested modules.
+func (p *GoProject) ListDir(dir string) ([]FileInfo, error) {
+	return p.listDir(dir, p.ignoreMatcher())
+}
+
+// listDir lists a directory with an ignore matcher shared across a walk
+func (p *GoProject) listDir(dir string, ignore *IgnoreMatcher) ([]FileInfo, error) {
+	entries, err := p.fs.ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if ignore.Ignored(entry.Path, entry.IsDir) {
+			continue
+		}
+		if rel, err := filepath.Rel(p.path, entry.Path); err == nil {
+			entry.RelPath = rel
+		}
+		markGenerated(&entry)
+		visible = append(visible, entry)
+	}
+	return visible, nil
+}
+
+// markGenerated flags Go files carrying the generated code marker
+func markGenerated(info *FileInfo) {
+	if !info.IsDir && info.Language == "go" {
+		info.Generated = IsGeneratedFile(info.Path)
+	}
+}
+
 // GetLanguageForFile returns the programming language for a file
 func GetLanguageForFile(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -0,0 +1,486 @@
+// Package core provides a project-wide index of Go symbols.
+package core
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SymbolKind is the kind of a declared Go symbol
+type SymbolKind string
+
+const (
+	SymbolPackage SymbolKind = "package"
+	SymbolType    SymbolKind = "type"
+	SymbolFunc    SymbolKind = "func"
+	SymbolMethod  SymbolKind = "method"
+	SymbolConst   SymbolKind = "const"
+	SymbolVar     SymbolKind = "var"
+	SymbolField   SymbolKind = "field"
+)
+
+// Symbol is a package-level declaration, a method, or a struct field
+type Symbol struct {
+	Name    string
+	Kind    SymbolKind
+	Package string // Name of the declaring package
+	Parent  string // Receiver type of a method, or type of a field or interface method
+	File    string // Absolute path
+	RelPath string // Path relative to the project root
+	Line    int
+	Column  int
+	Doc     string // Doc comment text, without comment markers
+}
+
+// QualifiedName returns the name with its package and parent type, e.g.
+// "core.GoBuilder.Build"
+func (s Symbol) QualifiedName() string {
+	switch {
+	case s.Kind == SymbolPackage:
+		return s.Name
+	case s.Parent != "":
+		return s.Package + "." + s.Parent + "." + s.Name
+	}
+	return s.Package + "." + s.Name
+}
+
+// Summary returns the first line of the doc comment
+func (s Symbol) Summary() string {
+	line, _, _ := strings.Cut(s.Doc, "\n")
+	return line
+}
+
+// SymbolMatch is a search result
+type SymbolMatch struct {
+	Symbol
+	Score int
+}
+
+// indexedFile holds the symbols of one file and the version they were
+// parsed from
+type indexedFile struct {
+	modTime int64
+	size    int64
+	pkg     Symbol // The package clause, with the package doc if any
+	symbols []Symbol
+}
+
+// SymbolIndex indexes the Go symbols of every .go file in a project. It
+// is updated incrementally and safe for concurrent use.
+type SymbolIndex struct {
+	project Project
+
+	mu    sync.RWMutex
+	files map[string]*indexedFile
+}
+
+// NewSymbolIndex creates an empty index of the project; Update fills it
+func NewSymbolIndex(project Project) *SymbolIndex {
+	return &SymbolIndex{
+		project: project,
+		files:   make(map[string]*indexedFile),
+	}
+}
+
+// Update walks the project, parsing new and modified .go files and
+// dropping removed ones. Like the go command it skips testdata and "_"
+// directories. Files are parsed concurrently.
+func (x *SymbolIndex) Update(ctx context.Context, opts WalkOptions) error {
+	files, err := x.project.WalkFiles(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	var (
+		changed []FileInfo
+		seen    = make(map[string]bool)
+	)
+	x.mu.RLock()
+	for _, file := range files {
+		if file.Language != "go" || isIgnoredPackageDir(filepath.Dir(file.RelPath)) {
+			continue
+		}
+		seen[file.Path] = true
+		if cur, ok := x.files[file.Path]; !ok || cur.modTime != file.ModTime || cur.size != file.Size {
+			changed = append(changed, file)
+		}
+	}
+	x.mu.RUnlock()
+
+	x.mu.Lock()
+	for path := range x.files {
+		if !seen[path] {
+			delete(x.files, path)
+		}
+	}
+	x.mu.Unlock()
+
+	jobs := make(chan FileInfo)
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				x.index(file)
+			}
+		}()
+	}
+
+	for _, file := range changed {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// UpdateFile re-indexes one file, e.g. after it was saved. Like Update it
+// leaves out files outside the project or hidden by its ignore files; such
+// a file is dropped, as is one that no longer exists.
+func (x *SymbolIndex) UpdateFile(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		x.drop(path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() || filepath.Ext(path) != ".go" {
+		return nil
+	}
+
+	rel, err := filepath.Rel(x.project.Path(), path)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(rel, "..") || isIgnoredPackageDir(filepath.Dir(rel)) || x.project.Ignored(path) {
+		x.drop(path)
+		return nil
+	}
+	x.index(FileInfo{
+		Name:    info.Name(),
+		Path:    path,
+		RelPath: rel,
+		Size:    info.Size(),
+		ModTime: info.ModTime().Unix(),
+	})
+	return nil
+}
+
+// drop removes a file from the index
+func (x *SymbolIndex) drop(path string) {
+	x.mu.Lock()
+	delete(x.files, path)
+	x.mu.Unlock()
+}
+
+// index parses a file and stores its symbols. Files that fail to parse
+// keep the declarations parsed before the error.
+func (x *SymbolIndex) index(file FileInfo) {
+	src, err := os.ReadFile(file.Path)
+	if err != nil {
+		return
+	}
+
+	entry := &indexedFile{modTime: file.ModTime, size: file.Size}
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, file.Path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if f != nil {
+		entry.pkg, entry.symbols = fileSymbols(fset, f)
+		entry.pkg.File = file.Path
+		entry.pkg.RelPath = file.RelPath
+		for i := range entry.symbols {
+			entry.symbols[i].File = file.Path
+			entry.symbols[i].RelPath = file.RelPath
+		}
+	}
+
+	x.mu.Lock()
+	x.files[file.Path] = entry
+	x.mu.Unlock()
+}
+
+// Len returns the number of indexed symbols, packages included
+func (x *SymbolIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	n := len(x.packages())
+	for _, file := range x.files {
+		n += len(file.symbols)
+	}
+	return n
+}
+
+// Search returns up to limit symbols fuzzily matching query, best first.
+// A query containing "." matches qualified names, e.g. "Builder.Build";
+// otherwise plain names are matched. limit <= 0 returns every match.
+func (x *SymbolIndex) Search(query string, limit int) []SymbolMatch {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	qualified := strings.Contains(query, ".")
+
+	x.mu.RLock()
+	var matches []SymbolMatch
+	match := func(sym Symbol) {
+		name := sym.Name
+		if qualified {
+			name = sym.QualifiedName()
+		}
+		if score, ok := FuzzyMatch(query, name); ok {
+			matches = append(matches, SymbolMatch{Symbol: sym, Score: score})
+		}
+	}
+	for _, pkg := range x.packages() {
+		match(pkg)
+	}
+	for _, file := range x.files {
+		for _, sym := range file.symbols {
+			match(sym)
+		}
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		}
+		if a.RelPath != b.RelPath {
+			return a.RelPath < b.RelPath
+		}
+		return a.Line < b.Line
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// packages returns one symbol per package directory, located at the file
+// with the package doc, or else the first file; x.mu must be held
+func (x *SymbolIndex) packages() []Symbol {
+	byDir := make(map[string]Symbol)
+	for _, file := range x.files {
+		pkg := file.pkg
+		if pkg.Name == "" {
+			continue
+		}
+		// Test packages like "x_test" share their directory
+		key := filepath.Dir(pkg.File) + "\x00" + pkg.Name
+		cur, ok := byDir[key]
+		hasDoc, curDoc := pkg.Doc != "", cur.Doc != ""
+		if !ok || hasDoc && !curDoc || hasDoc == curDoc && pkg.File < cur.File {
+			byDir[key] = pkg
+		}
+	}
+
+	pkgs := make([]Symbol, 0, len(byDir))
+	for _, pkg := range byDir {
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// fileSymbols returns the package clause and the declarations of a file
+func fileSymbols(fset *token.FileSet, f *ast.File) (Symbol, []Symbol) {
+	pkgName := f.Name.Name
+	newSymbol := func(name string, kind SymbolKind, parent string, pos token.Pos, docs ...*ast.CommentGroup) Symbol {
+		position := fset.Position(pos)
+		sym := Symbol{
+			Name:    name,
+			Kind:    kind,
+			Package: pkgName,
+			Parent:  parent,
+			Line:    position.Line,
+			Column:  position.Column,
+		}
+		for _, doc := range docs {
+			if doc != nil {
+				sym.Doc = strings.TrimSpace(doc.Text())
+				break
+			}
+		}
+		return sym
+	}
+
+	pkg := newSymbol(pkgName, SymbolPackage, "", f.Name.Pos(), f.Doc)
+
+	var symbols []Symbol
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				symbols = append(symbols, newSymbol(decl.Name.Name, SymbolFunc, "", decl.Name.Pos(), decl.Doc))
+			} else if len(decl.Recv.List) > 0 {
+				recv := receiverTypeName(decl.Recv.List[0].Type)
+				symbols = append(symbols, newSymbol(decl.Name.Name, SymbolMethod, recv, decl.Name.Pos(), decl.Doc))
+			}
+
+		case *ast.GenDecl:
+			// The declaration's doc applies to a spec without its own only
+			// when the declaration is not grouped
+			var declDoc *ast.CommentGroup
+			if !decl.Lparen.IsValid() {
+				declDoc = decl.Doc
+			}
+
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, newSymbol(spec.Name.Name, SymbolType, "", spec.Name.Pos(), spec.Doc, declDoc))
+					symbols = append(symbols, memberSymbols(spec, newSymbol)...)
+
+				case *ast.ValueSpec:
+					kind := SymbolVar
+					if decl.Tok == token.CONST {
+						kind = SymbolConst
+					}
+					for _, name := range spec.Names {
+						if name.Name != "_" {
+							symbols = append(symbols, newSymbol(name.Name, kind, "", name.Pos(), spec.Doc, declDoc, spec.Comment))
+						}
+					}
+				}
+			}
+		}
+	}
+	return pkg, symbols
+}
+
+// memberSymbols returns the fields of a struct type or the methods of an
+// interface type. Embedded fields are named after their type.
+func memberSymbols(spec *ast.TypeSpec, newSymbol func(string, SymbolKind, string, token.Pos, ...*ast.CommentGroup) Symbol) []Symbol {
+	var (
+		fields *ast.FieldList
+		kind   SymbolKind
+	)
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields, kind = t.Fields, SymbolField
+	case *ast.InterfaceType:
+		fields, kind = t.Methods, SymbolMethod
+	default:
+		return nil
+	}
+
+	var symbols []Symbol
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			if kind == SymbolField {
+				if name := receiverTypeName(field.Type); name != "" {
+					symbols = append(symbols, newSymbol(name, kind, spec.Name.Name, field.Type.Pos(), field.Doc, field.Comment))
+				}
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			symbols = append(symbols, newSymbol(name.Name, kind, spec.Name.Name, name.Pos(), field.Doc, field.Comment))
+		}
+	}
+	return symbols
+}
+
+// receiverTypeName returns the name of a receiver or embedded type,
+// dropping pointers, type parameters and package qualifiers
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.SelectorExpr:
+			return t.Sel.Name
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// FuzzyMatch reports whether the characters of query appear in order in
+// s, ignoring case, and scores the match: consecutive characters, word
+// starts and prefixes score higher, an exact match highest
+func FuzzyMatch(query, s string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	if strings.EqualFold(query, s) {
+		return 1000, true
+	}
+
+	var (
+		score   int
+		qi      int
+		prevHit = -2
+		prev    rune
+	)
+	q := []rune(query)
+	for i, r := range s {
+		if qi == len(q) {
+			break
+		}
+		if unicode.ToLower(r) == unicode.ToLower(q[qi]) {
+			score++
+			if r == q[qi] {
+				score++ // Same case
+			}
+			if i == 0 || isWordStart(prev, r) {
+				score += 8
+			}
+			if prevHit == i-utf8.RuneLen(prev) {
+				score += 5
+			}
+			prevHit = i
+			qi++
+		}
+		prev = r
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+
+	if len(query) <= len(s) && strings.EqualFold(query, s[:len(query)]) {
+		score += 20
+	}
+	// Prefer shorter names among equal matches
+	return score*10 - utf8.RuneCountInString(s), true
+}
+
+// isWordStart reports whether r starts a word after prev: after a
+// separator, or a capital after a lowercase letter or digit
+func isWordStart(prev, r rune) bool {
+	switch {
+	case prev == '.' || prev == '_' || prev == '/' || prev == '-':
+		return true
+	case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query, s string
+		ok       bool
+	}{
+		{"", "Anything", true},
+		{"build", "Build", true},
+		{"bld", "Build", true},
+		{"gb", "GoBuilder", true},
+		{"dliub", "Build", false},
+		{"buildx", "Build", false},
+		{"xyz", "Build", false},
+	}
+	for _, tt := range tests {
+		if _, ok := FuzzyMatch(tt.query, tt.s); ok != tt.ok {
+			t.Errorf("FuzzyMatch(%q, %q) matched = %t, want %t", tt.query, tt.s, ok, tt.ok)
+		}
+	}
+	if score, _ := FuzzyMatch("BUILD", "build"); score != 1000 {
+		t.Errorf("exact match scored %d, want 1000", score)
+	}
+}
+
+func TestFuzzyMatchRanking(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string // Best first
+	}{
+		{
+			// Exact, then prefix, then word start, then inside a word,
+			// then scattered
+			query: "build",
+			want:  []string{"Build", "BuildAll", "BuildContext", "GoBuilder", "rebuild", "bxuxixlxd"},
+		},
+		{
+			// A prefix, then word starts, then letters inside a word
+			query: "gb",
+			want:  []string{"gbx", "go_build", "GoBuilder", "logbook"},
+		},
+		{
+			// Same case scores higher
+			query: "Run",
+			want:  []string{"RunConfig", "runConfig"},
+		},
+	}
+	for _, tt := range tests {
+		got := slices.Clone(tt.want)
+		scores := make(map[string]int)
+		for _, s := range got {
+			score, ok := FuzzyMatch(tt.query, s)
+			if !ok {
+				t.Fatalf("FuzzyMatch(%q, %q) did not match", tt.query, s)
+			}
+			scores[s] = score
+		}
+		slices.SortStableFunc(got, func(a, b string) int { return scores[b] - scores[a] })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ranking for %q = %q (scores %v), want %q", tt.query, got, scores, tt.want)
+		}
+	}
+}
+
+const symbolsSource = `// Package shapes draws shapes.
+package shapes
+
+import "io"
+
+// Shape is drawn on a canvas
+type Shape interface {
+	io.Writer
+	// Area returns the area
+	Area() float64
+	Perimeter() float64 // In units
+}
+
+// Circle is round
+type Circle struct {
+	*Base
+	io.Reader
+	List[int]
+
+	// Radius in units
+	Radius    float64
+	X, Y      int // Center
+	unexported bool
+}
+
+type (
+	// Point is a location
+	Point struct{ X, Y int }
+	Alias = Point
+)
+
+// Area implements Shape
+func (c *Circle) Area() float64 { return 0 }
+
+func (l List[T]) Len() int { return 0 }
+
+// New returns a circle
+func New() *Circle { return nil }
+
+// Sizes of shapes
+const (
+	Small = iota // Smallest
+	_
+	// Large shapes
+	Large
+)
+
+// Default is the default shape
+var Default, _ = New(), 0
+`
+
+// symbolLine formats a symbol for comparison
+func symbolLine(s Symbol) string {
+	return fmt.Sprintf("%d:%d %s %s doc=%q", s.Line, s.Column, s.Kind, s.QualifiedName(), s.Doc)
+}
+
+func TestFileSymbols(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "shapes.go", symbolsSource, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, symbols := fileSymbols(fset, f)
+	if got, want := symbolLine(pkg), `2:9 package shapes doc="Package shapes draws shapes."`; got != want {
+		t.Errorf("package = %s, want %s", got, want)
+	}
+
+	var got []string
+	for _, s := range symbols {
+		got = append(got, symbolLine(s))
+	}
+	want := []string{
+		`7:6 type shapes.Shape doc="Shape is drawn on a canvas"`,
+		`10:2 method shapes.Shape.Area doc="Area returns the area"`,
+		`11:2 method shapes.Shape.Perimeter doc="In units"`,
+		`15:6 type shapes.Circle doc="Circle is round"`,
+		`16:2 field shapes.Circle.Base doc=""`,
+		`17:2 field shapes.Circle.Reader doc=""`,
+		`18:2 field shapes.Circle.List doc=""`,
+		`21:2 field shapes.Circle.Radius doc="Radius in units"`,
+		`22:2 field shapes.Circle.X doc="Center"`,
+		`22:5 field shapes.Circle.Y doc="Center"`,
+		`23:2 field shapes.Circle.unexported doc=""`,
+		`28:2 type shapes.Point doc="Point is a location"`,
+		`28:16 field shapes.Point.X doc=""`,
+		`28:19 field shapes.Point.Y doc=""`,
+		`29:2 type shapes.Alias doc=""`,
+		`33:18 method shapes.Circle.Area doc="Area implements Shape"`,
+		`35:18 method shapes.List.Len doc=""`,
+		`38:6 func shapes.New doc="New returns a circle"`,
+		`42:2 const shapes.Small doc="Smallest"`,
+		`45:2 const shapes.Large doc="Large shapes"`,
+		`49:5 var shapes.Default doc="Default is the default shape"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fileSymbols() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSymbolIndex(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":                "module example.com/s\n",
+		"build.go":              "package s\n\ntype Builder struct{}\n\nfunc (b *Builder) Build() {}\n\nfunc BuildAll() {}\n",
+		"doc.go":                "// Package s builds.\npackage s\n",
+		"sub/sub.go":            "package sub\n\nfunc Build() {}\n",
+		"testdata/t.go":         "package t\n\nfunc Build() {}\n",
+		"_old/o.go":             "package o\n\nfunc Build() {}\n",
+		"vendor/v/v.go":         "package v\n\nfunc Build() {}\n",
+		"broken/broken.go":      "package broken\n\nfunc Build() {}\n\nfunc (",
+		"notes/build_notes.txt": "func Build() {}\n",
+	})
+	index := NewSymbolIndex(NewGoProject(root, testFS{}))
+	if err := index.Update(context.Background(), DefaultWalkOptions()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	search := func(query string) []string {
+		var names []string
+		for _, m := range index.Search(query, 0) {
+			names = append(names, filepath.ToSlash(m.RelPath)+" "+m.QualifiedName())
+		}
+		return names
+	}
+
+	// Ties go to the shorter name, then by path; files that fail to parse
+	// keep what was parsed
+	want := []string{
+		"broken/broken.go broken.Build",
+		"build.go s.Builder.Build",
+		"sub/sub.go sub.Build",
+		"build.go s.Builder",
+		"build.go s.BuildAll",
+	}
+	if got := search("build"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(build) = %q, want %q", got, want)
+	}
+	if got, want := search("Builder.Build"), []string{"build.go s.Builder.Build"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(Builder.Build) = %q, want %q", got, want)
+	}
+	// Packages are found at the file with their doc
+	if got, want := search("s"), []string{"doc.go s", "sub/sub.go sub"}; !reflect.DeepEqual(got[:2], want) {
+		t.Errorf("Search(s) = %q, want %q first", got, want)
+	}
+	if got := index.Search("build", 2); len(got) != 2 {
+		t.Errorf("Search with limit 2 returned %d matches", len(got))
+	}
+
+	// Saved and removed files are updated one at a time
+	sub := filepath.Join(root, "sub", "sub.go")
+	if err := os.WriteFile(sub, []byte("package sub\n\nfunc Rebuild() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.UpdateFile(sub); err != nil {
+		t.Fatalf("UpdateFile: %v", err)
+	}
+	if err := os.Remove(filepath.Join(root, "broken", "broken.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.UpdateFile(filepath.Join(root, "broken", "broken.go")); err != nil {
+		t.Fatalf("UpdateFile of a removed file: %v", err)
+	}
+	if err := index.UpdateFile(filepath.Join(root, "testdata", "t.go")); err != nil {
+		t.Fatalf("UpdateFile in testdata: %v", err)
+	}
+	want = []string{"build.go s.Builder.Build", "build.go s.Builder", "build.go s.BuildAll", "sub/sub.go sub.Rebuild"}
+	if got := search("build"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(build) after updates = %q, want %q", got, want)
+	}
+}
+
+func TestProjectIgnored(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		".gitignore":    "build/\n!build/keep.go\n",
+		"build/keep.go": "",
+		"main.go":       "",
+	})
+	project := NewGoProject(root, testFS{})
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.go", false},
+		// Like git, files cannot be re-included below an excluded directory
+		{"build/keep.go", true},
+		{".hidden/a.go", true},
+		{"../outside.go", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(root, filepath.FromSlash(tt.path))
+		if got := project.Ignored(path); got != tt.want {
+			t.Errorf("Ignored(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Package core provides discovery and execution of Makefile and script tasks.
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrTaskNotFound is returned when no task has the requested name
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskKind identifies how a task is run
+type TaskKind string
+
+const (
+	TaskMake   TaskKind = "make"   // A Makefile target, run with make
+	TaskScript TaskKind = "script" // A script in the project root
+)
+
+// Task is a project task that can be run from the IDE
+type Task struct {
+	Name        string
+	Kind        TaskKind
+	Description string
+	File        string // Makefile or script path relative to the project root
+	Line        int    // Line of the target in the Makefile
+}
+
+// makefileNames are the files make reads by default, in order
+var makefileNames = []string{"GNUmakefile", "makefile", "Makefile"}
+
+// makeTargetPattern matches "target [target...]: prerequisites", but not
+// variable assignments such as "X := y"
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9_][A-Za-z0-9_./-]*(?:\s+[A-Za-z0-9_][A-Za-z0-9_./-]*)*)\s*::?(?:[^=:]|$)`)
+
+// DiscoverTasks returns the targets of the project's Makefile followed by
+// the scripts in the project root
+func DiscoverTasks(projectPath string) ([]Task, error) {
+	var tasks []Task
+
+	for _, name := range makefileNames {
+		f, err := os.Open(filepath.Join(projectPath, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		targets, err := ParseMakefile(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, target := range targets {
+			target.File = name
+			tasks = append(tasks, target)
+		}
+		break
+	}
+
+	scripts, err := findScripts(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	return append(tasks, scripts...), nil
+}
+
+// ParseMakefile returns the explicit targets of a Makefile in order.
+// A target's description is taken from the "##" comments directly above
+// it, a trailing "## comment" on its line, or, failing those, the plain
+// comments above it. Special (.PHONY) and pattern (%) targets, and lines
+// setting target-specific variables, are skipped.
+func ParseMakefile(r io.Reader) ([]Task, error) {
+	var (
+		tasks    []Task
+		seen     = make(map[string]bool)
+		doc      []string // "##" comment lines above the current line
+		comments []string // Plain comment lines above the current line
+	)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "##"):
+			doc = append(doc, strings.TrimSpace(strings.TrimLeft(line, "#")))
+			continue
+		case strings.HasPrefix(line, "#"):
+			comments = append(comments, strings.TrimSpace(strings.TrimLeft(line, "#")))
+			continue
+		case strings.HasPrefix(line, "\t"):
+			// Recipe lines do not break the association with a comment
+			continue
+		}
+
+		match := makeTargetPattern.FindStringSubmatch(line)
+		if match != nil && isTargetVariable(line) {
+			// Like a recipe line, it leaves the comments for the rule
+			continue
+		}
+
+		description := strings.Join(doc, " ")
+		if description == "" {
+			description = strings.Join(comments, " ")
+		}
+		doc, comments = nil, nil
+
+		if match == nil {
+			continue
+		}
+		if _, trailing, ok := strings.Cut(line, "##"); ok {
+			description = strings.TrimSpace(trailing)
+		}
+
+		for _, name := range strings.Fields(match[1]) {
+			if strings.HasPrefix(name, ".") || strings.Contains(name, "%") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			tasks = append(tasks, Task{
+				Name:        name,
+				Kind:        TaskMake,
+				Description: description,
+				Line:        lineNum,
+			})
+		}
+	}
+
+	return tasks, scanner.Err()
+}
+
+// isTargetVariable reports whether a line matching makeTargetPattern sets
+// a target-specific variable, e.g. "test: GOFLAGS := -race", rather than
+// declaring a rule
+func isTargetVariable(line string) bool {
+	_, rest, _ := strings.Cut(line, ":")
+	rest, _, _ = strings.Cut(strings.TrimPrefix(rest, ":"), "#")
+	return strings.Contains(rest, "=")
+}
+
+// findScripts returns the scripts in the project root: *.sh files and
+// executable files starting with a #! line
+func findScripts(projectPath string) ([]Task, error) {
+	entries, err := os.ReadDir(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []Task
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		isShell := strings.HasSuffix(entry.Name(), ".sh")
+		if !isShell && info.Mode()&0111 == 0 {
+			continue
+		}
+
+		header, ok := readScriptHeader(filepath.Join(projectPath, entry.Name()))
+		if !ok && !isShell {
+			// Executables without a shebang are binaries
+			continue
+		}
+
+		scripts = append(scripts, Task{
+			Name:        entry.Name(),
+			Kind:        TaskScript,
+			Description: header,
+			File:        entry.Name(),
+		})
+	}
+
+	sort.Slice(scripts, func(i, j int) bool {
+		return scripts[i].Name < scripts[j].Name
+	})
+	return scripts, nil
+}
+
+// readScriptHeader reports whether a file starts with a #! line and
+// returns the first comment among its leading lines as a description
+func readScriptHeader(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(io.LimitReader(f, 4096))
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "#!") {
+		return "", false
+	}
+
+	for i := 0; i < 10 && scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if comment, ok := strings.CutPrefix(line, "#"); ok && strings.TrimSpace(comment) != "" {
+			return strings.TrimSpace(strings.TrimLeft(comment, "#")), true
+		}
+	}
+	return "", true
+}
+
+// FindTask returns the task with the given name
+func FindTask(tasks []Task, name string) (Task, error) {
+	for _, task := range tasks {
+		if task.Name == name {
+			return task, nil
+		}
+	}
+	return Task{}, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+}
+
+// RunTask runs a Makefile target or script in the project root with
+// extra arguments, streaming its output to out
+func (b *GoBuilder) RunTask(ctx context.Context, project Project, task Task, args []string, out OutputSink) error {
+	var cmd *exec.Cmd
+	switch task.Kind {
+	case TaskMake:
+		makeArgs := []string{task.Name}
+		if task.File != "" {
+			makeArgs = []string{"-f", task.File, task.Name}
+		}
+		cmd = exec.CommandContext(ctx, "make", append(makeArgs, args...)...)
+	case TaskScript:
+		script := filepath.Join(project.Path(), task.File)
+		if info, err := os.Stat(script); err == nil && info.Mode()&0111 != 0 {
+			cmd = exec.CommandContext(ctx, script, args...)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", append([]string{script}, args...)...)
+		}
+	default:
+		return fmt.Errorf("unknown task kind %q", task.Kind)
+	}
+	cmd.Dir = project.Path()
+
+	if b.logger != nil {
+		b.logger.Info("Running task",
+			Field{Key: "project", Value: project.Path()},
+			Field{Key: "task", Value: task.Name})
+	}
+
+	return runCommand(cmd, out)
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMakefile(t *testing.T) {
+	makefile := `# Project tasks
+GO := go
+VERSION ?= dev
+LDFLAGS = -X main.version=$(VERSION)
+FLAGS ::= -v
+TAGS != echo tags
+
+.PHONY: build test lint clean
+
+## Build the binary
+## for the current platform
+build: deps
+	$(GO) build -ldflags "$(LDFLAGS)" ./...
+
+test: GOFLAGS := -race
+test: export CGO_ENABLED=1
+# Run the tests
+test: build ## Run all tests with the race detector
+	$(GO) test ./...
+
+# Plain comment
+lint:
+	golangci-lint run
+
+# Separated by a blank line
+
+clean distclean: ## Remove build outputs
+	rm -rf dist
+
+%.pb.go: %.proto
+	protoc $<
+
+install:: build
+deps:
+build: more-deps
+`
+	tasks, err := ParseMakefile(strings.NewReader(makefile))
+	if err != nil {
+		t.Fatalf("ParseMakefile: %v", err)
+	}
+
+	want := []Task{
+		{Name: "build", Kind: TaskMake, Description: "Build the binary for the current platform", Line: 12},
+		{Name: "test", Kind: TaskMake, Description: "Run all tests with the race detector", Line: 18},
+		{Name: "lint", Kind: TaskMake, Description: "Plain comment", Line: 22},
+		{Name: "clean", Kind: TaskMake, Description: "Remove build outputs", Line: 27},
+		{Name: "distclean", Kind: TaskMake, Description: "Remove build outputs", Line: 27},
+		{Name: "install", Kind: TaskMake, Line: 33},
+		{Name: "deps", Kind: TaskMake, Line: 34},
+	}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("ParseMakefile() =\n%+v\nwant\n%+v", tasks, want)
+	}
+}
+
+func TestParseMakefileTargetVariable(t *testing.T) {
+	// The comment above a target-specific variable documents the rule
+	makefile := "## Run the tests\n" +
+		"test: GOFLAGS = -race\n" +
+		"test:\n" +
+		"\tgo test ./...\n"
+	tasks, err := ParseMakefile(strings.NewReader(makefile))
+	if err != nil {
+		t.Fatalf("ParseMakefile: %v", err)
+	}
+	want := []Task{{Name: "test", Kind: TaskMake, Description: "Run the tests", Line: 3}}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("ParseMakefile() = %+v, want %+v", tasks, want)
+	}
+}
+
+func TestDiscoverTasks(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"GNUmakefile":  "gnu: ## Preferred by make\n",
+		"Makefile":     "ignored:\n",
+		"build.sh":     "# Not executable and no shebang\necho build\n",
+		"release":      "#!/bin/sh\n\n#\n## Publish a release\nexit 0\n",
+		"tool":         "\x7fELF binary",
+		".hidden.sh":   "#!/bin/sh\n",
+		"README.md":    "# Readme\n",
+		"scripts/x.sh": "#!/bin/sh\n",
+	})
+	for _, name := range []string{"release", "tool"} {
+		if err := os.Chmod(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := DiscoverTasks(root)
+	if err != nil {
+		t.Fatalf("DiscoverTasks: %v", err)
+	}
+	want := []Task{
+		{Name: "gnu", Kind: TaskMake, Description: "Preferred by make", File: "GNUmakefile", Line: 1},
+		{Name: "build.sh", Kind: TaskScript, File: "build.sh"},
+		{Name: "release", Kind: TaskScript, Description: "Publish a release", File: "release"},
+	}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("DiscoverTasks() =\n%+v\nwant\n%+v", tasks, want)
+	}
+
+	if task, err := FindTask(tasks, "release"); err != nil || task.Name != "release" {
+		t.Errorf("FindTask(release) = %+v, %v", task, err)
+	}
+	if _, err := FindTask(tasks, "deploy"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("FindTask(deploy) error = %v, want %v", err, ErrTaskNotFound)
+	}
+}
@@ -0,0 +1,24 @@
+package definition
+
+import (
+	"strings"
+
+	"example.com/definition/sub"
+)
+
+// Shape has an area
+type Shape struct {
+	Width int
+}
+
+// Area returns the area
+func (s *Shape) Area() int {
+	return s.Width * s.Width
+}
+
+// Describe describes a shape
+func Describe(s *Shape) string {
+	name := strings.ToUpper("shape")
+	sub.Exported()
+	return name + string(rune(len(name)+s.Area()))
+}
@@ -0,0 +1,9 @@
+package definition
+
+import "testing"
+
+func TestArea(t *testing.T) {
+	if got := (&Shape{Width: 2}).Area(); got != 4 {
+		t.Errorf("Area() = %d", got)
+	}
+}
@@ -0,0 +1,11 @@
+package definition_test
+
+import (
+	"fmt"
+
+	"example.com/definition"
+)
+
+func ExampleDescribe() {
+	fmt.Println(definition.Describe(&definition.Shape{}))
+}
@@ -0,0 +1,3 @@
+module example.com/definition
+
+go 1.25
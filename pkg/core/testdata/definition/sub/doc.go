@@ -0,0 +1,2 @@
+// Package sub is imported by definition.
+package sub
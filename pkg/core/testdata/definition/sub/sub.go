@@ -0,0 +1,4 @@
+package sub
+
+// Exported does nothing
+func Exported() {}
@@ -0,0 +1,248 @@
+// Package core provides parsing of go test -json output into test reports.
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// TestStatus represents the outcome of a test or package
+type TestStatus string
+
+const (
+	TestRunning TestStatus = "run"
+	TestPass    TestStatus = "pass"
+	TestFail    TestStatus = "fail"
+	TestSkip    TestStatus = "skip"
+)
+
+// TestEvent is a single event emitted by go test -json (see go doc test2json)
+type TestEvent struct {
+	Time        time.Time
+	Action      string
+	Package     string
+	ImportPath  string // Set on build-output and build-fail events
+	Test        string
+	Elapsed     float64 // Seconds
+	Output      string
+	FailedBuild string
+}
+
+// TestResult is the result of a single test or subtest
+type TestResult struct {
+	Name     string // Full name, e.g. "TestParse/empty"
+	Status   TestStatus
+	Elapsed  time.Duration
+	Output   []string
+	Subtests []*TestResult
+}
+
+// ShortName returns the last component of the test name
+func (t *TestResult) ShortName() string {
+	if i := strings.LastIndex(t.Name, "/"); i >= 0 {
+		return t.Name[i+1:]
+	}
+	return t.Name
+}
+
+// PackageResult is the result of testing a single package
+type PackageResult struct {
+	ImportPath string
+	Status     TestStatus
+	Elapsed    time.Duration
+	Output     []string
+	Tests      []*TestResult // Top-level tests; subtests are nested
+
+	tests map[string]*TestResult
+}
+
+// TestReport aggregates a go test -json event stream into a
+// package → test → subtest tree
+type TestReport struct {
+	Packages []*PackageResult
+	Output   []string // Lines that were not test events
+
+	packages map[string]*PackageResult
+}
+
+// NewTestReport creates an empty test report
+func NewTestReport() *TestReport {
+	return &TestReport{
+		packages: make(map[string]*PackageResult),
+	}
+}
+
+// ParseTestEvents reads a go test -json stream into a report
+func ParseTestEvents(r io.Reader) (*TestReport, error) {
+	report := NewTestReport()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputLineSize)
+	for scanner.Scan() {
+		report.AddLine(scanner.Text())
+	}
+
+	return report, scanner.Err()
+}
+
+// AddLine adds a raw line of go test -json output to the report.
+// It returns the decoded event and true, or false if the line was not
+// a test event.
+func (r *TestReport) AddLine(line string) (TestEvent, bool) {
+	var ev TestEvent
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+		r.Output = append(r.Output, line)
+		return ev, false
+	}
+	r.AddEvent(ev)
+	return ev, true
+}
+
+// AddEvent adds a test event to the report
+func (r *TestReport) AddEvent(ev TestEvent) {
+	// Build events are keyed by import path, e.g. "p [p.test]"
+	if ev.Package == "" && ev.ImportPath != "" {
+		ev.Package, _, _ = strings.Cut(ev.ImportPath, " ")
+	}
+	if ev.Package == "" {
+		if ev.Output != "" {
+			r.Output = append(r.Output, strings.TrimSuffix(ev.Output, "\n"))
+		}
+		return
+	}
+
+	pkg := r.pkg(ev.Package)
+
+	if ev.Test == "" {
+		switch ev.Action {
+		case "output", "build-output":
+			pkg.Output = append(pkg.Output, strings.TrimSuffix(ev.Output, "\n"))
+		case "build-fail":
+			pkg.Status = TestFail
+		case "pass", "fail", "skip":
+			pkg.Status = TestStatus(ev.Action)
+			pkg.Elapsed = seconds(ev.Elapsed)
+		}
+		return
+	}
+
+	test := pkg.test(ev.Test)
+	switch ev.Action {
+	case "output":
+		test.Output = append(test.Output, strings.TrimSuffix(ev.Output, "\n"))
+	case "pass", "fail", "skip":
+		test.Status = TestStatus(ev.Action)
+		test.Elapsed = seconds(ev.Elapsed)
+	}
+}
+
+// pkg returns the result for a package, creating it if needed
+func (r *TestReport) pkg(importPath string) *PackageResult {
+	if pkg, ok := r.packages[importPath]; ok {
+		return pkg
+	}
+
+	pkg := &PackageResult{
+		ImportPath: importPath,
+		Status:     TestRunning,
+		tests:      make(map[string]*TestResult),
+	}
+	r.packages[importPath] = pkg
+	r.Packages = append(r.Packages, pkg)
+	return pkg
+}
+
+// test returns the result for a test, creating it and attaching it to
+// its parent test if needed
+func (p *PackageResult) test(name string) *TestResult {
+	if test, ok := p.tests[name]; ok {
+		return test
+	}
+
+	test := &TestResult{
+		Name:   name,
+		Status: TestRunning,
+	}
+	p.tests[name] = test
+
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		parent := p.test(name[:i])
+		parent.Subtests = append(parent.Subtests, test)
+	} else {
+		p.Tests = append(p.Tests, test)
+	}
+
+	return test
+}
+
+// Counts returns the number of passed, failed and skipped tests,
+// including subtests
+func (p *PackageResult) Counts() (passed, failed, skipped int) {
+	walkTests(p.Tests, func(t *TestResult) {
+		switch t.Status {
+		case TestPass:
+			passed++
+		case TestFail:
+			failed++
+		case TestSkip:
+			skipped++
+		}
+	})
+	return passed, failed, skipped
+}
+
+// Counts returns the number of passed, failed and skipped tests across
+// all packages
+func (r *TestReport) Counts() (passed, failed, skipped int) {
+	for _, pkg := range r.Packages {
+		p, f, s := pkg.Counts()
+		passed += p
+		failed += f
+		skipped += s
+	}
+	return passed, failed, skipped
+}
+
+// Passed returns true if no package or test failed
+func (r *TestReport) Passed() bool {
+	for _, pkg := range r.Packages {
+		if pkg.Status == TestFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the innermost failing tests of a package: a test whose
+// subtests failed is represented by those subtests only
+func (p *PackageResult) Failed() []*TestResult {
+	var failed []*TestResult
+	walkTests(p.Tests, func(t *TestResult) {
+		if t.Status != TestFail {
+			return
+		}
+		for _, sub := range t.Subtests {
+			if sub.Status == TestFail {
+				return
+			}
+		}
+		failed = append(failed, t)
+	})
+	return failed
+}
+
+// walkTests calls fn for every test in tests and their subtests, depth first
+func walkTests(tests []*TestResult, fn func(*TestResult)) {
+	for _, t := range tests {
+		fn(t)
+		walkTests(t.Subtests, fn)
+	}
+}
+
+// seconds converts go test's elapsed seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
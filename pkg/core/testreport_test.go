@@ -0,0 +1,165 @@
+package core
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTree returns "name status" for each test and subtest, indented by
+// depth
+func testTree(tests []*TestResult) []string {
+	var lines []string
+	var walk func(tests []*TestResult, indent string)
+	walk = func(tests []*TestResult, indent string) {
+		for _, test := range tests {
+			lines = append(lines, indent+test.Name+" "+string(test.Status))
+			walk(test.Subtests, indent+"  ")
+		}
+	}
+	walk(tests, "")
+	return lines
+}
+
+func TestParseTestEvents(t *testing.T) {
+	// go test -json ./... of a package with parallel subtests, one that
+	// fails to build and one without tests. The parallel subtests'
+	// output is interleaved.
+	f, err := os.Open("testdata/testevents.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	report, err := ParseTestEvents(f)
+	if err != nil {
+		t.Fatalf("ParseTestEvents: %v", err)
+	}
+
+	if want := []string{"go: downloading example.com/dep v1.0.0"}; !reflect.DeepEqual(report.Output, want) {
+		t.Errorf("Output = %q, want %q", report.Output, want)
+	}
+
+	var pkgs []string
+	for _, pkg := range report.Packages {
+		pkgs = append(pkgs, pkg.ImportPath+" "+string(pkg.Status))
+	}
+	want := []string{"example.com/tj fail", "example.com/tj/bad fail", "example.com/tj/skipme skip"}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Fatalf("packages = %q, want %q", pkgs, want)
+	}
+	tj, bad, skipme := report.Packages[0], report.Packages[1], report.Packages[2]
+
+	want = []string{
+		"TestA fail",
+		"  TestA/one pass",
+		"  TestA/two fail",
+		"TestSkip skip",
+	}
+	if got := testTree(tj.Tests); !reflect.DeepEqual(got, want) {
+		t.Errorf("tests = %q, want %q", got, want)
+	}
+	if tj.Elapsed != 3*time.Millisecond {
+		t.Errorf("Elapsed = %v, want 3ms", tj.Elapsed)
+	}
+
+	one, two := tj.Tests[0].Subtests[0], tj.Tests[0].Subtests[1]
+	if want := []string{"=== RUN   TestA/one", "=== PAUSE TestA/one", "=== CONT  TestA/one", "    a_test.go:6: in one", "--- PASS: TestA/one (0.00s)"}; !reflect.DeepEqual(one.Output, want) {
+		t.Errorf("TestA/one output = %q, want %q", one.Output, want)
+	}
+	if want := []string{"=== RUN   TestA/two", "=== PAUSE TestA/two", "=== CONT  TestA/two", "    a_test.go:7: two failed", "--- FAIL: TestA/two (0.00s)"}; !reflect.DeepEqual(two.Output, want) {
+		t.Errorf("TestA/two output = %q, want %q", two.Output, want)
+	}
+	if two.ShortName() != "two" {
+		t.Errorf("ShortName() = %q, want two", two.ShortName())
+	}
+	if failed := tj.Failed(); len(failed) != 1 || failed[0] != two {
+		t.Errorf("Failed() = %q, want only TestA/two", testTree(failed))
+	}
+
+	// Build output is reported under the package being tested
+	if len(bad.Tests) != 0 {
+		t.Errorf("package that failed to build has tests %q", testTree(bad.Tests))
+	}
+	want = []string{
+		"# example.com/tj/bad [example.com/tj/bad.test]",
+		"bad/b_test.go:5:28: undefined: undefined",
+		"FAIL\texample.com/tj/bad [build failed]",
+	}
+	if !reflect.DeepEqual(bad.Output, want) {
+		t.Errorf("build failure output = %q, want %q", bad.Output, want)
+	}
+	if want := []string{"?   \texample.com/tj/skipme\t[no test files]"}; !reflect.DeepEqual(skipme.Output, want) {
+		t.Errorf("skipped package output = %q, want %q", skipme.Output, want)
+	}
+
+	if passed, failed, skipped := report.Counts(); passed != 1 || failed != 2 || skipped != 1 {
+		t.Errorf("Counts() = %d, %d, %d; want 1, 2, 1", passed, failed, skipped)
+	}
+	if report.Passed() {
+		t.Error("Passed() = true, want false")
+	}
+}
+
+func TestTestReportPackageOnly(t *testing.T) {
+	// A package can fail or be skipped without reporting any test, e.g.
+	// when TestMain exits early
+	events := `{"Action":"start","Package":"p"}
+{"Action":"output","Package":"p","Output":"setup failed\n"}
+{"Action":"output","Package":"p","Output":"FAIL\tp\t0.010s\n"}
+{"Action":"fail","Package":"p","Elapsed":0.01}
+{"Action":"start","Package":"q"}
+{"Action":"skip","Package":"q","Elapsed":0}
+`
+	report, err := ParseTestEvents(strings.NewReader(events))
+	if err != nil {
+		t.Fatalf("ParseTestEvents: %v", err)
+	}
+	if len(report.Packages) != 2 {
+		t.Fatalf("got %d packages, want 2", len(report.Packages))
+	}
+	p, q := report.Packages[0], report.Packages[1]
+	if p.Status != TestFail || len(p.Tests) != 0 || len(p.Failed()) != 0 {
+		t.Errorf("p = %s with tests %q, want a failure without tests", p.Status, testTree(p.Tests))
+	}
+	if want := []string{"setup failed", "FAIL\tp\t0.010s"}; !reflect.DeepEqual(p.Output, want) {
+		t.Errorf("p output = %q, want %q", p.Output, want)
+	}
+	if q.Status != TestSkip {
+		t.Errorf("q = %s, want %s", q.Status, TestSkip)
+	}
+	if passed, failed, skipped := report.Counts(); passed+failed+skipped != 0 {
+		t.Errorf("Counts() = %d, %d, %d; want no tests", passed, failed, skipped)
+	}
+	if report.Passed() {
+		t.Error("Passed() = true with a failed package")
+	}
+}
+
+func TestTestReportRunning(t *testing.T) {
+	report := NewTestReport()
+	for _, line := range []string{
+		`{"Action":"run","Package":"p","Test":"TestB/sub"}`,
+		`{"Action":"output","Package":"p","Test":"TestB/sub","Output":"=== RUN   TestB/sub\n"}`,
+		`not json`,
+		`{"Action":"output","Output":"no package\n"}`,
+	} {
+		report.AddLine(line)
+	}
+
+	// A subtest seen first creates its parent
+	if got, want := testTree(report.Packages[0].Tests), []string{"TestB run", "  TestB/sub run"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tests = %q, want %q", got, want)
+	}
+	if report.Packages[0].Status != TestRunning {
+		t.Errorf("package status = %s, want %s", report.Packages[0].Status, TestRunning)
+	}
+	if want := []string{"not json", "no package"}; !reflect.DeepEqual(report.Output, want) {
+		t.Errorf("Output = %q, want %q", report.Output, want)
+	}
+	if !report.Passed() {
+		t.Error("Passed() = false while running")
+	}
+}
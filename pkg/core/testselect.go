@@ -0,0 +1,145 @@
+// Package core provides selection of packages and tests to run.
+package core
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestOptions selects which tests to run and how.
+// The zero value runs every test in the module.
+type TestOptions struct {
+	Package  string   // Package pattern, e.g. "./pkg/core"; defaults to "./..."
+	Packages []string // Package patterns tested together; replaces Package when set
+	Run      string   // -run regular expression
+	Count    int      // -count; 0 leaves the go default (cached results)
+	Verbose  bool     // -v
+	Race     bool     // -race
+
+	CoverProfile string // -coverprofile output file
+}
+
+// Args returns the go test arguments for the options, excluding the
+// package pattern
+func (o TestOptions) Args() []string {
+	var args []string
+	if o.Run != "" {
+		args = append(args, "-run", o.Run)
+	}
+	if o.Count > 0 {
+		args = append(args, "-count", strconv.Itoa(o.Count))
+	}
+	if o.Verbose {
+		args = append(args, "-v")
+	}
+	if o.Race {
+		args = append(args, "-race")
+	}
+	if o.CoverProfile != "" {
+		args = append(args, "-coverprofile", o.CoverProfile)
+	}
+	return args
+}
+
+// PackagePattern returns the package pattern, defaulting to "./...".
+// Several Packages are joined by spaces for display.
+func (o TestOptions) PackagePattern() string {
+	if len(o.Packages) > 0 {
+		return strings.Join(o.Packages, " ")
+	}
+	if o.Package == "" {
+		return "./..."
+	}
+	return o.Package
+}
+
+// PackagePatterns returns the package patterns as separate arguments
+func (o TestOptions) PackagePatterns() []string {
+	if len(o.Packages) > 0 {
+		return o.Packages
+	}
+	return []string{o.PackagePattern()}
+}
+
+// ExactTestPattern returns a -run pattern matching exactly the named
+// test, including subtest names separated by "/"
+func ExactTestPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// PackagePatternForFile returns the "./dir" package pattern for a file
+// inside the project at root
+func PackagePatternForFile(root, file string) (string, error) {
+	rel, err := filepath.Rel(root, filepath.Dir(file))
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return ".", nil
+	}
+	return "./" + filepath.ToSlash(rel), nil
+}
+
+// FindTestAt returns the name of the TestXxx function enclosing the
+// given 1-based line of a Go source file
+func FindTestAt(filename string, src []byte, line int) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil && file == nil {
+		return "", false
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !isTestFunc(fn) {
+			continue
+		}
+
+		start := fset.Position(fn.Pos()).Line
+		end := fset.Position(fn.End()).Line
+		if line >= start && line <= end {
+			return fn.Name.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// isTestFunc reports whether fn has the form func TestXxx(t *testing.T)
+func isTestFunc(fn *ast.FuncDecl) bool {
+	name := fn.Name.Name
+	if !strings.HasPrefix(name, "Test") {
+		return false
+	}
+
+	// "Test" must be followed by a non-lowercase letter, if anything
+	if rest := name[len("Test"):]; rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "T"
+}
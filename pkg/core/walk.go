@@ -0,0 +1,241 @@
+// Package core provides concurrent, cancellable walking of project trees.
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWalkMaxFiles bounds listings of the whole project, so that
+// opening "/" or a home directory fails instead of walking for minutes
+const DefaultWalkMaxFiles = 1 << 20
+
+// walkProgressInterval is the minimum time between progress callbacks
+const walkProgressInterval = 200 * time.Millisecond
+
+// ErrTooManyFiles is returned by a walk seeing more than MaxFiles files
+var ErrTooManyFiles = errors.New("too many files")
+
+// WalkOptions bounds a walk and reports on its progress
+type WalkOptions struct {
+	Workers  int // Concurrent directory reads; defaults to the number of CPUs
+	MaxDepth int // Deepest level listed, the root's entries being 1; 0 is unlimited
+	MaxFiles int // Fail with ErrTooManyFiles past this many files seen; 0 is unlimited
+
+	// MarkGenerated reads the header of every Go file kept to set
+	// FileInfo.Generated, which listings shown to the user need
+	MarkGenerated bool
+
+	// Progress is called periodically with the counts so far, one call at
+	// a time but from the walk's goroutines
+	Progress func(WalkProgress)
+
+	// Logger is warned about directories skipped because they cannot be
+	// read; nil skips them silently
+	Logger Logger
+}
+
+// DefaultWalkOptions returns the options of whole-project listings
+func DefaultWalkOptions() WalkOptions {
+	return WalkOptions{MaxFiles: DefaultWalkMaxFiles}
+}
+
+// WalkProgress counts what a walk has seen so far
+type WalkProgress struct {
+	Dirs  int // Directories read
+	Files int // Files seen, whether kept or not
+}
+
+// Walk lists the files under root, reading directories concurrently with
+// a bounded pool of workers. keep is called concurrently for every entry
+// with RelPath relative to root and may annotate it; returning false
+// skips a file or prunes a directory. Files are returned in the order of filepath.WalkDir.
+// A directory below root that cannot be read is skipped, and one removed
+// while walking is too. Cancelling ctx stops the walk and returns ctx.Err().
+func Walk(ctx context.Context, fs FileSystem, root string, opts WalkOptions, keep func(*FileInfo) bool) ([]FileInfo, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	w := &walker{
+		ctx:     ctx,
+		fs:      fs,
+		root:    root,
+		opts:    opts,
+		keep:    keep,
+		queue:   []walkDir{{path: root}},
+		pending: 1,
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	// Wake idle workers when the walk is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		w.mu.Lock()
+		w.fail(ctx.Err())
+		w.mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	stop()
+
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Progress != nil {
+		opts.Progress(w.progress)
+	}
+
+	// Sorting on path elements matches filepath.WalkDir, which lists a
+	// directory's entries right after it
+	keys := make(map[string]string, len(w.files))
+	for _, file := range w.files {
+		keys[file.Path] = strings.ReplaceAll(file.RelPath, string(filepath.Separator), "\x00")
+	}
+	sort.Slice(w.files, func(i, j int) bool {
+		return keys[w.files[i].Path] < keys[w.files[j].Path]
+	})
+	return w.files, nil
+}
+
+// walkDir is a directory waiting to be read
+type walkDir struct {
+	path  string
+	depth int // Of its entries minus one
+}
+
+// walker is the state of one Walk, guarded by mu
+type walker struct {
+	ctx  context.Context
+	fs   FileSystem
+	root string
+	opts WalkOptions
+	keep func(*FileInfo) bool
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	queue        []walkDir
+	pending      int // Directories queued or being read
+	files        []FileInfo
+	progress     WalkProgress
+	lastProgress time.Time
+	err          error
+}
+
+// work reads queued directories until the walk is done or failed
+func (w *walker) work() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+			w.cond.Wait()
+		}
+		if w.pending == 0 || w.err != nil {
+			return
+		}
+
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+
+		w.mu.Unlock()
+		dirs, files, seen, err := w.read(dir)
+		w.mu.Lock()
+
+		w.pending--
+		if err != nil {
+			w.fail(err)
+			return
+		}
+		w.queue = append(w.queue, dirs...)
+		w.pending += len(dirs)
+		w.files = append(w.files, files...)
+		w.progress.Dirs++
+		w.progress.Files += seen
+
+		if w.opts.MaxFiles > 0 && w.progress.Files > w.opts.MaxFiles {
+			w.fail(fmt.Errorf("%w: more than %d under %s", ErrTooManyFiles, w.opts.MaxFiles, w.root))
+			return
+		}
+		if w.opts.Progress != nil && time.Since(w.lastProgress) >= walkProgressInterval {
+			w.lastProgress = time.Now()
+			w.opts.Progress(w.progress)
+		}
+		w.cond.Broadcast()
+	}
+}
+
+// read lists one directory, returning the subdirectories to read, the
+// files kept and the number of files seen
+func (w *walker) read(dir walkDir) ([]walkDir, []FileInfo, int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+
+	entries, err := w.fs.ListFiles(dir.path)
+	switch {
+	case err != nil && dir.path == w.root:
+		return nil, nil, 0, err
+	case err != nil:
+		// One unreadable directory does not hide the rest of the tree
+		if w.opts.Logger != nil && !errors.Is(err, os.ErrNotExist) {
+			w.opts.Logger.Warn("Skipping unreadable directory",
+				Field{Key: "path", Value: dir.path},
+				Field{Key: "error", Value: err.Error()})
+		}
+		return nil, nil, 0, nil
+	}
+
+	var (
+		dirs  []walkDir
+		files []FileInfo
+		seen  int
+	)
+	depth := dir.depth + 1
+	for _, entry := range entries {
+		if !entry.IsDir {
+			seen++
+		}
+		if rel, err := filepath.Rel(w.root, entry.Path); err == nil {
+			entry.RelPath = rel
+		}
+		if w.keep != nil && !w.keep(&entry) {
+			continue
+		}
+
+		switch {
+		case !entry.IsDir:
+			files = append(files, entry)
+		case w.opts.MaxDepth == 0 || depth < w.opts.MaxDepth:
+			dirs = append(dirs, walkDir{path: entry.Path, depth: depth})
+		}
+	}
+	return dirs, files, seen, nil
+}
+
+// fail stops the walk with the first error; w.mu must be held
+func (w *walker) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+	w.cond.Broadcast()
+}
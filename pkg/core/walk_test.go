@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// testLogger records the messages logged
+type testLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) log(level, msg string, fields []Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, field := range fields {
+		msg += fmt.Sprintf(" %s=%v", field.Key, field.Value)
+	}
+	l.messages = append(l.messages, level+" "+msg)
+}
+
+func (l *testLogger) Debug(msg string, fields ...Field) { l.log("DEBUG", msg, fields) }
+func (l *testLogger) Info(msg string, fields ...Field)  { l.log("INFO", msg, fields) }
+func (l *testLogger) Warn(msg string, fields ...Field)  { l.log("WARN", msg, fields) }
+func (l *testLogger) Error(msg string, fields ...Field) { l.log("ERROR", msg, fields) }
+
+// relPaths returns the slash-separated relative paths of files
+func relPaths(files []FileInfo) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.ToSlash(file.RelPath)
+	}
+	return paths
+}
+
+// walkTree is the tree most walk tests list
+var walkTree = map[string]string{
+	"a.go":           "",
+	"b/c.go":         "",
+	"b/d/e.go":       "",
+	"b/d/f/g.txt":    "",
+	"b-c/h.go":       "",
+	"empty/":         "",
+	"skip/i.go":      "",
+	"skip/deep/j.go": "",
+}
+
+func TestWalk(t *testing.T) {
+	root := writeFiles(t, walkTree)
+
+	tests := []struct {
+		name string
+		opts WalkOptions
+		keep func(*FileInfo) bool
+		want []string
+	}{
+		{
+			name: "all files in filepath.WalkDir order",
+			want: []string{"a.go", "b/c.go", "b/d/e.go", "b/d/f/g.txt", "b-c/h.go", "skip/deep/j.go", "skip/i.go"},
+		},
+		{
+			name: "one worker",
+			opts: WalkOptions{Workers: 1},
+			want: []string{"a.go", "b/c.go", "b/d/e.go", "b/d/f/g.txt", "b-c/h.go", "skip/deep/j.go", "skip/i.go"},
+		},
+		{
+			name: "max depth 1 lists the root's entries",
+			opts: WalkOptions{MaxDepth: 1},
+			want: []string{"a.go"},
+		},
+		{
+			name: "max depth 2",
+			opts: WalkOptions{MaxDepth: 2},
+			want: []string{"a.go", "b/c.go", "b-c/h.go", "skip/i.go"},
+		},
+		{
+			name: "keep prunes directories and skips files",
+			keep: func(info *FileInfo) bool {
+				return info.Name != "skip" && info.Name != "e.go"
+			},
+			want: []string{"a.go", "b/c.go", "b/d/f/g.txt", "b-c/h.go"},
+		},
+		{
+			name: "max files not exceeded",
+			opts: WalkOptions{MaxFiles: 7},
+			want: []string{"a.go", "b/c.go", "b/d/e.go", "b/d/f/g.txt", "b-c/h.go", "skip/deep/j.go", "skip/i.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Walk(context.Background(), testFS{}, root, tt.opts, tt.keep)
+			if err != nil {
+				t.Fatalf("Walk: %v", err)
+			}
+			if got := relPaths(files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkTooManyFiles(t *testing.T) {
+	root := writeFiles(t, walkTree)
+
+	_, err := Walk(context.Background(), testFS{}, root, WalkOptions{MaxFiles: 6}, nil)
+	if !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("Walk error = %v, want %v", err, ErrTooManyFiles)
+	}
+}
+
+func TestWalkCancelled(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 50 {
+		files[fmt.Sprintf("d%d/f.go", i)] = ""
+	}
+	root := writeFiles(t, files)
+
+	// Cancel from inside the walk once a few entries have been seen
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu   sync.Mutex
+		seen int
+	)
+	keep := func(*FileInfo) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if seen++; seen == 10 {
+			cancel()
+		}
+		return true
+	}
+
+	got, err := Walk(ctx, testFS{}, root, WalkOptions{Workers: 4}, keep)
+	if !errors.Is(err, context.Canceled) || got != nil {
+		t.Fatalf("Walk() = %d files, %v; want no files and %v", len(got), err, context.Canceled)
+	}
+}
+
+func TestWalkProgress(t *testing.T) {
+	root := writeFiles(t, walkTree)
+
+	var last WalkProgress
+	opts := WalkOptions{Progress: func(p WalkProgress) { last = p }}
+	if _, err := Walk(context.Background(), testFS{}, root, opts, nil); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	// The root, b, b/d, b/d/f, b-c, empty, skip and skip/deep
+	if want := (WalkProgress{Dirs: 8, Files: 7}); last != want {
+		t.Errorf("final progress = %+v, want %+v", last, want)
+	}
+}
+
+func TestWalkSkipsUnreadableDirectories(t *testing.T) {
+	root := writeFiles(t, walkTree)
+	logger := &testLogger{}
+	fs := testFS{fail: map[string]error{
+		filepath.Join(root, "b", "d"): os.ErrPermission,
+		filepath.Join(root, "skip"):   os.ErrNotExist, // Removed during the walk
+	}}
+
+	files, err := Walk(context.Background(), fs, root, WalkOptions{Logger: logger}, nil)
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if got, want := relPaths(files), []string{"a.go", "b/c.go", "b-c/h.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() = %q, want %q", got, want)
+	}
+
+	// Only the unreadable directory is worth a warning
+	want := []string{fmt.Sprintf("WARN Skipping unreadable directory path=%s error=%v", filepath.Join(root, "b", "d"), os.ErrPermission)}
+	if !reflect.DeepEqual(logger.messages, want) {
+		t.Errorf("logged %q, want %q", logger.messages, want)
+	}
+
+	// Without the root there is nothing to list
+	fs.fail[root] = os.ErrPermission
+	if _, err := Walk(context.Background(), fs, root, WalkOptions{}, nil); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Walk of an unreadable root error = %v, want %v", err, os.ErrPermission)
+	}
+}
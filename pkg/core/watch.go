@@ -0,0 +1,220 @@
+// Package core provides watching of the project tree for file changes.
+package core
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// WatchInterval is how often the project tree is polled for changes.
+	// Polling waits longer on trees that take long to walk.
+	WatchInterval = 500 * time.Millisecond
+
+	// watchBackoff is how many times the duration of the last walk the
+	// watcher waits before the next one, so that a large tree is not
+	// walked back to back
+	watchBackoff = 4
+
+	// WatchDebounce is how long the tree must be quiet after a change
+	// before the watch action runs, so a burst of saves runs it once
+	WatchDebounce = 300 * time.Millisecond
+
+	// watchHashWindow is how recently a file must have been modified for
+	// the watcher to hash its content. Modification times are stored in
+	// whole seconds and some file systems keep only two-second times, so
+	// a save of the same size soon after the last one can leave both the
+	// time and the size unchanged.
+	watchHashWindow = 3 * time.Second
+
+	// watchHashLimit is the size above which recently modified files are
+	// not hashed; their changes are seen through time and size alone
+	watchHashLimit = 1 << 20
+)
+
+// WatchAction is what watch mode does when files change
+type WatchAction string
+
+const (
+	WatchBuild WatchAction = "build" // Rebuild the project
+	WatchTest  WatchAction = "test"  // Test the packages affected by the changed files
+	WatchRun   WatchAction = "run"   // Restart the running program
+)
+
+// ParseWatchAction parses a watch action name
+func ParseWatchAction(name string) (WatchAction, bool) {
+	switch action := WatchAction(name); action {
+	case WatchBuild, WatchTest, WatchRun:
+		return action, true
+	}
+	return "", false
+}
+
+// Watcher polls the project tree for changes. It sees the same files as
+// Project.Files, minus the build outputs Clean removes. Each poll walks
+// the tree, so polls are spaced by the time the last walk took.
+type Watcher struct {
+	project  Project
+	logger   Logger
+	interval time.Duration
+	debounce time.Duration
+}
+
+// NewWatcher creates a watcher for the project
+func NewWatcher(project Project, logger Logger) *Watcher {
+	return &Watcher{
+		project:  project,
+		logger:   logger,
+		interval: WatchInterval,
+		debounce: WatchDebounce,
+	}
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime int64
+	size    int64
+	hash    uint64 // Content hash of recently modified files, 0 otherwise
+}
+
+// differs reports whether two stamps are different versions of a file.
+// Hashes are compared only when both stamps have one, so a file that is
+// no longer recent does not show up as changed.
+func (s fileStamp) differs(other fileStamp) bool {
+	if s.modTime != other.modTime || s.size != other.size {
+		return true
+	}
+	return s.hash != 0 && other.hash != 0 && s.hash != other.hash
+}
+
+// Watch polls the project until ctx is cancelled, calling onChange with
+// the sorted absolute paths of added, modified and removed files once
+// changes have settled. onChange runs on the watching goroutine; changes
+// made while it runs are reported by the next call.
+func (w *Watcher) Watch(ctx context.Context, onChange func(changed []string)) error {
+	start := time.Now()
+	prev, err := w.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(w.pollDelay(time.Since(start)))
+	defer timer.Stop()
+
+	pending := make(map[string]struct{})
+	var lastChange time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		start = time.Now()
+		cur, err := w.snapshot(ctx)
+		timer.Reset(w.pollDelay(time.Since(start)))
+		if err != nil {
+			// Files may vanish mid-walk; try again on the next poll
+			if w.logger != nil {
+				w.logger.Debug("Watch scan failed", Field{Key: "error", Value: err.Error()})
+			}
+			continue
+		}
+
+		for _, path := range diffSnapshots(prev, cur) {
+			pending[path] = struct{}{}
+			lastChange = time.Now()
+		}
+		prev = cur
+
+		if len(pending) == 0 || time.Since(lastChange) < w.debounce {
+			continue
+		}
+
+		changed := make([]string, 0, len(pending))
+		for path := range pending {
+			changed = append(changed, path)
+		}
+		sort.Strings(changed)
+		pending = make(map[string]struct{})
+
+		if w.logger != nil {
+			w.logger.Info("Files changed", Field{Key: "count", Value: len(changed)})
+		}
+		onChange(changed)
+	}
+}
+
+// pollDelay returns how long to wait before the next poll after a walk
+// that took walked
+func (w *Watcher) pollDelay(walked time.Duration) time.Duration {
+	return max(w.interval, watchBackoff*walked)
+}
+
+// snapshot records the current version of every watched file
+func (w *Watcher) snapshot(ctx context.Context) (map[string]fileStamp, error) {
+	files, err := w.project.WalkFiles(ctx, DefaultWalkOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stamps := make(map[string]fileStamp, len(files))
+	for _, file := range files {
+		if isBuildOutput(w.project, file.RelPath) {
+			continue
+		}
+		stamp := fileStamp{modTime: file.ModTime, size: file.Size}
+		if file.Size <= watchHashLimit && now.Sub(time.Unix(file.ModTime, 0)) < watchHashWindow {
+			stamp.hash = hashFile(file.Path)
+		}
+		stamps[file.Path] = stamp
+	}
+	return stamps, nil
+}
+
+// hashFile returns a hash of a file's content, or 0 if it cannot be read
+func hashFile(path string) uint64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	h := fnv.New64a()
+	if _, err := io.Copy(h, f); err != nil {
+		return 0
+	}
+	return h.Sum64()
+}
+
+// diffSnapshots returns the paths that differ between two snapshots
+func diffSnapshots(prev, cur map[string]fileStamp) []string {
+	var changed []string
+	for path, stamp := range cur {
+		if old, ok := prev[path]; !ok || old.differs(stamp) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// isBuildOutput reports whether a project-relative path is written by
+// building, so that rebuilding does not trigger the watcher again
+func isBuildOutput(project Project, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	name := project.Name()
+	return relPath == name || relPath == name+".exe" || strings.HasPrefix(relPath, MatrixOutputDir+"/")
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	base := fileStamp{modTime: 100, size: 10, hash: 1}
+
+	tests := []struct {
+		name      string
+		prev, cur map[string]fileStamp
+		want      []string
+	}{
+		{
+			name: "unchanged",
+			prev: map[string]fileStamp{"a": base, "b": {modTime: 50, size: 3}},
+			cur:  map[string]fileStamp{"a": base, "b": {modTime: 50, size: 3}},
+		},
+		{
+			name: "added and removed",
+			prev: map[string]fileStamp{"a": base, "gone": base},
+			cur:  map[string]fileStamp{"a": base, "new": base},
+			want: []string{"gone", "new"},
+		},
+		{
+			name: "modification time",
+			prev: map[string]fileStamp{"a": base},
+			cur:  map[string]fileStamp{"a": {modTime: 101, size: 10, hash: 1}},
+			want: []string{"a"},
+		},
+		{
+			name: "size",
+			prev: map[string]fileStamp{"a": base},
+			cur:  map[string]fileStamp{"a": {modTime: 100, size: 11, hash: 1}},
+			want: []string{"a"},
+		},
+		{
+			name: "content within the same second and size",
+			prev: map[string]fileStamp{"a": base},
+			cur:  map[string]fileStamp{"a": {modTime: 100, size: 10, hash: 2}},
+			want: []string{"a"},
+		},
+		{
+			name: "no longer recently modified",
+			prev: map[string]fileStamp{"a": base},
+			cur:  map[string]fileStamp{"a": {modTime: 100, size: 10}},
+		},
+		{
+			name: "newly hashed",
+			prev: map[string]fileStamp{"a": {modTime: 100, size: 10}},
+			cur:  map[string]fileStamp{"a": base},
+		},
+		{
+			name: "everything removed",
+			prev: map[string]fileStamp{"a": base, "b": base},
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffSnapshots(tt.prev, tt.cur)
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffSnapshots() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcherSnapshot(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":         "module example.com/w\n",
+		"main.go":        "package main\n",
+		"big.bin":        strings.Repeat("x", watchHashLimit+1),
+		"dist/w-linux":   "",
+		".git/HEAD":      "",
+		"cmd/old/old.go": "package main\n",
+	})
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "cmd", "old", "old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	project := NewGoProject(root, testFS{})
+	if err := os.WriteFile(filepath.Join(root, project.Name()), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stamps, err := NewWatcher(project, nil).snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	var paths []string
+	for path := range stamps {
+		rel, _ := filepath.Rel(root, path)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	slices.Sort(paths)
+	if want := []string{"big.bin", "cmd/old/old.go", "go.mod", "main.go"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("snapshot files = %q, want %q", paths, want)
+	}
+
+	// Only small, recently modified files are hashed
+	for rel, hashed := range map[string]bool{"main.go": true, "big.bin": false, "cmd/old/old.go": false} {
+		if got := stamps[filepath.Join(root, filepath.FromSlash(rel))].hash != 0; got != hashed {
+			t.Errorf("%s hashed = %t, want %t", rel, got, hashed)
+		}
+	}
+}
+
+func TestWatcherPollDelay(t *testing.T) {
+	w := &Watcher{interval: WatchInterval}
+	for _, tt := range []struct{ walked, want time.Duration }{
+		{0, WatchInterval},
+		{WatchInterval / watchBackoff, WatchInterval},
+		{time.Second, watchBackoff * time.Second},
+	} {
+		if got := w.pollDelay(tt.walked); got != tt.want {
+			t.Errorf("pollDelay(%v) = %v, want %v", tt.walked, got, tt.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":  "module example.com/w\n",
+		"main.go": "package main\n",
+	})
+	w := NewWatcher(NewGoProject(root, testFS{}), nil)
+	w.interval, w.debounce = 10*time.Millisecond, 50*time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	changes := make(chan []string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Watch(ctx, func(changed []string) {
+			changes <- changed
+			cancel()
+		})
+	}()
+
+	// Let the first snapshot be taken before changing the tree
+	time.Sleep(100 * time.Millisecond)
+	// The same size within the same second is only seen by hashing
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package demo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "util.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changed := <-changes:
+		want := []string{filepath.Join(root, "go.mod"), filepath.Join(root, "main.go"), filepath.Join(root, "util.go")}
+		if !reflect.DeepEqual(changed, want) {
+			t.Errorf("changed = %q, want %q", changed, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("no changes reported")
+	}
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Watch() = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,188 @@
+// Package core provides discovery of the modules of a Go workspace.
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WorkspaceModule is a module of a workspace
+type WorkspaceModule struct {
+	Path string // Module path declared in go.mod
+	Dir  string // Absolute directory containing go.mod
+}
+
+// Workspace lists the modules of a project: those used by its go.work
+// file, or else every go.mod found in the project tree
+type Workspace struct {
+	Root     string
+	WorkFile string // Path of go.work, empty if modules were discovered
+	Modules  []WorkspaceModule
+}
+
+// LoadWorkspace discovers the modules of the project at root. Discovery
+// walks the tree, fails past DefaultWalkMaxFiles and stops when ctx is
+// cancelled.
+func LoadWorkspace(ctx context.Context, fs FileSystem, root string) (*Workspace, error) {
+	ws := &Workspace{Root: root}
+
+	workFile := filepath.Join(root, "go.work")
+	data, err := fs.ReadFile(workFile)
+	switch {
+	case err == nil:
+		ws.WorkFile = workFile
+		for _, dir := range ParseGoWorkUses(data) {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(root, dir)
+			}
+			if mod, ok := readWorkspaceModule(fs, filepath.Clean(dir)); ok {
+				ws.Modules = append(ws.Modules, mod)
+			}
+		}
+	case os.IsNotExist(err):
+		if mod, ok := readWorkspaceModule(fs, root); ok {
+			ws.Modules = append(ws.Modules, mod)
+		}
+
+		ignore := NewIgnoreMatcher(root, fs.ReadFile)
+		goMods, err := Walk(ctx, fs, root, DefaultWalkOptions(), func(info *FileInfo) bool {
+			if info.IsDir {
+				return info.Name != "testdata" && !ignore.Ignored(info.Path, true)
+			}
+			return info.Name == "go.mod" && info.RelPath != "go.mod"
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, goMod := range goMods {
+			if mod, ok := readWorkspaceModule(fs, filepath.Dir(goMod.Path)); ok {
+				ws.Modules = append(ws.Modules, mod)
+			}
+		}
+	default:
+		return nil, err
+	}
+
+	sort.Slice(ws.Modules, func(i, j int) bool {
+		return ws.Modules[i].Dir < ws.Modules[j].Dir
+	})
+	return ws, nil
+}
+
+// readWorkspaceModule reads the module in dir, if dir has a go.mod
+func readWorkspaceModule(fs FileSystem, dir string) (WorkspaceModule, bool) {
+	data, err := fs.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return WorkspaceModule{}, false
+	}
+	return WorkspaceModule{Path: ParseModulePath(data), Dir: dir}, true
+}
+
+// ModuleForFile returns the innermost module whose directory contains
+// path, which may be a file or a directory
+func (w *Workspace) ModuleForFile(path string) (WorkspaceModule, bool) {
+	var (
+		best  WorkspaceModule
+		found bool
+	)
+	for _, mod := range w.Modules {
+		if path != mod.Dir && !strings.HasPrefix(path, mod.Dir+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(mod.Dir) > len(best.Dir) {
+			best, found = mod, true
+		}
+	}
+	return best, found
+}
+
+// ParseModulePath returns the module path declared by a go.mod file
+func ParseModulePath(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := goModFields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// ParseGoWorkUses returns the directories of the use directives of a
+// go.work file, in both the single-line and the block form
+func ParseGoWorkUses(data []byte) []string {
+	var (
+		dirs  []string
+		inUse bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := goModFields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+		case inUse && fields[0] == ")":
+			inUse = false
+		case inUse:
+			dirs = append(dirs, fields[0])
+		case fields[0] == "use" && len(fields) == 2 && fields[1] == "(":
+			inUse = true
+		case fields[0] == "use" && len(fields) >= 2:
+			dirs = append(dirs, fields[1])
+		}
+	}
+	return dirs
+}
+
+// goModFields splits a go.mod or go.work line into fields, dropping
+// comments and unquoting quoted strings
+func goModFields(line string) []string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if unquoted, err := strconv.Unquote(field); err == nil {
+			fields[i] = unquoted
+		}
+	}
+	return fields
+}
+
+// ModuleTrees returns the file tree of the project followed by the trees
+// of the workspace modules in other directories, whose roots are named
+// after their module paths. Each tree leaves out nested modules.
+func ModuleTrees(ctx context.Context, project Project) ([]TreeNode, error) {
+	root, err := project.FileTree()
+	if err != nil {
+		return nil, err
+	}
+	trees := []TreeNode{root}
+
+	ws, err := project.Workspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, mod := range ws.Modules {
+		if mod.Dir == project.Path() {
+			continue
+		}
+
+		tree, err := project.ForModule(ctx, mod.Dir).FileTree()
+		if err != nil {
+			return nil, err
+		}
+		if mod.Path != "" {
+			tree.File.Name = mod.Path
+		}
+		trees = append(trees, tree)
+	}
+	return trees, nil
+}
@@ -0,0 +1,169 @@
+package gui
+
+import (
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"gox-ide/pkg/core"
+)
+
+// indirectModuleColor dims indirect dependencies
+var indirectModuleColor = color.NRGBA{R: 130, G: 130, B: 130, A: 255}
+
+// DependencyPanelImpl implements DependencyPanel interface
+type DependencyPanelImpl struct {
+	id             string
+	list           widget.List
+	graph          *core.ModuleGraph
+	modules        []*core.GoModule
+	buttons        []widget.Clickable
+	selectedIdx    int
+	message        string
+	onModuleSelect func(module *core.GoModule)
+}
+
+// NewDependencyPanel creates a new dependency panel component
+func NewDependencyPanel() *DependencyPanelImpl {
+	return &DependencyPanelImpl{
+		id:          "dependency-panel",
+		selectedIdx: -1,
+		message:     "Loading dependencies...",
+		list: widget.List{
+			List: layout.List{
+				Axis: layout.Vertical,
+			},
+		},
+	}
+}
+
+// ID returns the component ID
+func (p *DependencyPanelImpl) ID() string {
+	return p.id
+}
+
+// SetGraph shows the dependencies of a module graph; nil shows message
+// instead, e.g. while loading or after an error
+func (p *DependencyPanelImpl) SetGraph(graph *core.ModuleGraph, message string) {
+	p.graph = graph
+	p.modules = nil
+	if graph != nil {
+		p.modules = graph.Dependencies()
+	}
+	p.buttons = make([]widget.Clickable, len(p.modules))
+	p.selectedIdx = -1
+	p.message = message
+	if graph != nil && len(p.modules) == 0 {
+		p.message = "No dependencies"
+	}
+}
+
+// Graph returns the module graph shown, or nil
+func (p *DependencyPanelImpl) Graph() *core.ModuleGraph {
+	return p.graph
+}
+
+// SetOnModuleSelect sets the callback for module selection
+func (p *DependencyPanelImpl) SetOnModuleSelect(callback func(module *core.GoModule)) {
+	p.onModuleSelect = callback
+}
+
+// Update processes events and updates component state
+func (p *DependencyPanelImpl) Update(gtx layout.Context) bool {
+	changed := false
+
+	for i := range p.buttons {
+		if p.buttons[i].Clicked(gtx) {
+			p.selectedIdx = i
+			if p.onModuleSelect != nil {
+				p.onModuleSelect(p.modules[i])
+			}
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Layout renders the dependency panel
+func (p *DependencyPanelImpl) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	// Update state
+	p.Update(gtx)
+
+	// Draw background
+	bg := color.NRGBA{R: 245, G: 245, B: 240, A: 255}
+	paint.FillShape(gtx.Ops, bg, clip.Rect{Max: gtx.Constraints.Max}.Op())
+
+	return layout.Inset{
+		Top: unit.Dp(8), Bottom: unit.Dp(8),
+		Left: unit.Dp(8), Right: unit.Dp(8),
+	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{
+			Axis: layout.Vertical,
+		}.Layout(gtx,
+			// Title
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				label := material.Caption(theme, "📦 Dependencies")
+				label.Color = color.NRGBA{R: 100, G: 100, B: 100, A: 255}
+				return layout.Inset{Bottom: unit.Dp(4)}.Layout(gtx, label.Layout)
+			}),
+
+			// Modules
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				if len(p.modules) == 0 {
+					label := material.Body2(theme, p.message)
+					label.Color = indirectModuleColor
+					return label.Layout(gtx)
+				}
+				return material.List(theme, &p.list).Layout(gtx, len(p.modules), func(gtx layout.Context, i int) layout.Dimensions {
+					return p.layoutModule(gtx, theme, i)
+				})
+			}),
+		)
+	})
+}
+
+// layoutModule renders a single module with its version
+func (p *DependencyPanelImpl) layoutModule(gtx layout.Context, theme *material.Theme, index int) layout.Dimensions {
+	mod := p.modules[index]
+
+	return p.buttons[index].Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		// Handle selection highlighting
+		if index == p.selectedIdx {
+			selectionBG := color.NRGBA{R: 173, G: 216, B: 230, A: 255}
+			paint.FillShape(gtx.Ops, selectionBG, clip.Rect{Max: gtx.Constraints.Max}.Op())
+		}
+
+		return layout.Inset{Top: unit.Dp(2), Bottom: unit.Dp(2)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{
+				Axis: layout.Vertical,
+			}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					label := material.Body2(theme, mod.Path)
+					label.Color = theme.Fg
+					if mod.Indirect {
+						label.Color = indirectModuleColor
+					}
+					return label.Layout(gtx)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					version := mod.Version
+					if mod.Indirect {
+						version += " // indirect"
+					}
+					if mod.Replace != nil {
+						version += " => " + mod.Replace.Path
+					}
+					label := material.Caption(theme, version)
+					label.Color = indirectModuleColor
+					return label.Layout(gtx)
+				}),
+			)
+		})
+	})
+}
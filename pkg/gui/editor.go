@@ -3,11 +3,16 @@ package gui
 
 import (
 	"errors"
+	"image"
 	"image/color"
 	"os"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
 	"gioui.org/layout"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
@@ -26,6 +31,14 @@ const (
 var (
 	ErrFileTooLarge = errors.New("file too large to open in editor")
 	
+	// Gutter mark colors
+	errorMarkColor      = color.NRGBA{R: 211, G: 47, B: 47, A: 255}
+	warningMarkColor    = color.NRGBA{R: 245, G: 124, B: 0, A: 255}
+	coveredMarkColor    = color.NRGBA{R: 102, G: 187, B: 106, A: 255}
+	uncoveredMarkColor  = color.NRGBA{R: 239, G: 154, B: 154, A: 255}
+	breakpointMarkColor = color.NRGBA{R: 142, G: 36, B: 170, A: 255}
+	executionMarkColor  = color.NRGBA{R: 255, G: 193, B: 7, A: 255}
+
 	// Performance: Pool of strings.Builder for reducing allocations
 	builderPool = sync.Pool{
 		New: func() interface{} {
@@ -41,10 +54,23 @@ type TextEditorImpl struct {
 	currentFile *core.FileInfo
 	dirty       bool
 	onChange    func()
+	focus       bool // Focus the editor on next layout
+
+	// Go to definition, requested by F12 or Ctrl+click
+	onDefinition    func(line, col int)
+	definitionClick bool // A Ctrl+click is moving the caret
 	
 	// Performance optimization: cache line count
 	cachedContent string
 	cachedLineCount int
+
+	// Gutter annotations
+	diagnostics []core.Diagnostic
+	coverage    *core.CoverageReport
+	breakpoints []core.Breakpoint
+	execution   *core.StackFrame
+	profile     *core.ProfileReport
+	lineMarks   map[int]color.NRGBA
 }
 
 // NewTextEditor creates a new text editor component
@@ -83,9 +109,11 @@ func (te *TextEditorImpl) OpenFile(file *core.FileInfo) error {
 
 	// Set content and file
 	te.editor.SetText(string(content))
+	te.editor.ReadOnly = file.Generated
 	te.currentFile = file
 	te.dirty = false
 	te.invalidateCache() // Clear cache for new file
+	te.updateLineMarks()
 
 	return nil
 }
@@ -133,11 +161,183 @@ func (te *TextEditorImpl) SetOnChange(callback func()) {
 	te.onChange = callback
 }
 
+// SetReadOnly sets whether the content can be edited
+func (te *TextEditorImpl) SetReadOnly(readOnly bool) {
+	te.editor.ReadOnly = readOnly
+}
+
+// IsReadOnly returns true if the content cannot be edited
+func (te *TextEditorImpl) IsReadOnly() bool {
+	return te.editor.ReadOnly
+}
+
 // GetCurrentFile returns the currently open file
 func (te *TextEditorImpl) GetCurrentFile() *core.FileInfo {
 	return te.currentFile
 }
 
+// GetCursorPosition returns the 1-based line and column of the caret
+func (te *TextEditorImpl) GetCursorPosition() (line, col int) {
+	line, col = te.editor.CaretPos()
+	return line + 1, col + 1
+}
+
+// GoToPosition moves the caret to a 1-based line and byte column, as
+// reported by the Go toolchain, scrolls it into view and focuses the
+// editor
+func (te *TextEditorImpl) GoToPosition(line, col int) {
+	// The widget addresses text in runes
+	text := te.editor.Text()
+	offset := 0
+	for ; line > 1; line-- {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			break
+		}
+		offset += utf8.RuneCountInString(text[:i+1])
+		text = text[i+1:]
+	}
+	if end := strings.IndexByte(text, '\n'); end >= 0 {
+		text = text[:end]
+	}
+	offset += utf8.RuneCountInString(text[:min(max(col-1, 0), len(text))])
+
+	te.editor.SetCaret(offset, offset)
+	te.focus = true
+}
+
+// SetOnDefinition sets the callback for go to definition requests, called
+// with the 1-based line and byte column of the caret
+func (te *TextEditorImpl) SetOnDefinition(callback func(line, col int)) {
+	te.onDefinition = callback
+}
+
+// updateDefinitionRequests handles F12 and notes Ctrl+clicks, which the
+// editor widget also sees and moves the caret for
+func (te *TextEditorImpl) updateDefinitionRequests(gtx layout.Context) {
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: &te.editor, Name: key.NameF12},
+			pointer.Filter{Target: te, Kinds: pointer.Press},
+		)
+		if !ok {
+			break
+		}
+		switch e := ev.(type) {
+		case key.Event:
+			if e.State == key.Press {
+				te.requestDefinition()
+			}
+		case pointer.Event:
+			if e.Buttons == pointer.ButtonPrimary && e.Modifiers.Contain(key.ModShortcut) {
+				te.definitionClick = true
+			}
+		}
+	}
+}
+
+// requestDefinition reports the caret position to the definition callback
+func (te *TextEditorImpl) requestDefinition() {
+	if te.onDefinition == nil || te.currentFile == nil {
+		return
+	}
+
+	// The widget counts columns in runes, the Go toolchain in bytes
+	line, col := te.editor.CaretPos()
+	text := te.editor.Text()
+	for i := 0; i < line; i++ {
+		next := strings.IndexByte(text, '\n')
+		if next < 0 {
+			break
+		}
+		text = text[next+1:]
+	}
+	offset := 0
+	for ; col > 0 && offset < len(text); col-- {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	te.onDefinition(line+1, offset+1)
+}
+
+// SetDiagnostics sets diagnostics to highlight in the gutter
+func (te *TextEditorImpl) SetDiagnostics(diags []core.Diagnostic) {
+	te.diagnostics = diags
+	te.updateLineMarks()
+}
+
+// SetCoverage sets coverage to color in the line-number gutter
+func (te *TextEditorImpl) SetCoverage(report *core.CoverageReport) {
+	te.coverage = report
+	te.updateLineMarks()
+}
+
+// SetBreakpoints sets the debugger breakpoints to mark in the gutter
+func (te *TextEditorImpl) SetBreakpoints(breakpoints []core.Breakpoint) {
+	te.breakpoints = breakpoints
+	te.updateLineMarks()
+}
+
+// SetExecutionLine marks the line of the frame the debugged program
+// stopped in; nil clears it
+func (te *TextEditorImpl) SetExecutionLine(frame *core.StackFrame) {
+	te.execution = frame
+	te.updateLineMarks()
+}
+
+// SetProfile sets a profile whose per-line costs are shown next to the
+// line numbers; nil hides them
+func (te *TextEditorImpl) SetProfile(report *core.ProfileReport) {
+	te.profile = report
+}
+
+// updateLineMarks recomputes the gutter marks for the current file.
+// Diagnostics are drawn over coverage, and debugger marks over both.
+func (te *TextEditorImpl) updateLineMarks() {
+	te.lineMarks = nil
+	if te.currentFile == nil {
+		return
+	}
+
+	te.lineMarks = make(map[int]color.NRGBA)
+
+	if fileCoverage := te.coverage.File(te.currentFile.Path); fileCoverage != nil {
+		for line, state := range fileCoverage.Lines() {
+			switch state {
+			case core.CoverCovered:
+				te.lineMarks[line] = coveredMarkColor
+			case core.CoverUncovered:
+				te.lineMarks[line] = uncoveredMarkColor
+			}
+		}
+	}
+
+	for _, d := range te.diagnostics {
+		if d.File != te.currentFile.Path {
+			continue
+		}
+
+		// Errors take precedence over warnings on the same line
+		if existing, ok := te.lineMarks[d.Line]; ok && existing == errorMarkColor {
+			continue
+		}
+		if d.Severity == core.SeverityError {
+			te.lineMarks[d.Line] = errorMarkColor
+		} else {
+			te.lineMarks[d.Line] = warningMarkColor
+		}
+	}
+
+	for _, bp := range te.breakpoints {
+		if bp.File == te.currentFile.Path {
+			te.lineMarks[bp.Line] = breakpointMarkColor
+		}
+	}
+	if te.execution != nil && te.execution.File == te.currentFile.Path {
+		te.lineMarks[te.execution.Line] = executionMarkColor
+	}
+}
+
 // Update processes events and updates component state
 func (te *TextEditorImpl) Update(gtx layout.Context) bool {
 	// For now, we'll check for changes in the Layout method
@@ -203,9 +403,30 @@ func (te *TextEditorImpl) layoutEditor(gtx layout.Context, theme *material.Theme
 
 		// Editor content
 		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if te.focus {
+				gtx.Execute(key.FocusCmd{Tag: &te.editor})
+				te.focus = false
+			}
+			te.updateDefinitionRequests(gtx)
+
 			ed := material.Editor(theme, &te.editor, "")
 			ed.Color = theme.Fg
-			return ed.Layout(gtx)
+			dims := ed.Layout(gtx)
+
+			// The editor has moved the caret to the Ctrl+clicked position
+			if te.definitionClick {
+				te.definitionClick = false
+				te.requestDefinition()
+			}
+
+			// Watch for Ctrl+clicks, passing them on to the editor
+			pass := pointer.PassOp{}.Push(gtx.Ops)
+			area := clip.Rect{Max: dims.Size}.Push(gtx.Ops)
+			event.Op(gtx.Ops, te)
+			area.Pop()
+			pass.Pop()
+
+			return dims
 		}),
 	)
 }
@@ -243,36 +464,78 @@ func (te *TextEditorImpl) layoutLineNumbers(gtx layout.Context, theme *material.
 		lnBg := color.NRGBA{R: 245, G: 245, B: 245, A: 255}
 		paint.FillShape(gtx.Ops, lnBg, clip.Rect{Max: gtx.Constraints.Max}.Op())
 
-		return layout.Inset{
+		inset := layout.Inset{
 			Top: unit.Dp(4), Bottom: unit.Dp(4),
 			Left: unit.Dp(8), Right: unit.Dp(8),
-		}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		}
+		dims := inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 
 			// Create line number text using pooled builder for performance
 			lineNumbers := builderPool.Get().(*strings.Builder)
 			lineNumbers.Reset()
 			defer builderPool.Put(lineNumbers)
 			
+			var lineCosts map[int]int64
+			if te.profile != nil && te.currentFile != nil {
+				lineCosts = te.profile.FileLines(te.currentFile.Path)
+			}
+
 			for i := 1; i <= lineCount; i++ {
 				if i > 1 {
 					lineNumbers.WriteString("\n")
 				}
 				lineNumbers.WriteString(strings.ReplaceAll(sprintf("%4d", i), " ", " "))
+				if cost := lineCosts[i]; cost > 0 {
+					lineNumbers.WriteString(" " + te.profile.Format(cost))
+				}
 			}
 
 			// Render line numbers
 			lnLabel := material.Body2(theme, lineNumbers.String())
 			lnLabel.Color = color.NRGBA{R: 150, G: 150, B: 150, A: 255}
 
-			// Constrain width for line numbers
-			gtx.Constraints.Max.X = gtx.Dp(unit.Dp(60))
+			// Constrain width for line numbers, widened for profile costs
+			width := unit.Dp(60)
+			if len(lineCosts) > 0 {
+				width = unit.Dp(130)
+			}
+			gtx.Constraints.Max.X = gtx.Dp(width)
 			gtx.Constraints.Min.X = gtx.Constraints.Max.X
 
 			return lnLabel.Layout(gtx)
 		})
+
+		te.layoutLineMarks(gtx, dims, inset, lineCount)
+		return dims
 	})
 }
 
+// layoutLineMarks paints a colored bar in the gutter next to each marked line
+func (te *TextEditorImpl) layoutLineMarks(gtx layout.Context, dims layout.Dimensions, inset layout.Inset, lineCount int) {
+	if len(te.lineMarks) == 0 || lineCount == 0 {
+		return
+	}
+
+	top := gtx.Dp(inset.Top)
+	textHeight := dims.Size.Y - top - gtx.Dp(inset.Bottom)
+	lineHeight := textHeight / lineCount
+	if lineHeight <= 0 {
+		return
+	}
+
+	width := gtx.Dp(unit.Dp(4))
+	for line, markColor := range te.lineMarks {
+		if line < 1 || line > lineCount {
+			continue
+		}
+		y := top + (line-1)*lineHeight
+		paint.FillShape(gtx.Ops, markColor, clip.Rect{
+			Min: image.Point{X: 0, Y: y},
+			Max: image.Point{X: width, Y: y + lineHeight},
+		}.Op())
+	}
+}
+
 // markDirty marks the editor content as modified
 func (te *TextEditorImpl) markDirty() {
 	if !te.dirty {
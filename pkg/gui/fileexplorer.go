@@ -1,7 +1,10 @@
 package gui
 
 import (
+	"context"
 	"image/color"
+	"path/filepath"
+	"sync"
 
 	"gioui.org/layout"
 	"gioui.org/op/clip"
@@ -13,42 +16,61 @@ import (
 	"gox-ide/pkg/core"
 )
 
-// FileExplorerImpl implements FileExplorer interface
+// generatedFileColor dims generated files, which should not be edited
+var generatedFileColor = color.NRGBA{R: 140, G: 140, B: 140, A: 255}
+
+// excludedFileColor dims files the build context excludes
+var excludedFileColor = color.NRGBA{R: 175, G: 175, B: 175, A: 255}
+
+// FileExplorerImpl implements FileExplorer interface. Directories are
+// read on first expansion on background goroutines and cached in a
+// core.LazyTree, so large projects open without walking the whole tree.
 type FileExplorerImpl struct {
 	id           string
 	project      core.Project
-	tree         core.TreeNode
+	tree         *core.LazyTree
 	list         widget.List
 	items        []ExplorerItem
-	selectedIdx  int
+	expanded     map[string]bool              // Expanded directories by path
+	buttons      map[string]*widget.Clickable // By path, so clicks survive rebuilds
+	selectedPath string
 	onFileSelect func(file *core.FileInfo)
+
+	// Loaded entries are written from background goroutines
+	mu         sync.Mutex
+	buildCtx   core.BuildContext
+	roots      []core.FileInfo            // nil until loaded
+	entries    map[string][]core.FileInfo // Loaded directories, marked for buildCtx
+	loading    map[string]bool            // Directories being read; "" for the roots
+	errs       map[string]error           // Why directories failed to load; "" for the roots
+	dirty      bool                       // Entries changed since items were built
+	invalidate func()
+	loadCtx    context.Context // Cancelled when the project changes
+	stopLoads  context.CancelFunc
 }
 
 // ExplorerItem represents an item in the file explorer
 type ExplorerItem struct {
-	File     *core.FileInfo
-	Button   widget.Clickable
-	Level    int
-	IsLast   bool
-	Expanded bool
+	File        *core.FileInfo
+	Button      *widget.Clickable
+	Level       int
+	IsLast      bool
+	Expanded    bool
+	Placeholder bool // Shown while a directory loads or if it failed to load
 }
 
 // NewFileExplorer creates a new file explorer component
 func NewFileExplorer(project core.Project) *FileExplorerImpl {
 	fe := &FileExplorerImpl{
-		id:          "file-explorer",
-		project:     project,
-		selectedIdx: -1,
+		id:       "file-explorer",
+		buildCtx: core.HostBuildContext(),
 		list: widget.List{
 			List: layout.List{
 				Axis: layout.Vertical,
 			},
 		},
 	}
-
-	if project != nil {
-		fe.loadFileTree()
-	}
+	fe.SetProject(project)
 
 	return fe
 }
@@ -58,16 +80,60 @@ func (fe *FileExplorerImpl) ID() string {
 	return fe.id
 }
 
-// SetProject sets the project to display
+// SetProject sets the project to display, starting with every directory
+// collapsed
 func (fe *FileExplorerImpl) SetProject(project core.Project) {
+	var tree *core.LazyTree
+	if project != nil {
+		tree = core.NewLazyTree(project)
+	}
 	fe.project = project
-	fe.loadFileTree()
+	fe.expanded = make(map[string]bool)
+	fe.buttons = make(map[string]*widget.Clickable)
+	fe.selectedPath = ""
+
+	fe.mu.Lock()
+	if fe.stopLoads != nil {
+		fe.stopLoads()
+	}
+	fe.loadCtx, fe.stopLoads = context.WithCancel(context.Background())
+	fe.tree = tree
+	fe.roots = nil
+	fe.entries = make(map[string][]core.FileInfo)
+	fe.loading = make(map[string]bool)
+	fe.errs = make(map[string]error)
+	fe.mu.Unlock()
+
+	fe.rebuildItems()
+}
+
+// SetBuildContext sets the build context whose excluded files are dimmed
+func (fe *FileExplorerImpl) SetBuildContext(ctx core.BuildContext) {
+	fe.mu.Lock()
+	fe.buildCtx = ctx
+	for _, entries := range fe.entries {
+		ctx.MarkFiles(entries)
+	}
+	fe.mu.Unlock()
+
+	fe.rebuildItems()
+}
+
+// SetOnInvalidate sets the callback used to request a redraw when a
+// directory has loaded
+func (fe *FileExplorerImpl) SetOnInvalidate(callback func()) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.invalidate = callback
 }
 
 // GetSelectedFile returns the currently selected file
 func (fe *FileExplorerImpl) GetSelectedFile() *core.FileInfo {
-	if fe.selectedIdx >= 0 && fe.selectedIdx < len(fe.items) {
-		return fe.items[fe.selectedIdx].File
+	for i := range fe.items {
+		if !fe.items[i].Placeholder && fe.items[i].File.Path == fe.selectedPath {
+			return fe.items[i].File
+		}
 	}
 	return nil
 }
@@ -77,27 +143,81 @@ func (fe *FileExplorerImpl) SetOnFileSelect(callback func(file *core.FileInfo))
 	fe.onFileSelect = callback
 }
 
-// Refresh reloads the file tree
+// Refresh reloads the roots and every loaded directory in the background,
+// keeping the current entries until they are replaced
 func (fe *FileExplorerImpl) Refresh() error {
-	fe.loadFileTree()
+	if fe.tree == nil {
+		return nil
+	}
+	fe.tree.Reset()
+
+	fe.mu.Lock()
+	dirs := make([]string, 0, len(fe.entries))
+	for dir := range fe.entries {
+		dirs = append(dirs, dir)
+	}
+	fe.mu.Unlock()
+
+	fe.load("")
+	for _, dir := range dirs {
+		fe.load(dir)
+	}
 	return nil
 }
 
+// RefreshPaths reloads the loaded directories containing changed files.
+// A changed go.mod or go.work file reloads everything, since modules
+// may have been added or removed.
+func (fe *FileExplorerImpl) RefreshPaths(paths []string) {
+	if fe.tree == nil {
+		return
+	}
+
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if name := filepath.Base(path); name == "go.mod" || name == "go.work" {
+			fe.Refresh()
+			return
+		}
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		fe.tree.Invalidate(dir)
+
+		fe.mu.Lock()
+		_, loaded := fe.entries[dir]
+		fe.mu.Unlock()
+		if loaded {
+			fe.load(dir)
+		}
+	}
+}
+
 // Update processes events and updates component state
 func (fe *FileExplorerImpl) Update(gtx layout.Context) bool {
 	changed := false
 
+	// Pick up directories loaded in the background
+	fe.mu.Lock()
+	dirty := fe.dirty
+	fe.mu.Unlock()
+	if dirty {
+		fe.rebuildItems()
+		changed = true
+	}
+
 	// Handle item clicks
 	for i := range fe.items {
 		item := &fe.items[i]
 
-		if item.Button.Clicked(gtx) {
-			fe.selectedIdx = i
+		if !item.Placeholder && item.Button.Clicked(gtx) {
+			fe.selectedPath = item.File.Path
 
 			if item.File.IsDir {
-				// Toggle directory expansion
-				item.Expanded = !item.Expanded
+				// Toggle directory expansion; children load on demand
+				fe.expanded[item.File.Path] = !item.Expanded
 				fe.rebuildItems()
+				break
 			} else {
 				// File selection
 				if fe.onFileSelect != nil {
@@ -148,15 +268,16 @@ func (fe *FileExplorerImpl) layoutItem(gtx layout.Context, theme *material.Theme
 	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 
 		// Handle selection highlighting
-		if index == fe.selectedIdx {
+		if !item.Placeholder && item.File.Path == fe.selectedPath {
 			selectionBG := color.NRGBA{R: 173, G: 216, B: 230, A: 255}
 			paint.FillShape(gtx.Ops, selectionBG, clip.Rect{Max: gtx.Constraints.Max}.Op())
 		}
 
-		// Create button for the item
-		btn := material.Button(theme, &item.Button, "")
-		btn.Background = color.NRGBA{} // Transparent
-		btn.Color = theme.Fg
+		if item.Placeholder {
+			label := material.Body2(theme, item.File.Name)
+			label.Color = excludedFileColor
+			return layout.Inset{Left: unit.Dp(24)}.Layout(gtx, label.Layout)
+		}
 
 		return layout.Flex{
 			Axis:      layout.Horizontal,
@@ -179,9 +300,16 @@ func (fe *FileExplorerImpl) layoutItem(gtx layout.Context, theme *material.Theme
 					name = "▶ " + name
 				}
 
-				btn := material.Button(theme, &item.Button, name)
+				btn := material.Button(theme, item.Button, name)
 				btn.Background = color.NRGBA{} // Transparent
 				btn.Color = theme.Fg
+				if item.File.Generated {
+					btn.Text = name + " (generated)"
+					btn.Color = generatedFileColor
+				}
+				if item.File.BuildExcluded {
+					btn.Color = excludedFileColor
+				}
 				return btn.Layout(gtx)
 			}),
 		)
@@ -191,55 +319,180 @@ func (fe *FileExplorerImpl) layoutItem(gtx layout.Context, theme *material.Theme
 // getFileIcon returns an appropriate icon for the file type
 func (fe *FileExplorerImpl) getFileIcon(file *core.FileInfo) string {
 	if file.IsDir {
+		if fe.tree != nil && fe.tree.IsModule(file.Path) {
+			return "📦"
+		}
 		return "📁"
 	}
 
 	return core.GetIconForLanguage(file.Language)
 }
 
-// loadFileTree loads the file tree from the project
-func (fe *FileExplorerImpl) loadFileTree() {
-	if fe.project == nil {
-		fe.items = nil
+// load reads a directory, or the roots for "", on a background
+// goroutine unless it is already being read
+func (fe *FileExplorerImpl) load(dir string) {
+	fe.mu.Lock()
+	if fe.loading[dir] || fe.tree == nil {
+		fe.mu.Unlock()
 		return
 	}
+	fe.loading[dir] = true
+	ctx, tree, buildCtx := fe.loadCtx, fe.tree, fe.buildCtx
+	fe.mu.Unlock()
+
+	go func() {
+		var (
+			entries []core.FileInfo
+			err     error
+		)
+		if dir == "" {
+			entries, err = tree.Roots(ctx)
+		} else {
+			entries, err = tree.Children(ctx, dir)
+		}
+		// Errors are shown in place of the entries
+		loaded := make([]core.FileInfo, 0, len(entries))
+		if err == nil {
+			loaded = append(loaded, entries...)
+			if dir != "" {
+				buildCtx.MarkFiles(loaded)
+			}
+		}
+
+		// Reading the roots reads the project directory too, which is
+		// shown at the top level
+		var top []core.FileInfo
+		topLoaded := false
+		if dir == "" && len(loaded) > 0 {
+			if entries, ok := tree.Cached(loaded[0].Path); ok {
+				top = append([]core.FileInfo(nil), entries...)
+				buildCtx.MarkFiles(top)
+				topLoaded = true
+			}
+		}
+
+		// Listing a directory may find modules, which become roots
+		var roots []core.FileInfo
+		if dir != "" && err == nil {
+			roots, _ = tree.Roots(ctx)
+		}
+
+		fe.mu.Lock()
+		delete(fe.loading, dir)
+		if tree != fe.tree {
+			// The project changed meanwhile
+			fe.mu.Unlock()
+			return
+		}
+		if err != nil {
+			fe.errs[dir] = err
+		} else {
+			delete(fe.errs, dir)
+		}
+		if dir == "" {
+			fe.roots = loaded
+		} else {
+			fe.entries[dir] = loaded
+		}
+		if roots != nil {
+			fe.roots = roots
+		}
+		if topLoaded {
+			fe.entries[loaded[0].Path] = top
+			delete(fe.errs, loaded[0].Path)
+		}
+		fe.dirty = true
+		invalidate := fe.invalidate
+		fe.mu.Unlock()
+
+		if invalidate != nil {
+			invalidate()
+		}
+	}()
+}
 
-	tree, err := fe.project.FileTree()
+// rebuildItems rebuilds the flat list of visible items from the loaded
+// directories, starting loads for expanded directories not yet read
+func (fe *FileExplorerImpl) rebuildItems() {
+	fe.mu.Lock()
+	fe.dirty = false
+	roots, err := fe.roots, fe.errs[""]
+	fe.mu.Unlock()
+
+	fe.items = nil
+	if fe.tree == nil {
+		return
+	}
 	if err != nil {
-		fe.items = nil
+		fe.addPlaceholder(0, "⚠️ "+err.Error())
+		return
+	}
+	if roots == nil {
+		fe.load("")
+		fe.addPlaceholder(0, "Loading...")
+		return
+	}
+	if len(roots) == 0 {
 		return
 	}
 
-	fe.tree = tree
-	fe.rebuildItems()
+	// The project's entries are top level, followed by the roots of the
+	// other modules named after their module paths
+	fe.addDir(roots[0].Path, 0)
+	for i, root := range roots[1:] {
+		fe.addItem(root, 0, i == len(roots)-2)
+	}
 }
 
-// rebuildItems rebuilds the flat list from the tree structure
-func (fe *FileExplorerImpl) rebuildItems() {
-	fe.items = nil
-	if fe.tree.File.Name != "" {
-		fe.addTreeNode(fe.tree, 0, true)
+// addDir adds the entries of a directory, or a placeholder while it loads
+// or if it failed to load
+func (fe *FileExplorerImpl) addDir(dir string, level int) {
+	fe.mu.Lock()
+	entries, ok := fe.entries[dir]
+	err := fe.errs[dir]
+	fe.mu.Unlock()
+
+	if err != nil {
+		fe.addPlaceholder(level, "⚠️ "+err.Error())
+		return
+	}
+	if !ok {
+		fe.load(dir)
+		fe.addPlaceholder(level, "Loading...")
+		return
+	}
+	for i, entry := range entries {
+		fe.addItem(entry, level, i == len(entries)-1)
 	}
 }
 
-// addTreeNode adds a tree node and its children to the flat list
-func (fe *FileExplorerImpl) addTreeNode(node core.TreeNode, level int, isLast bool) {
-	// Skip the root node (project name)
-	if level > 0 {
-		item := ExplorerItem{
-			File:     &node.File,
-			Level:    level - 1,
-			IsLast:   isLast,
-			Expanded: true, // Default to expanded for now
-		}
-		fe.items = append(fe.items, item)
+// addItem adds an entry and, if it is an expanded directory, its children
+func (fe *FileExplorerImpl) addItem(file core.FileInfo, level int, isLast bool) {
+	button, ok := fe.buttons[file.Path]
+	if !ok {
+		button = new(widget.Clickable)
+		fe.buttons[file.Path] = button
 	}
 
-	// Add children if directory is expanded
-	if node.File.IsDir {
-		for i, child := range node.Children {
-			isLastChild := i == len(node.Children)-1
-			fe.addTreeNode(child, level+1, isLastChild)
-		}
+	expanded := file.IsDir && fe.expanded[file.Path]
+	fe.items = append(fe.items, ExplorerItem{
+		File:     &file,
+		Button:   button,
+		Level:    level,
+		IsLast:   isLast,
+		Expanded: expanded,
+	})
+	if expanded {
+		fe.addDir(file.Path, level+1)
 	}
 }
+
+// addPlaceholder adds an item showing a message, such as "Loading..."
+func (fe *FileExplorerImpl) addPlaceholder(level int, message string) {
+	fe.items = append(fe.items, ExplorerItem{
+		File:        &core.FileInfo{Name: message},
+		Level:       level,
+		IsLast:      true,
+		Placeholder: true,
+	})
+}
@@ -2,8 +2,13 @@ package gui
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"gox-ide/pkg/core"
 )
@@ -19,11 +24,24 @@ var (
 
 // IDEApp integrates the GUI with the core IDE functionality
 type IDEApp struct {
-	config  IDEConfig
-	window  IDEWindow
-	project core.Project
-	builder core.Builder
-	logger  core.Logger
+	config    IDEConfig
+	window    IDEWindow
+	project   core.Project
+	builder   core.Builder
+	logger    core.Logger
+	processes *core.ProcessManager
+	debugger  *core.Debugger
+
+	// ID of the program started by watch mode, which it restarts
+	watchProcess atomic.Int64
+
+	// Cancels the debug launch in progress
+	launchMu     sync.Mutex
+	cancelLaunch context.CancelFunc
+
+	// Module owning the open file, which commands operate on
+	moduleMu sync.Mutex
+	module   core.Project
 }
 
 // NewIDEApp creates a new GUI IDE application
@@ -38,9 +56,11 @@ func NewIDEApp(project core.Project, builder core.Builder, logger core.Logger) *
 // NewIDEAppWithConfig creates a new GUI IDE application with custom configuration
 func NewIDEAppWithConfig(config IDEConfig) *IDEApp {
 	app := &IDEApp{
-		project: config.Project,
-		builder: config.Builder,
-		logger:  config.Logger,
+		project:   config.Project,
+		builder:   config.Builder,
+		logger:    config.Logger,
+		processes: core.NewProcessManager(config.Builder, config.Logger),
+		debugger:  core.NewDebugger(config.Logger),
 	}
 
 	// Create event handler if not provided
@@ -60,6 +80,8 @@ func NewIDEAppWithConfig(config IDEConfig) *IDEApp {
 
 	// Create window with loose coupling
 	app.window = NewWindow(app.config)
+	app.processes.SetOnExit(app.onProcessExit)
+	app.debugger.SetOnEvent(app.onDebugEvent)
 
 	return app
 }
@@ -73,10 +95,129 @@ func (app *IDEApp) Run(ctx context.Context) error {
 	// Set initial project
 	app.window.SetProject(app.project)
 
+	// Programs started from the IDE do not outlive it
+	defer app.processes.StopAll()
+	defer func() {
+		app.cancelDebugLaunch()
+		if app.debugger.State() != core.DebugIdle {
+			app.debugger.Stop()
+		}
+	}()
+
 	// Run the window
 	return app.window.Run(ctx)
 }
 
+// onProcessExit reports a finished program.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (app *IDEApp) onProcessExit(p *core.Process) {
+	var message string
+	switch {
+	case p.State() == core.ProcessStopped:
+		message = fmt.Sprintf("Stopped %s", p.Config.Name)
+	case p.ExitCode() == 0:
+		message = fmt.Sprintf("%s exited", p.Config.Name)
+	default:
+		message = fmt.Sprintf("%s exited: %v", p.Config.Name, p.Err())
+	}
+
+	app.window.Post(func() {
+		if statusBar := app.window.GetStatusBar(); statusBar != nil {
+			statusBar.SetMessage(message)
+		}
+		app.updateProcessActions()
+	})
+}
+
+// updateProcessActions enables the stop and restart actions while there
+// are programs to act on. It must run on the UI goroutine.
+func (app *IDEApp) updateProcessActions() {
+	if toolbar := app.window.GetToolBar(); toolbar != nil {
+		toolbar.EnableAction("stop", len(app.processes.Running()) > 0)
+		toolbar.EnableAction("restart", len(app.processes.List()) > 0)
+	}
+}
+
+// onDebugEvent shows where the debugged program stopped, with its stack
+// and locals in the output panel, or that the debug session ended.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (app *IDEApp) onDebugEvent(ev core.DebugEvent) {
+	var message string
+	var details []string
+	switch ev.Kind {
+	case core.DebugEventStopped:
+		message = fmt.Sprintf("Stopped (%s)", ev.Reason)
+		if f := ev.Frame; f != nil && f.File != "" {
+			message = fmt.Sprintf("Stopped at %s:%d in %s (%s)", filepath.Base(f.File), f.Line, f.Name, ev.Reason)
+		}
+		details = app.debugSnapshot()
+	case core.DebugEventExited:
+		message = fmt.Sprintf("Debug session ended (exit code %d)", ev.ExitCode)
+	default:
+		return
+	}
+
+	if panel := app.window.GetOutputPanel(); panel != nil {
+		for _, text := range append([]string{message}, details...) {
+			panel.WriteLine(core.OutputLine{Text: text, Time: time.Now()})
+		}
+	}
+
+	breakpoints := app.debugger.Breakpoints()
+	app.window.Post(func() {
+		if statusBar := app.window.GetStatusBar(); statusBar != nil {
+			statusBar.SetMessage(message)
+		}
+		if editor := app.window.GetEditor(); editor != nil {
+			editor.SetBreakpoints(breakpoints)
+			editor.SetExecutionLine(ev.Frame)
+		}
+		app.updateDebugActions()
+	})
+}
+
+// debugSnapshot formats the stack and locals of the stopped goroutine
+func (app *IDEApp) debugSnapshot() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), core.DebugStartTimeout)
+	defer cancel()
+
+	frames, err := app.debugger.StackTrace(ctx, 0)
+	if err != nil {
+		return []string{"  " + err.Error()}
+	}
+	lines := []string{"Stack:"}
+	for i, f := range frames {
+		lines = append(lines, fmt.Sprintf("  #%d %s  %s:%d", i, f.Name, f.File, f.Line))
+	}
+
+	vars, err := app.debugger.Locals(ctx, 0)
+	if err != nil {
+		return append(lines, "  "+err.Error())
+	}
+	lines = append(lines, "Locals:")
+	for _, v := range vars {
+		lines = append(lines, fmt.Sprintf("  %s %s = %s", v.Name, v.Type, v.Value))
+	}
+	return lines
+}
+
+// updateDebugActions enables the debug actions that apply to the
+// debugger's state. It must run on the UI goroutine.
+func (app *IDEApp) updateDebugActions() {
+	toolbar := app.window.GetToolBar()
+	if toolbar == nil {
+		return
+	}
+
+	state := app.debugger.State()
+	launching := app.isLaunching()
+	toolbar.EnableAction("debug", state == core.DebugIdle && !launching)
+	toolbar.EnableAction("debug-stop", state != core.DebugIdle || launching)
+	for _, action := range []string{"continue", "step-over", "step-in", "step-out"} {
+		toolbar.EnableAction(action, state == core.DebugStopped)
+	}
+}
+
 // Close closes the GUI IDE
 func (app *IDEApp) Close() {
 	if app.window != nil {
@@ -95,12 +236,22 @@ func (h *ideEventHandler) OnFileOpen(file *core.FileInfo) {
 		h.app.logger.Info("File opened", core.Field{Key: "file", Value: file.Path})
 	}
 
+	module := h.app.project.ForModule(context.Background(), file.Path)
+	h.app.moduleMu.Lock()
+	h.app.module = module
+	h.app.moduleMu.Unlock()
+
 	// Update status
 	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
 		msg := guiMessagePool.Get().(*strings.Builder)
 		msg.Reset()
 		msg.WriteString("Opened ")
 		msg.WriteString(file.Name)
+		if module.Path() != h.app.project.Path() {
+			msg.WriteString(" (module ")
+			msg.WriteString(module.Name())
+			msg.WriteString(")")
+		}
 		statusBar.SetMessage(msg.String())
 		guiMessagePool.Put(msg)
 		statusBar.SetFileInfo(file, 1, 1)
@@ -152,6 +303,9 @@ func (h *ideEventHandler) OnFileSave(file *core.FileInfo) error {
 // OnProjectChange handles project changes
 func (h *ideEventHandler) OnProjectChange(project core.Project) {
 	h.app.project = project
+	h.app.moduleMu.Lock()
+	h.app.module = nil
+	h.app.moduleMu.Unlock()
 
 	if h.app.logger != nil {
 		h.app.logger.Info("Project changed", core.Field{Key: "project", Value: project.Path()})
@@ -172,34 +326,32 @@ func (h *ideEventHandler) OnProjectChange(project core.Project) {
 	}
 }
 
-// OnBuild handles build requests
+// OnBuild handles build requests.
+// It is called off the UI goroutine, so UI updates are posted to the window.
 func (h *ideEventHandler) OnBuild() error {
 	if h.app.logger != nil {
 		h.app.logger.Info("Build started", core.Field{Key: "project", Value: h.app.project.Path()})
 	}
 
 	// Update status
-	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
-		statusBar.SetMessage("Building...")
-	}
+	h.setStatus("Building...")
 
-	// Execute build
+	// Execute build, streaming into the output panel
 	ctx := context.Background()
-	err := h.app.builder.Build(ctx, h.app.project)
+	diags, err := h.app.builder.Build(ctx, h.module(), h.outputSink())
 
-	// Update status based on result
-	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
-		if err != nil {
-			msg := guiMessagePool.Get().(*strings.Builder)
-			msg.Reset()
-			msg.WriteString("Build failed: ")
-			msg.WriteString(err.Error())
-			statusBar.SetMessage(msg.String())
-			guiMessagePool.Put(msg)
-		} else {
-			statusBar.SetMessage("Build successful")
+	// Highlight offending lines
+	h.app.window.Post(func() {
+		if editor := h.app.window.GetEditor(); editor != nil {
+			editor.SetDiagnostics(diags)
 		}
-	}
+		if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
+			statusBar.SetDiagnostics(diags)
+		}
+	})
+
+	// Update status based on result
+	h.setResultStatus(err, "Build failed: ", "Build successful")
 
 	if h.app.logger != nil {
 		if err != nil {
@@ -212,73 +364,304 @@ func (h *ideEventHandler) OnBuild() error {
 	return err
 }
 
-// OnRun handles run requests
-func (h *ideEventHandler) OnRun() error {
+// OnTask handles requests to run a Makefile target or script.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnTask(task core.Task) error {
 	if h.app.logger != nil {
-		h.app.logger.Info("Run started", core.Field{Key: "project", Value: h.app.project.Path()})
+		h.app.logger.Info("Task started", core.Field{Key: "task", Value: task.Name})
 	}
 
 	// Update status
-	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
-		statusBar.SetMessage("Running...")
+	h.setStatus("Running " + task.Name + "...")
+
+	// Execute the task, streaming into the output panel
+	ctx := context.Background()
+	err := h.app.builder.RunTask(ctx, h.app.project, task, nil, h.outputSink())
+
+	// Update status based on result
+	h.setResultStatus(err, task.Name+" failed: ", task.Name+" completed")
+
+	if h.app.logger != nil && err != nil {
+		h.app.logger.Error("Task failed", core.Field{Key: "task", Value: task.Name}, core.Field{Key: "error", Value: err.Error()})
+	}
+
+	return err
+}
+
+// OnGenerate handles requests to run go:generate directives.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnGenerate(opts core.GenerateOptions) error {
+	if h.app.logger != nil {
+		h.app.logger.Info("Generate started", core.Field{Key: "project", Value: h.app.project.Path()})
 	}
 
-	// Execute run
+	// Update status
+	h.setStatus("Running go generate...")
+
+	// Execute the directives, streaming into the output panel
 	ctx := context.Background()
-	err := h.app.builder.Run(ctx, h.app.project)
+	err := h.app.builder.Generate(ctx, h.module(), opts, h.outputSink())
 
 	// Update status based on result
-	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
-		if err != nil {
-			msg := guiMessagePool.Get().(*strings.Builder)
-			msg.Reset()
-			msg.WriteString("Run failed: ")
-			msg.WriteString(err.Error())
-			statusBar.SetMessage(msg.String())
-			guiMessagePool.Put(msg)
-		} else {
-			statusBar.SetMessage("Execution completed")
+	h.setResultStatus(err, "Generate failed: ", "Generate complete")
+
+	if h.app.logger != nil && err != nil {
+		h.app.logger.Error("Generate failed", core.Field{Key: "error", Value: err.Error()})
+	}
+
+	return err
+}
+
+// OnDependencies handles requests to load the module dependencies into
+// the dependency panel.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnDependencies() error {
+	graph, err := core.LoadModuleGraph(context.Background(), h.module().Path())
+
+	message := ""
+	if err != nil {
+		message = "Failed to load dependencies"
+	}
+	h.app.window.Post(func() {
+		if panel := h.app.window.GetDependencyPanel(); panel != nil {
+			panel.SetGraph(graph, message)
 		}
+	})
+
+	if graph != nil {
+		h.setStatus(fmt.Sprintf("%d modules in the build list", len(graph.Dependencies())))
+	} else {
+		h.setResultStatus(err, "Dependencies failed: ", "")
+	}
+
+	if h.app.logger != nil && err != nil {
+		h.app.logger.Error("Loading dependencies failed", core.Field{Key: "error", Value: err.Error()})
+	}
+
+	return err
+}
+
+// OnModuleSelect handles requests to explain a module dependency: the
+// modules requiring it and the import chain needing it are written to the
+// output panel.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnModuleSelect(graph *core.ModuleGraph, module *core.GoModule) error {
+	panel := h.app.window.GetOutputPanel()
+	writeLine := func(text string) {
+		if panel != nil {
+			panel.WriteLine(core.OutputLine{Text: text, Time: time.Now()})
+		}
+	}
+
+	writeLine(fmt.Sprintf("%s %s", module.Path, module.Version))
+	if graph != nil {
+		writeLine("Required by:")
+		for _, req := range graph.RequiredBy(module.Path) {
+			_, version, _ := strings.Cut(req.To, "@")
+			writeLine(fmt.Sprintf("  %s requires %s", req.From, version))
+		}
+	}
+
+	why, err := core.WhyModule(context.Background(), h.module().Path(), module.Path)
+	if err != nil {
+		h.setResultStatus(err, "Explaining "+module.Path+" failed: ", "")
+		return err
+	}
+
+	writeLine("Needed by:")
+	if why.NotNeeded || len(why.Packages) == 0 {
+		writeLine("  no package of the main module imports it")
+	}
+	for _, pkg := range why.Packages {
+		writeLine("  " + pkg)
 	}
 
+	h.setStatus(module.Path + " " + module.Version)
+	return nil
+}
+
+// OnCheck handles requests to run the check pipeline.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnCheck() error {
 	if h.app.logger != nil {
-		if err != nil {
+		h.app.logger.Info("Check started", core.Field{Key: "project", Value: h.app.project.Path()})
+	}
+
+	projectConfig, err := core.LoadProjectConfig(h.app.project.Path())
+	if err != nil {
+		h.setResultStatus(err, "Check failed: ", "")
+		return err
+	}
+
+	// Update status
+	h.setStatus("Running checks...")
+
+	// Execute the pipeline, streaming into the output panel
+	ctx := context.Background()
+	report, err := h.app.builder.Check(ctx, h.module(), projectConfig.CheckConfigOrDefault(), h.outputSink())
+
+	// Highlight the merged findings
+	if report != nil {
+		h.app.window.Post(func() {
+			if editor := h.app.window.GetEditor(); editor != nil {
+				editor.SetDiagnostics(report.Diagnostics)
+			}
+			if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
+				statusBar.SetDiagnostics(report.Diagnostics)
+			}
+		})
+	}
+
+	// Update status based on result
+	h.setResultStatus(err, "Check failed: ", "All checks passed")
+
+	if h.app.logger != nil && err != nil {
+		h.app.logger.Error("Check failed", core.Field{Key: "error", Value: err.Error()})
+	}
+
+	return err
+}
+
+// OnRun handles run requests.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnRun() error {
+	return h.OnRunConfig(core.DefaultRunConfig())
+}
+
+// OnRunConfig handles requests to run a named run configuration.
+// The program is built and started in the background; this returns once
+// it is running. It is called off the UI goroutine, so UI updates are
+// posted to the window.
+func (h *ideEventHandler) OnRunConfig(config core.RunConfig) error {
+	_, err := h.startRun(config)
+	return err
+}
+
+// OnWatchRun handles watch mode starting a run configuration. The
+// started program is the one OnWatchRestart restarts.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnWatchRun(config core.RunConfig) error {
+	h.app.watchProcess.Store(0)
+	p, err := h.startRun(config)
+	if err != nil {
+		return err
+	}
+	h.app.watchProcess.Store(int64(p.ID))
+	return nil
+}
+
+// startRun builds and starts a run configuration
+func (h *ideEventHandler) startRun(config core.RunConfig) (*core.Process, error) {
+	if h.app.logger != nil {
+		h.app.logger.Info("Run started",
+			core.Field{Key: "project", Value: h.app.project.Path()},
+			core.Field{Key: "config", Value: config.Name})
+	}
+
+	// Update status
+	h.setStatus("Building " + config.Name + "...")
+
+	// Build and start the program, streaming into the output panel
+	ctx := context.Background()
+	p, err := h.app.processes.Start(ctx, h.module(), config, h.outputSink())
+	if err != nil {
+		h.setResultStatus(err, "Run failed: ", "")
+		if h.app.logger != nil {
 			h.app.logger.Error("Run failed", core.Field{Key: "error", Value: err.Error()})
-		} else {
-			h.app.logger.Info("Run completed successfully")
 		}
+		return nil, err
 	}
 
-	return err
+	h.setStatus(fmt.Sprintf("Started %s (pid %d)", config.Name, p.PID))
+	h.app.window.Post(h.app.updateProcessActions)
+
+	return p, nil
+}
+
+// OnStop handles requests to stop the most recently started program that
+// is still running. It blocks until the program has exited.
+func (h *ideEventHandler) OnStop() error {
+	running := h.app.processes.Running()
+	if len(running) == 0 {
+		return fmt.Errorf("no running program")
+	}
+
+	p := running[len(running)-1]
+	h.setStatus("Stopping " + p.Config.Name + "...")
+
+	// The exit callback reports the stop
+	return h.app.processes.Stop(p.ID)
 }
 
-// OnTest handles test requests
+// OnRestart handles requests to rebuild and restart the most recently
+// started program. It is called off the UI goroutine, so UI updates are
+// posted to the window.
+func (h *ideEventHandler) OnRestart() error {
+	processes := h.app.processes.List()
+	if len(processes) == 0 {
+		return fmt.Errorf("no program to restart")
+	}
+
+	return h.restart(processes[len(processes)-1])
+}
+
+// OnWatchRestart handles watch mode rebuilding and restarting the program
+// it started, even if other programs were started since.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnWatchRestart() error {
+	p, ok := h.app.processes.Get(int(h.app.watchProcess.Load()))
+	if !ok {
+		return fmt.Errorf("watch mode has no program to restart")
+	}
+	return h.restart(p)
+}
+
+// restart rebuilds and restarts a program under the same ID
+func (h *ideEventHandler) restart(p *core.Process) error {
+	h.setStatus("Restarting " + p.Config.Name + "...")
+
+	ctx := context.Background()
+	p, err := h.app.processes.Restart(ctx, p.ID, h.outputSink())
+	if err != nil {
+		h.setResultStatus(err, "Restart failed: ", "")
+		return err
+	}
+
+	h.setStatus(fmt.Sprintf("Restarted %s (pid %d)", p.Config.Name, p.PID))
+	h.app.window.Post(h.app.updateProcessActions)
+
+	return nil
+}
+
+// OnTest handles test requests.
+// It is called off the UI goroutine, so UI updates are posted to the window.
 func (h *ideEventHandler) OnTest() error {
+	return h.OnTestSelected(core.TestOptions{})
+}
+
+// OnTestSelected handles requests to run a package or single test.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnTestSelected(opts core.TestOptions) error {
 	if h.app.logger != nil {
-		h.app.logger.Info("Test started", core.Field{Key: "project", Value: h.app.project.Path()})
+		h.app.logger.Info("Test started",
+			core.Field{Key: "project", Value: h.app.project.Path()},
+			core.Field{Key: "package", Value: opts.PackagePattern()},
+			core.Field{Key: "run", Value: opts.Run})
 	}
 
 	// Update status
-	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
-		statusBar.SetMessage("Running tests...")
-	}
+	h.setStatus("Running tests...")
 
-	// Execute tests
+	// Execute tests, streaming into the output panel
 	ctx := context.Background()
-	err := h.app.builder.Test(ctx, h.app.project)
+	report, err := h.app.builder.Test(ctx, h.module(), opts, h.outputSink())
 
 	// Update status based on result
-	if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
-		if err != nil {
-			msg := guiMessagePool.Get().(*strings.Builder)
-			msg.Reset()
-			msg.WriteString("Tests failed: ")
-			msg.WriteString(err.Error())
-			statusBar.SetMessage(msg.String())
-			guiMessagePool.Put(msg)
-		} else {
-			statusBar.SetMessage("All tests passed")
-		}
+	if report != nil && !report.Passed() {
+		passed, failed, _ := report.Counts()
+		h.setStatus(fmt.Sprintf("Tests failed: %d passed, %d failed", passed, failed))
+	} else {
+		h.setResultStatus(err, "Tests failed: ", "All tests passed")
 	}
 
 	if h.app.logger != nil {
@@ -291,3 +674,264 @@ func (h *ideEventHandler) OnTest() error {
 
 	return err
 }
+
+// OnTestAffected handles requests to test the packages affected by
+// changed files, following reverse imports.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnTestAffected(changed []string) error {
+	h.setStatus("Finding affected packages...")
+
+	ctx := context.Background()
+	opts, ok, err := core.AffectedTestOptions(ctx, h.module().Path(), changed)
+	if err != nil {
+		h.setResultStatus(err, "Affected tests failed: ", "")
+		return err
+	}
+	if !ok {
+		h.setStatus(fmt.Sprintf("No packages affected by %d changed files", len(changed)))
+		return nil
+	}
+
+	if panel := h.app.window.GetOutputPanel(); panel != nil {
+		panel.WriteLine(core.OutputLine{Text: "Testing " + opts.PackagePattern(), Time: time.Now()})
+	}
+	return h.OnTestSelected(opts)
+}
+
+// OnCoverage handles requests to run tests with coverage.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnCoverage() error {
+	if h.app.logger != nil {
+		h.app.logger.Info("Coverage started", core.Field{Key: "project", Value: h.app.project.Path()})
+	}
+
+	// Update status
+	h.setStatus("Running tests with coverage...")
+
+	// Execute tests with coverage, streaming into the output panel
+	ctx := context.Background()
+	report, err := h.app.builder.Cover(ctx, h.module(), core.TestOptions{}, h.outputSink())
+
+	// Color the gutter of the open file
+	if report != nil {
+		h.app.window.Post(func() {
+			if editor := h.app.window.GetEditor(); editor != nil {
+				editor.SetCoverage(report)
+			}
+		})
+		h.setStatus(fmt.Sprintf("Coverage: %.1f%% of statements", report.Percent()))
+	} else {
+		h.setResultStatus(err, "Coverage failed: ", "Coverage complete")
+	}
+
+	if h.app.logger != nil && err != nil {
+		h.app.logger.Error("Coverage run failed", core.Field{Key: "error", Value: err.Error()})
+	}
+
+	return err
+}
+
+// OnProfile handles requests to profile tests or a program. The costliest
+// functions are listed in the output panel and the per-line costs shown in
+// the editor gutter.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnProfile(opts core.ProfileOptions) error {
+	h.setStatus(fmt.Sprintf("Profiling (%s)...", opts.Kind))
+
+	profile, err := h.app.builder.Profile(context.Background(), h.module(), opts, h.outputSink())
+	if profile == nil {
+		h.setResultStatus(err, "Profile failed: ", "")
+		if h.app.logger != nil && err != nil {
+			h.app.logger.Error("Profile failed", core.Field{Key: "error", Value: err.Error()})
+		}
+		return err
+	}
+
+	index, indexErr := profile.SampleIndex(opts.Kind.DefaultSampleType())
+	if indexErr != nil {
+		err = errors.Join(err, indexErr)
+		h.setResultStatus(err, "Profile failed: ", "")
+		return err
+	}
+	report := profile.Summarize(index)
+
+	if panel := h.app.window.GetOutputPanel(); panel != nil {
+		now := time.Now()
+		panel.WriteLine(core.OutputLine{
+			Text: fmt.Sprintf("Profile: %s (total %s)", report.SampleType.Type, report.Format(report.Total)),
+			Time: now,
+		})
+		for _, e := range report.Top(10, false) {
+			text := fmt.Sprintf("  %10s flat %10s cum  %s", report.Format(e.Flat), report.Format(e.Cum), e.Function)
+			if e.File != "" {
+				text += fmt.Sprintf(" (%s:%d)", filepath.Base(e.File), e.Line)
+			}
+			panel.WriteLine(core.OutputLine{Text: text, Time: now})
+		}
+	}
+
+	h.app.window.Post(func() {
+		if editor := h.app.window.GetEditor(); editor != nil {
+			editor.SetProfile(report)
+		}
+	})
+	h.setStatus(fmt.Sprintf("Profile: %s %s in total", report.Format(report.Total), report.SampleType.Type))
+
+	return err
+}
+
+// OnDebug handles requests to debug a run configuration with Delve.
+// It is called off the UI goroutine, so UI updates are posted to the window.
+func (h *ideEventHandler) OnDebug(config core.RunConfig) error {
+	h.setStatus("Starting debugger for " + config.Name + "...")
+
+	// Stopping the debugger or closing the IDE cancels the launch
+	ctx, cancel := context.WithTimeout(context.Background(), core.DebugLaunchTimeout)
+	defer cancel()
+	h.app.setCancelLaunch(cancel)
+	h.app.window.Post(h.app.updateDebugActions)
+
+	err := h.app.debugger.Launch(ctx, h.module(), config, h.outputSink())
+	h.app.setCancelLaunch(nil)
+	h.app.window.Post(h.app.updateDebugActions)
+	switch {
+	case errors.Is(err, context.Canceled):
+		h.setStatus("Debugger stopped")
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		err = fmt.Errorf("debugger did not start within %s", core.DebugLaunchTimeout)
+	}
+	if err != nil {
+		h.setResultStatus(err, "Debug failed: ", "")
+		if h.app.logger != nil {
+			h.app.logger.Error("Debug failed", core.Field{Key: "error", Value: err.Error()})
+		}
+		return err
+	}
+
+	h.setStatus("Debugging " + config.Name)
+	h.app.window.Post(h.app.updateDebugActions)
+
+	return nil
+}
+
+// OnDebugCommand handles requests to continue or step the stopped
+// program. The next stop is reported by the debug event callback.
+func (h *ideEventHandler) OnDebugCommand(command core.DebugCommand) error {
+	err := h.app.debugger.Resume(context.Background(), command)
+	h.app.window.Post(func() {
+		if editor := h.app.window.GetEditor(); editor != nil && err == nil {
+			editor.SetExecutionLine(nil)
+		}
+		h.app.updateDebugActions()
+	})
+	return err
+}
+
+// OnDebugStop handles requests to end the debug session. It blocks until
+// the session has ended.
+func (h *ideEventHandler) OnDebugStop() error {
+	h.setStatus("Stopping debugger...")
+
+	// A launch still starting ends itself once cancelled
+	if h.app.cancelDebugLaunch() && h.app.debugger.State() == core.DebugIdle {
+		return nil
+	}
+
+	// The debug event callback reports the end of the session
+	return h.app.debugger.Stop()
+}
+
+// setCancelLaunch records the cancel function of the debug launch in
+// progress, or clears it with nil
+func (app *IDEApp) setCancelLaunch(cancel context.CancelFunc) {
+	app.launchMu.Lock()
+	defer app.launchMu.Unlock()
+	app.cancelLaunch = cancel
+}
+
+// isLaunching reports whether a debug launch is in progress
+func (app *IDEApp) isLaunching() bool {
+	app.launchMu.Lock()
+	defer app.launchMu.Unlock()
+	return app.cancelLaunch != nil
+}
+
+// cancelDebugLaunch cancels the debug launch in progress, reporting
+// whether there was one
+func (app *IDEApp) cancelDebugLaunch() bool {
+	app.launchMu.Lock()
+	defer app.launchMu.Unlock()
+	if app.cancelLaunch == nil {
+		return false
+	}
+	app.cancelLaunch()
+	app.cancelLaunch = nil
+	return true
+}
+
+// OnToggleBreakpoint handles requests to set or clear a breakpoint
+func (h *ideEventHandler) OnToggleBreakpoint(file string, line int) error {
+	set, err := h.app.debugger.ToggleBreakpoint(context.Background(), file, line)
+	if err != nil {
+		return err
+	}
+
+	if set {
+		h.setStatus(fmt.Sprintf("Breakpoint set at %s:%d", filepath.Base(file), line))
+	} else {
+		h.setStatus(fmt.Sprintf("Breakpoint cleared at %s:%d", filepath.Base(file), line))
+	}
+
+	breakpoints := h.app.debugger.Breakpoints()
+	h.app.window.Post(func() {
+		if editor := h.app.window.GetEditor(); editor != nil {
+			editor.SetBreakpoints(breakpoints)
+		}
+	})
+
+	return nil
+}
+
+// outputSink returns the sink that command output is streamed to
+func (h *ideEventHandler) outputSink() core.OutputSink {
+	if panel := h.app.window.GetOutputPanel(); panel != nil {
+		return panel
+	}
+	return nil
+}
+
+// module returns the project of the module owning the open file, or of
+// the project's main module when no file is open
+func (h *ideEventHandler) module() core.Project {
+	h.app.moduleMu.Lock()
+	defer h.app.moduleMu.Unlock()
+	if h.app.module == nil {
+		h.app.module = h.app.project.ForModule(context.Background(), "")
+	}
+	return h.app.module
+}
+
+// setStatus posts a status bar message to the UI goroutine
+func (h *ideEventHandler) setStatus(message string) {
+	h.app.window.Post(func() {
+		if statusBar := h.app.window.GetStatusBar(); statusBar != nil {
+			statusBar.SetMessage(message)
+		}
+	})
+}
+
+// setResultStatus posts a failure or success status message
+func (h *ideEventHandler) setResultStatus(err error, failurePrefix, success string) {
+	if err == nil {
+		h.setStatus(success)
+		return
+	}
+
+	msg := guiMessagePool.Get().(*strings.Builder)
+	msg.Reset()
+	msg.WriteString(failurePrefix)
+	msg.WriteString(err.Error())
+	h.setStatus(msg.String())
+	guiMessagePool.Put(msg)
+}
@@ -47,6 +47,9 @@ type FileExplorer interface {
 	// SetProject sets the project to display
 	SetProject(project core.Project)
 
+	// SetBuildContext sets the build context whose excluded files are dimmed
+	SetBuildContext(ctx core.BuildContext)
+
 	// GetSelectedFile returns the currently selected file
 	GetSelectedFile() *core.FileInfo
 
@@ -55,6 +58,12 @@ type FileExplorer interface {
 
 	// Refresh reloads the file tree
 	Refresh() error
+
+	// RefreshPaths reloads the directories containing changed files
+	RefreshPaths(paths []string)
+
+	// SetOnInvalidate sets the callback used to request a redraw
+	SetOnInvalidate(callback func())
 }
 
 // Editor provides text editing functionality
@@ -81,6 +90,39 @@ type Editor interface {
 
 	// GetCurrentFile returns the currently open file
 	GetCurrentFile() *core.FileInfo
+
+	// SetReadOnly sets whether the content can be edited; generated
+	// files are opened read-only
+	SetReadOnly(readOnly bool)
+
+	// IsReadOnly returns true if the content cannot be edited
+	IsReadOnly() bool
+
+	// SetDiagnostics sets diagnostics to highlight in the gutter
+	SetDiagnostics(diags []core.Diagnostic)
+
+	// SetCoverage sets coverage to color in the line-number gutter
+	SetCoverage(report *core.CoverageReport)
+
+	// GetCursorPosition returns the 1-based line and column of the caret
+	GetCursorPosition() (line, col int)
+
+	// GoToPosition moves the caret to a 1-based line and byte column, as
+	// reported by the Go toolchain, and scrolls it into view
+	GoToPosition(line, col int)
+
+	// SetOnDefinition sets the callback for go to definition requests (F12
+	// or Ctrl+click), called with the 1-based line and byte column
+	SetOnDefinition(callback func(line, col int))
+
+	// SetBreakpoints sets the debugger breakpoints to mark in the gutter
+	SetBreakpoints(breakpoints []core.Breakpoint)
+
+	// SetExecutionLine marks the line the debugged program stopped at
+	SetExecutionLine(frame *core.StackFrame)
+
+	// SetProfile shows a profile's per-line costs in the gutter
+	SetProfile(report *core.ProfileReport)
 }
 
 // StatusBar displays status information
@@ -95,6 +137,60 @@ type StatusBar interface {
 
 	// SetProjectInfo sets project information
 	SetProjectInfo(project core.Project)
+
+	// SetDiagnostics sets the diagnostics summary
+	SetDiagnostics(diags []core.Diagnostic)
+
+	// SetBuildContext sets the build context display
+	SetBuildContext(ctx core.BuildContext)
+}
+
+// OutputPanel displays streamed command output
+type OutputPanel interface {
+	Component
+	core.OutputSink
+
+	// Clear removes all output and sets the panel title
+	Clear(title string)
+
+	// SetOnInvalidate sets the callback used to request a redraw
+	SetOnInvalidate(callback func())
+}
+
+// DependencyPanel lists the module dependencies next to the file explorer
+type DependencyPanel interface {
+	Component
+
+	// SetGraph shows the dependencies of a module graph; nil shows
+	// message instead
+	SetGraph(graph *core.ModuleGraph, message string)
+
+	// Graph returns the module graph shown, or nil
+	Graph() *core.ModuleGraph
+
+	// SetOnModuleSelect sets the callback for module selection
+	SetOnModuleSelect(callback func(module *core.GoModule))
+}
+
+// SymbolSearch is a popup fuzzy-searching the project's Go symbols
+type SymbolSearch interface {
+	Component
+
+	// SetIndex sets the index searched; nil shows that indexing is under way
+	SetIndex(index *core.SymbolIndex)
+
+	// Show opens the popup with an empty query
+	Show()
+
+	// Hide closes the popup
+	Hide()
+
+	// IsVisible returns true if the popup is open
+	IsVisible() bool
+
+	// SetOnSelect sets the callback for symbol selection, which closes
+	// the popup
+	SetOnSelect(callback func(sym core.Symbol))
 }
 
 // ToolBar provides quick action buttons
@@ -107,6 +203,15 @@ type ToolBar interface {
 	// EnableAction enables/disables an action
 	EnableAction(action string, enabled bool)
 
+	// SetOptions turns an action into a dropdown with the given options
+	SetOptions(action string, options []string, selected int)
+
+	// SetMenu turns an action into a menu of commands
+	SetMenu(action string, options []string)
+
+	// SetOnSelect sets callback for dropdown option selection
+	SetOnSelect(action string, callback func(option string))
+
 	// AddSeparator adds a visual separator
 	AddSeparator()
 }
@@ -137,6 +242,18 @@ type IDEWindow interface {
 	// GetToolBar returns the toolbar component
 	GetToolBar() ToolBar
 
+	// GetOutputPanel returns the output panel component
+	GetOutputPanel() OutputPanel
+
+	// GetDependencyPanel returns the dependency panel component
+	GetDependencyPanel() DependencyPanel
+
+	// GetSymbolSearch returns the symbol search popup
+	GetSymbolSearch() SymbolSearch
+
+	// Post schedules fn to run on the UI goroutine before the next frame
+	Post(fn func())
+
 	// ShowMessage displays a message to the user
 	ShowMessage(message string)
 
@@ -188,11 +305,66 @@ type EventHandler interface {
 	// OnBuild handles build requests
 	OnBuild() error
 
+	// OnTask handles requests to run a Makefile target or script
+	OnTask(task core.Task) error
+
+	// OnCheck handles requests to run the check pipeline
+	OnCheck() error
+
 	// OnRun handles run requests
 	OnRun() error
 
+	// OnRunConfig handles requests to run a named run configuration
+	OnRunConfig(config core.RunConfig) error
+
+	// OnStop handles requests to stop the running program
+	OnStop() error
+
+	// OnRestart handles requests to restart the last started program
+	OnRestart() error
+
+	// OnWatchRun handles watch mode starting a run configuration
+	OnWatchRun(config core.RunConfig) error
+
+	// OnWatchRestart handles watch mode restarting the program it started
+	OnWatchRestart() error
+
 	// OnTest handles test requests
 	OnTest() error
+
+	// OnTestSelected handles requests to run a package or single test
+	OnTestSelected(opts core.TestOptions) error
+
+	// OnTestAffected handles requests to test the packages affected by
+	// changed files
+	OnTestAffected(changed []string) error
+
+	// OnCoverage handles requests to run tests with coverage
+	OnCoverage() error
+
+	// OnGenerate handles requests to run go:generate directives
+	OnGenerate(opts core.GenerateOptions) error
+
+	// OnDependencies handles requests to load the module dependencies
+	OnDependencies() error
+
+	// OnModuleSelect handles requests to explain a module dependency
+	OnModuleSelect(graph *core.ModuleGraph, module *core.GoModule) error
+
+	// OnProfile handles requests to profile tests or a program
+	OnProfile(opts core.ProfileOptions) error
+
+	// OnDebug handles requests to debug a run configuration
+	OnDebug(config core.RunConfig) error
+
+	// OnDebugCommand handles requests to continue or step the stopped program
+	OnDebugCommand(command core.DebugCommand) error
+
+	// OnDebugStop handles requests to end the debug session
+	OnDebugStop() error
+
+	// OnToggleBreakpoint handles requests to set or clear a breakpoint
+	OnToggleBreakpoint(file string, line int) error
 }
 
 // ComponentFactory creates GUI components with loose coupling
@@ -201,6 +373,9 @@ type ComponentFactory interface {
 	CreateEditor() Editor
 	CreateStatusBar() StatusBar
 	CreateToolBar() ToolBar
+	CreateOutputPanel() OutputPanel
+	CreateDependencyPanel() DependencyPanel
+	CreateSymbolSearch() SymbolSearch
 }
 
 // IDEConfig holds configuration for the IDE
@@ -216,6 +391,9 @@ type IDEConfig struct {
 	Editor       Editor
 	StatusBar    StatusBar
 	ToolBar      ToolBar
+	OutputPanel  OutputPanel
+	DepsPanel    DependencyPanel
+	SymbolSearch SymbolSearch
 
 	// Factory for creating components
 	Factory ComponentFactory
@@ -244,6 +422,21 @@ func (f *DefaultComponentFactory) CreateToolBar() ToolBar {
 	return NewToolBar()
 }
 
+// CreateOutputPanel creates a default output panel
+func (f *DefaultComponentFactory) CreateOutputPanel() OutputPanel {
+	return NewOutputPanel()
+}
+
+// CreateDependencyPanel creates a default dependency panel
+func (f *DefaultComponentFactory) CreateDependencyPanel() DependencyPanel {
+	return NewDependencyPanel()
+}
+
+// CreateSymbolSearch creates a default symbol search popup
+func (f *DefaultComponentFactory) CreateSymbolSearch() SymbolSearch {
+	return NewSymbolSearch()
+}
+
 // NewDefaultFactory creates a default component factory
 func NewDefaultFactory() ComponentFactory {
 	return &DefaultComponentFactory{}
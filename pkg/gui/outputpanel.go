@@ -0,0 +1,131 @@
+package gui
+
+import (
+	"image/color"
+	"sync"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"gox-ide/pkg/core"
+)
+
+const (
+	// MaxOutputLines bounds the output panel scrollback
+	MaxOutputLines = 5000
+)
+
+// OutputPanelImpl implements OutputPanel interface
+type OutputPanelImpl struct {
+	id    string
+	list  widget.List
+	title string
+
+	// Lines are written from command goroutines and read while laying out
+	mu         sync.Mutex
+	lines      []core.OutputLine
+	invalidate func()
+}
+
+// NewOutputPanel creates a new output panel component
+func NewOutputPanel() *OutputPanelImpl {
+	return &OutputPanelImpl{
+		id:    "output-panel",
+		title: "Output",
+		list: widget.List{
+			List: layout.List{
+				Axis:        layout.Vertical,
+				ScrollToEnd: true,
+			},
+		},
+	}
+}
+
+// ID returns the component ID
+func (p *OutputPanelImpl) ID() string {
+	return p.id
+}
+
+// WriteLine appends a line of command output; safe for concurrent use
+func (p *OutputPanelImpl) WriteLine(line core.OutputLine) {
+	p.mu.Lock()
+	p.lines = append(p.lines, line)
+	if len(p.lines) > MaxOutputLines+MaxOutputLines/10 {
+		// Drop the oldest lines in batches. Copy into a fresh array so a
+		// snapshot taken by Layout is never overwritten.
+		p.lines = append([]core.OutputLine(nil), p.lines[len(p.lines)-MaxOutputLines:]...)
+	}
+	invalidate := p.invalidate
+	p.mu.Unlock()
+
+	if invalidate != nil {
+		invalidate()
+	}
+}
+
+// Clear removes all output and sets the panel title
+func (p *OutputPanelImpl) Clear(title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lines = nil
+	p.title = title
+}
+
+// SetOnInvalidate sets the callback used to request a redraw
+func (p *OutputPanelImpl) SetOnInvalidate(callback func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.invalidate = callback
+}
+
+// Update processes events and updates component state
+func (p *OutputPanelImpl) Update(gtx layout.Context) bool {
+	// Output panel is passive
+	return false
+}
+
+// Layout renders the output panel
+func (p *OutputPanelImpl) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	p.mu.Lock()
+	lines := p.lines
+	title := p.title
+	p.mu.Unlock()
+
+	// Draw panel background
+	bg := color.NRGBA{R: 250, G: 250, B: 250, A: 255}
+	paint.FillShape(gtx.Ops, bg, clip.Rect{Max: gtx.Constraints.Max}.Op())
+
+	return layout.Inset{
+		Top: unit.Dp(4), Bottom: unit.Dp(4),
+		Left: unit.Dp(8), Right: unit.Dp(8),
+	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{
+			Axis: layout.Vertical,
+		}.Layout(gtx,
+			// Title
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				label := material.Caption(theme, title)
+				label.Color = color.NRGBA{R: 100, G: 100, B: 100, A: 255}
+				return layout.Inset{Bottom: unit.Dp(4)}.Layout(gtx, label.Layout)
+			}),
+
+			// Output lines
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return material.List(theme, &p.list).Layout(gtx, len(lines), func(gtx layout.Context, i int) layout.Dimensions {
+					label := material.Body2(theme, lines[i].Text)
+					label.Color = theme.Fg
+					if lines[i].Stream == core.StreamStderr {
+						label.Color = color.NRGBA{R: 211, G: 47, B: 47, A: 255}
+					}
+					return label.Layout(gtx)
+				})
+			}),
+		)
+	})
+}
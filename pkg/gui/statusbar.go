@@ -30,6 +30,8 @@ type StatusBarImpl struct {
 	message     string
 	fileInfo    string
 	projectInfo string
+	diagInfo    string
+	buildInfo   string
 }
 
 // NewStatusBar creates a new status bar component
@@ -76,6 +78,22 @@ func (sb *StatusBarImpl) SetProjectInfo(project core.Project) {
 	}
 }
 
+// SetDiagnostics sets the diagnostics summary
+func (sb *StatusBarImpl) SetDiagnostics(diags []core.Diagnostic) {
+	if len(diags) == 0 {
+		sb.diagInfo = ""
+		return
+	}
+
+	errs, warnings := core.CountDiagnostics(diags)
+	sb.diagInfo = fmt.Sprintf("❌ %d  ⚠️ %d", errs, warnings)
+}
+
+// SetBuildContext sets the build context display
+func (sb *StatusBarImpl) SetBuildContext(ctx core.BuildContext) {
+	sb.buildInfo = "🎯 " + ctx.String()
+}
+
 // Update processes events and updates component state
 func (sb *StatusBarImpl) Update(gtx layout.Context) bool {
 	// Status bar is mostly passive
@@ -109,6 +127,28 @@ func (sb *StatusBarImpl) Layout(gtx layout.Context, theme *material.Theme) layou
 				return label.Layout(gtx)
 			}),
 
+			// Diagnostics summary
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if sb.diagInfo == "" {
+					return layout.Dimensions{}
+				}
+
+				label := material.Caption(theme, sb.diagInfo)
+				label.Color = color.NRGBA{R: 211, G: 47, B: 47, A: 255}
+				return layout.Inset{Left: unit.Dp(16)}.Layout(gtx, label.Layout)
+			}),
+
+			// Build context
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if sb.buildInfo == "" {
+					return layout.Dimensions{}
+				}
+
+				label := material.Caption(theme, sb.buildInfo)
+				label.Color = color.NRGBA{R: 100, G: 100, B: 100, A: 255}
+				return layout.Inset{Left: unit.Dp(16)}.Layout(gtx, label.Layout)
+			}),
+
 			// Project info in the middle
 			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 				if sb.projectInfo == "" {
@@ -0,0 +1,296 @@
+package gui
+
+import (
+	"image/color"
+	"path/filepath"
+	"strconv"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"gox-ide/pkg/core"
+)
+
+// maxSymbolMatches bounds the results listed by the symbol search popup
+const maxSymbolMatches = 50
+
+var (
+	// symbolBackdropColor shades the window behind the popup
+	symbolBackdropColor = color.NRGBA{A: 64}
+
+	// symbolDetailColor dims locations and doc comments
+	symbolDetailColor = color.NRGBA{R: 120, G: 120, B: 120, A: 255}
+)
+
+// SymbolSearchImpl implements SymbolSearch interface
+type SymbolSearchImpl struct {
+	id       string
+	index    *core.SymbolIndex
+	query    widget.Editor
+	list     widget.List
+	backdrop widget.Clickable
+	matches  []core.SymbolMatch
+	buttons  []widget.Clickable
+	visible  bool
+	focus    bool // Focus the query on next layout
+	onSelect func(sym core.Symbol)
+}
+
+// NewSymbolSearch creates a new symbol search popup
+func NewSymbolSearch() *SymbolSearchImpl {
+	return &SymbolSearchImpl{
+		id: "symbol-search",
+		query: widget.Editor{
+			SingleLine: true,
+			Submit:     true,
+		},
+		list: widget.List{
+			List: layout.List{
+				Axis: layout.Vertical,
+			},
+		},
+	}
+}
+
+// ID returns the component ID
+func (s *SymbolSearchImpl) ID() string {
+	return s.id
+}
+
+// SetIndex sets the index searched and repeats the current search
+func (s *SymbolSearchImpl) SetIndex(index *core.SymbolIndex) {
+	s.index = index
+	s.search()
+}
+
+// Show opens the popup with an empty query
+func (s *SymbolSearchImpl) Show() {
+	s.query.SetText("")
+	s.search()
+	s.visible = true
+	s.focus = true
+}
+
+// Hide closes the popup
+func (s *SymbolSearchImpl) Hide() {
+	s.visible = false
+}
+
+// IsVisible returns true if the popup is open
+func (s *SymbolSearchImpl) IsVisible() bool {
+	return s.visible
+}
+
+// SetOnSelect sets the callback for symbol selection
+func (s *SymbolSearchImpl) SetOnSelect(callback func(sym core.Symbol)) {
+	s.onSelect = callback
+}
+
+// search lists the symbols matching the query
+func (s *SymbolSearchImpl) search() {
+	s.matches = nil
+	if s.index != nil {
+		s.matches = s.index.Search(s.query.Text(), maxSymbolMatches)
+	}
+	s.buttons = make([]widget.Clickable, len(s.matches))
+	s.list.Position = layout.Position{}
+}
+
+// choose closes the popup and reports the selected symbol
+func (s *SymbolSearchImpl) choose(index int) {
+	if index >= len(s.matches) {
+		return
+	}
+	s.visible = false
+	if s.onSelect != nil {
+		s.onSelect(s.matches[index].Symbol)
+	}
+}
+
+// Update processes events and updates component state. Enter opens the
+// best match, Escape or a click outside closes the popup.
+func (s *SymbolSearchImpl) Update(gtx layout.Context) bool {
+	changed := false
+
+	for {
+		ev, ok := s.query.Update(gtx)
+		if !ok {
+			break
+		}
+		switch ev.(type) {
+		case widget.ChangeEvent:
+			s.search()
+			changed = true
+		case widget.SubmitEvent:
+			s.choose(0)
+			changed = true
+		}
+	}
+
+	for {
+		ev, ok := gtx.Event(key.Filter{Focus: &s.query, Name: key.NameEscape})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			s.visible = false
+			changed = true
+		}
+	}
+
+	if s.backdrop.Clicked(gtx) {
+		s.visible = false
+		changed = true
+	}
+
+	for i := range s.buttons {
+		if s.buttons[i].Clicked(gtx) {
+			s.choose(i)
+			changed = true
+			break
+		}
+	}
+
+	return changed
+}
+
+// Layout renders the popup over the window when it is visible
+func (s *SymbolSearchImpl) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	if !s.visible {
+		return layout.Dimensions{}
+	}
+
+	// Update state
+	s.Update(gtx)
+	if !s.visible {
+		return layout.Dimensions{}
+	}
+	if s.focus {
+		gtx.Execute(key.FocusCmd{Tag: &s.query})
+		s.focus = false
+	}
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return layout.Stack{}.Layout(gtx,
+		// Clicks outside the popup close it
+		layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+			return s.backdrop.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				paint.FillShape(gtx.Ops, symbolBackdropColor, clip.Rect{Max: gtx.Constraints.Min}.Op())
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			})
+		}),
+
+		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			return layout.N.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(unit.Dp(600)))
+				gtx.Constraints.Max.Y = min(gtx.Constraints.Max.Y, gtx.Dp(unit.Dp(480)))
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				return layout.Inset{Top: unit.Dp(48)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return s.layoutPanel(gtx, theme)
+				})
+			})
+		}),
+	)
+}
+
+// layoutPanel renders the query and the results
+func (s *SymbolSearchImpl) layoutPanel(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	return layout.Stack{}.Layout(gtx,
+		// Background, which also keeps clicks from reaching the backdrop
+		layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+			area := clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops)
+			event.Op(gtx.Ops, s)
+			paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			area.Pop()
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}),
+
+		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{
+					Axis: layout.Vertical,
+				}.Layout(gtx,
+					// Query
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						ed := material.Editor(theme, &s.query, "🔎 Go to symbol (e.g. Builder.Build)")
+						return layout.Inset{Bottom: unit.Dp(8)}.Layout(gtx, ed.Layout)
+					}),
+
+					// Results
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if len(s.matches) == 0 {
+							message := "No matching symbols"
+							switch {
+							case s.index == nil:
+								message = "Indexing symbols..."
+							case s.query.Text() == "":
+								message = "Type to search types, functions, methods and fields"
+							}
+							label := material.Body2(theme, message)
+							label.Color = symbolDetailColor
+							return label.Layout(gtx)
+						}
+						return material.List(theme, &s.list).Layout(gtx, len(s.matches), func(gtx layout.Context, i int) layout.Dimensions {
+							return s.layoutMatch(gtx, theme, i)
+						})
+					}),
+				)
+			})
+		}),
+	)
+}
+
+// layoutMatch renders a single result, highlighting the one Enter opens
+func (s *SymbolSearchImpl) layoutMatch(gtx layout.Context, theme *material.Theme, index int) layout.Dimensions {
+	sym := s.matches[index].Symbol
+
+	return s.buttons[index].Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min.X = gtx.Constraints.Max.X
+		return layout.Stack{}.Layout(gtx,
+			// The first match is opened by Enter
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				if index == 0 {
+					selectionBG := color.NRGBA{R: 173, G: 216, B: 230, A: 255}
+					paint.FillShape(gtx.Ops, selectionBG, clip.Rect{Max: gtx.Constraints.Min}.Op())
+				}
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}),
+			layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+				return s.layoutMatchText(gtx, theme, sym)
+			}),
+		)
+	})
+}
+
+// layoutMatchText renders the name of a result above its location and
+// doc summary
+func (s *SymbolSearchImpl) layoutMatchText(gtx layout.Context, theme *material.Theme, sym core.Symbol) layout.Dimensions {
+	return layout.Inset{Top: unit.Dp(2), Bottom: unit.Dp(2)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{
+			Axis: layout.Vertical,
+		}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				label := material.Body2(theme, string(sym.Kind)+" "+sym.QualifiedName())
+				label.Color = theme.Fg
+				label.MaxLines = 1
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				detail := filepath.ToSlash(sym.RelPath) + ":" + strconv.Itoa(sym.Line)
+				if summary := sym.Summary(); summary != "" {
+					detail += " — " + summary
+				}
+				label := material.Caption(theme, detail)
+				label.Color = symbolDetailColor
+				label.MaxLines = 1
+				return label.Layout(gtx)
+			}),
+		)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"image/color"
 
 	"gioui.org/layout"
+	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/unit"
@@ -14,9 +15,10 @@ import (
 
 // ToolBarImpl implements ToolBar interface
 type ToolBarImpl struct {
-	id      string
-	buttons []ToolBarButton
-	actions map[string]func()
+	id       string
+	buttons  []ToolBarButton
+	actions  map[string]func()
+	onSelect map[string]func(option string)
 }
 
 // ToolBarButton represents a toolbar button
@@ -26,21 +28,51 @@ type ToolBarButton struct {
 	Icon    string
 	Enabled bool
 	Button  widget.Clickable
+
+	// Dropdown state; a button with options opens a menu when clicked
+	Options       []string
+	Selected      int
+	Expanded      bool
+	OptionButtons []widget.Clickable
+	Menu          bool // The label stays fixed instead of showing the selection
 }
 
 // NewToolBar creates a new toolbar component
 func NewToolBar() *ToolBarImpl {
 	tb := &ToolBarImpl{
-		id:      "toolbar",
-		actions: make(map[string]func()),
+		id:       "toolbar",
+		actions:  make(map[string]func()),
+		onSelect: make(map[string]func(option string)),
 	}
 
 	// Initialize default buttons
 	tb.buttons = []ToolBarButton{
 		{ID: "save", Text: "Save", Icon: "💾", Enabled: false},
+		{ID: "unlock", Text: "Read-only", Icon: "🔒", Enabled: false},
 		{ID: "build", Text: "Build", Icon: "🔨", Enabled: true},
+		{ID: "check", Text: "Check", Icon: "🔍", Enabled: true},
 		{ID: "run", Text: "Run", Icon: "▶️", Enabled: true},
+		{ID: "run-config", Text: "default", Icon: "⚙️", Enabled: true},
+		{ID: "stop", Text: "Stop", Icon: "⏹️", Enabled: false},
+		{ID: "restart", Text: "Restart", Icon: "🔄", Enabled: false},
+		{ID: "debug", Text: "Debug", Icon: "🐞", Enabled: true},
+		{ID: "breakpoint", Text: "Breakpoint", Icon: "🔴", Enabled: false},
+		{ID: "continue", Text: "Continue", Icon: "⏯️", Enabled: false},
+		{ID: "step-over", Text: "Step Over", Icon: "⤵️", Enabled: false},
+		{ID: "step-in", Text: "Step In", Icon: "⬇️", Enabled: false},
+		{ID: "step-out", Text: "Step Out", Icon: "⬆️", Enabled: false},
+		{ID: "debug-stop", Text: "Stop Debug", Icon: "⏏️", Enabled: false},
 		{ID: "test", Text: "Test", Icon: "🧪", Enabled: true},
+		{ID: "test-cursor", Text: "Test at Cursor", Icon: "🎯", Enabled: false},
+		{ID: "test-affected", Text: "Test Affected", Icon: "🔗", Enabled: true},
+		{ID: "cover", Text: "Coverage", Icon: "📈", Enabled: true},
+		{ID: "profile", Text: "Profile", Icon: "🔥", Enabled: true},
+		{ID: "watch", Text: "Watch off", Icon: "👀", Enabled: true},
+		{ID: "tasks", Text: "Tasks", Icon: "📋", Enabled: false},
+		{ID: "deps", Text: "Dependencies", Icon: "📦", Enabled: true},
+		{ID: "symbols", Text: "Symbols", Icon: "🔎", Enabled: true},
+		{ID: "generate", Text: "Generate", Icon: "⚙️", Enabled: false},
+		{ID: "target", Text: "Host", Icon: "🎯", Enabled: true},
 	}
 
 	return tb
@@ -66,6 +98,44 @@ func (tb *ToolBarImpl) EnableAction(action string, enabled bool) {
 	}
 }
 
+// SetOptions turns an action into a dropdown with the given options
+func (tb *ToolBarImpl) SetOptions(action string, options []string, selected int) {
+	for i := range tb.buttons {
+		button := &tb.buttons[i]
+		if button.ID != action {
+			continue
+		}
+
+		button.Options = options
+		button.OptionButtons = make([]widget.Clickable, len(options))
+		button.Expanded = false
+		button.Selected = 0
+		if selected >= 0 && selected < len(options) {
+			button.Selected = selected
+			button.Text = options[selected]
+		}
+		break
+	}
+}
+
+// SetMenu turns an action into a menu of commands; unlike a dropdown
+// its label does not change when an option is chosen
+func (tb *ToolBarImpl) SetMenu(action string, options []string) {
+	tb.SetOptions(action, options, -1)
+	for i := range tb.buttons {
+		if tb.buttons[i].ID == action {
+			tb.buttons[i].Menu = true
+			tb.buttons[i].Enabled = len(options) > 0
+			break
+		}
+	}
+}
+
+// SetOnSelect sets callback for dropdown option selection
+func (tb *ToolBarImpl) SetOnSelect(action string, callback func(option string)) {
+	tb.onSelect[action] = callback
+}
+
 // AddSeparator adds a visual separator
 func (tb *ToolBarImpl) AddSeparator() {
 	tb.buttons = append(tb.buttons, ToolBarButton{
@@ -86,11 +156,29 @@ func (tb *ToolBarImpl) Update(gtx layout.Context) bool {
 		}
 
 		if button.Button.Clicked(gtx) && button.Enabled {
-			if action, exists := tb.actions[button.ID]; exists && action != nil {
+			if len(button.Options) > 0 {
+				button.Expanded = !button.Expanded
+			} else if action, exists := tb.actions[button.ID]; exists && action != nil {
 				action()
 			}
 			changed = true
 		}
+
+		// Handle dropdown option clicks
+		for j := range button.OptionButtons {
+			if !button.OptionButtons[j].Clicked(gtx) {
+				continue
+			}
+			if !button.Menu {
+				button.Selected = j
+				button.Text = button.Options[j]
+			}
+			button.Expanded = false
+			if callback, exists := tb.onSelect[button.ID]; exists && callback != nil {
+				callback(button.Options[j])
+			}
+			changed = true
+		}
 	}
 
 	return changed
@@ -166,7 +254,43 @@ func (tb *ToolBarImpl) layoutButton(gtx layout.Context, theme *material.Theme, b
 		} else {
 			btn.Text = button.Text
 		}
+		if len(button.Options) > 0 {
+			btn.Text += " ▾"
+		}
 
-		return btn.Layout(gtx)
+		dims := btn.Layout(gtx)
+		if button.Expanded {
+			tb.layoutDropdown(gtx, theme, button, dims.Size.Y)
+		}
+		return dims
 	})
 }
+
+// layoutDropdown renders the options of an expanded dropdown below its
+// button, deferred so it is drawn on top of the rest of the window
+func (tb *ToolBarImpl) layoutDropdown(gtx layout.Context, theme *material.Theme, button *ToolBarButton, offsetY int) {
+	macro := op.Record(gtx.Ops)
+	offset := op.Offset(image.Point{Y: offsetY}).Push(gtx.Ops)
+
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max.Y = gtx.Dp(unit.Dp(400))
+
+	children := make([]layout.FlexChild, len(button.Options))
+	for i := range button.Options {
+		i := i
+		children[i] = layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			btn := material.Button(theme, &button.OptionButtons[i], button.Options[i])
+			btn.Background = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			btn.Color = theme.Fg
+			if i == button.Selected {
+				btn.Background = color.NRGBA{R: 173, G: 216, B: 230, A: 255}
+			}
+			return btn.Layout(gtx)
+		})
+	}
+
+	layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+
+	offset.Pop()
+	op.Defer(gtx.Ops, macro.Stop())
+}
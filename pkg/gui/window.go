@@ -3,10 +3,15 @@ package gui
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"gioui.org/app"
+	"gioui.org/io/key"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/unit"
@@ -32,9 +37,33 @@ type Window struct {
 	editor       Editor
 	statusBar    StatusBar
 	toolBar      ToolBar
+	outputPanel  OutputPanel
+	depsPanel    DependencyPanel
+	symbolSearch SymbolSearch
 
 	// State
-	running bool
+	running    bool
+	busy       bool
+	runConfigs []core.RunConfig
+	runConfig  core.RunConfig
+	buildCtxs  []core.BuildContext
+	tasks      []core.Task
+	showDeps   bool
+	directives []core.GenerateDirective
+	stopWatch  context.CancelFunc
+	watchMode  core.WatchAction
+	watchQueue []string // Changes seen while busy, acted on once idle
+	stopScan   context.CancelFunc
+	savedScan  []string           // Files saved while scanning, rescanned once done
+	stopWalk   context.CancelFunc // Stops finding the workspace modules
+	symbols    *core.SymbolIndex
+	stopIndex  context.CancelFunc
+	savedIndex []string // Files saved while indexing, re-indexed once done
+	stopDef    context.CancelFunc
+
+	// Functions posted from background goroutines
+	postMu sync.Mutex
+	posted []func()
 }
 
 // NewWindow creates a new IDE window
@@ -80,6 +109,24 @@ func NewWindow(config IDEConfig) *Window {
 		w.toolBar = factory.CreateToolBar()
 	}
 
+	if config.OutputPanel != nil {
+		w.outputPanel = config.OutputPanel
+	} else {
+		w.outputPanel = factory.CreateOutputPanel()
+	}
+
+	if config.DepsPanel != nil {
+		w.depsPanel = config.DepsPanel
+	} else {
+		w.depsPanel = factory.CreateDependencyPanel()
+	}
+
+	if config.SymbolSearch != nil {
+		w.symbolSearch = config.SymbolSearch
+	} else {
+		w.symbolSearch = factory.CreateSymbolSearch()
+	}
+
 	// Setup event handlers
 	w.setupEventHandlers()
 
@@ -96,6 +143,7 @@ func NewWindow(config IDEConfig) *Window {
 func (w *Window) Run(ctx context.Context) error {
 	w.running = true
 	defer func() { w.running = false }()
+	defer w.setWatch(ctx, "")
 
 	var ops op.Ops
 
@@ -108,6 +156,7 @@ func (w *Window) Run(ctx context.Context) error {
 			case app.DestroyEvent:
 				return nil
 			case app.FrameEvent:
+				w.runPosted()
 				gtx := app.NewContext(&ops, e)
 				w.layout(gtx)
 				e.Frame(gtx.Ops)
@@ -118,6 +167,27 @@ func (w *Window) Run(ctx context.Context) error {
 	return nil
 }
 
+// Post schedules fn to run on the UI goroutine before the next frame
+func (w *Window) Post(fn func()) {
+	w.postMu.Lock()
+	w.posted = append(w.posted, fn)
+	w.postMu.Unlock()
+
+	w.window.Invalidate()
+}
+
+// runPosted runs functions posted from background goroutines
+func (w *Window) runPosted() {
+	w.postMu.Lock()
+	posted := w.posted
+	w.posted = nil
+	w.postMu.Unlock()
+
+	for _, fn := range posted {
+		fn()
+	}
+}
+
 // Close closes the IDE window
 func (w *Window) Close() {
 	w.running = false
@@ -137,9 +207,396 @@ func (w *Window) SetProject(project core.Project) {
 	if w.statusBar != nil {
 		w.statusBar.SetProjectInfo(project)
 	}
+	w.loadRunConfigs()
+	w.loadBuildContexts()
+	w.loadTasks()
+	w.loadGenerateDirectives()
+	w.loadSymbols()
+	w.loadWorkspace()
+	w.showDeps = false // Dependencies are reloaded when shown again
 	w.updateTitle()
 }
 
+// loadTasks loads the project's Makefile targets and scripts into the
+// toolbar task menu
+func (w *Window) loadTasks() {
+	w.tasks = nil
+	if w.config.Project != nil {
+		tasks, err := core.DiscoverTasks(w.config.Project.Path())
+		if err != nil {
+			w.ShowError(err)
+		}
+		w.tasks = tasks
+	}
+
+	if w.toolBar != nil {
+		names := make([]string, len(w.tasks))
+		for i, task := range w.tasks {
+			names[i] = task.Name
+		}
+		w.toolBar.SetMenu("tasks", names)
+	}
+}
+
+// Fixed options of the generate menu, followed by one per directive
+const (
+	generateAllOption     = "All packages"
+	generatePackageOption = "Current package"
+)
+
+// loadGenerateDirectives scans the project for go:generate directives in
+// the background and loads them into the toolbar generate menu. A new
+// scan cancels the previous one, so switching away from a huge directory
+// does not wait for its walk.
+func (w *Window) loadGenerateDirectives() {
+	if w.stopScan != nil {
+		w.stopScan()
+		w.stopScan = nil
+	}
+	w.directives = nil
+	w.savedScan = nil
+	w.setGenerateMenu()
+
+	project := w.config.Project
+	if project == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stopScan = cancel
+
+	// Report progress once the scan takes more than a second
+	opts := core.DefaultWalkOptions()
+	opts.Logger = w.config.Logger
+	start := time.Now()
+	reported := false
+	opts.Progress = func(p core.WalkProgress) {
+		if time.Since(start) < time.Second {
+			return
+		}
+		w.Post(func() {
+			if ctx.Err() == nil {
+				reported = true
+				w.ShowMessage(fmt.Sprintf("Scanning project... %d files", p.Files))
+			}
+		})
+	}
+
+	go func() {
+		directives, err := core.FindGenerateDirectives(ctx, project, opts)
+		w.Post(func() {
+			if ctx.Err() != nil {
+				// Superseded by another scan
+				return
+			}
+			cancel()
+			w.stopScan = nil
+
+			switch {
+			case err != nil:
+				w.ShowError(err)
+			case reported:
+				w.ShowMessage("Ready")
+			}
+			w.directives = directives
+			w.setGenerateMenu()
+
+			// The walk may have read files before they were saved
+			saved := w.savedScan
+			w.savedScan = nil
+			for _, path := range saved {
+				w.updateGenerateDirectives(path)
+			}
+		})
+	}()
+}
+
+// updateGenerateDirectives rescans a saved Go file for go:generate
+// directives in the background
+func (w *Window) updateGenerateDirectives(path string) {
+	if w.stopScan != nil {
+		// A scan is under way and may have read the file already
+		w.savedScan = append(w.savedScan, path)
+		return
+	}
+	project := w.config.Project
+	if project == nil {
+		return
+	}
+
+	go func() {
+		found, err := core.FileGenerateDirectives(context.Background(), project, path)
+		w.Post(func() {
+			switch {
+			case w.config.Project != project:
+				// Superseded by another project
+			case w.stopScan != nil:
+				w.savedScan = append(w.savedScan, path)
+			case err != nil:
+				w.ShowError(err)
+			default:
+				w.directives = core.ReplaceGenerateDirectives(w.directives, path, found)
+				w.setGenerateMenu()
+			}
+		})
+	}()
+}
+
+// loadWorkspace finds the project's modules in the background, which
+// walks the tree unless there is a go.work file. Until then a tree of
+// modules without a go.mod at its root is not built as a Go project. A
+// new project cancels the previous walk.
+func (w *Window) loadWorkspace() {
+	if w.stopWalk != nil {
+		w.stopWalk()
+		w.stopWalk = nil
+	}
+
+	project := w.config.Project
+	if project == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stopWalk = cancel
+
+	go func() {
+		_, err := project.Workspace(ctx)
+		w.Post(func() {
+			if ctx.Err() != nil {
+				// Superseded by another project
+				return
+			}
+			cancel()
+			w.stopWalk = nil
+
+			if err != nil {
+				w.ShowError(fmt.Errorf("finding modules: %w", err))
+			}
+		})
+	}()
+}
+
+// filesChanged tells the project about changed files, finding the
+// modules again when a go.mod or go.work file is among them
+func (w *Window) filesChanged(paths []string) {
+	w.config.Project.FilesChanged(paths)
+	for _, path := range paths {
+		if name := filepath.Base(path); name == "go.mod" || name == "go.work" {
+			w.loadWorkspace()
+			return
+		}
+	}
+}
+
+// loadSymbols indexes the project's Go symbols in the background for the
+// symbol search popup. A new project cancels the previous indexing.
+func (w *Window) loadSymbols() {
+	if w.stopIndex != nil {
+		w.stopIndex()
+		w.stopIndex = nil
+	}
+	w.symbols = nil
+	w.savedIndex = nil
+	if w.symbolSearch != nil {
+		w.symbolSearch.SetIndex(nil)
+	}
+
+	project := w.config.Project
+	if project == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stopIndex = cancel
+
+	index := core.NewSymbolIndex(project)
+	go func() {
+		opts := core.DefaultWalkOptions()
+		opts.Logger = w.config.Logger
+		err := index.Update(ctx, opts)
+		w.Post(func() {
+			if ctx.Err() != nil {
+				// Superseded by another project
+				return
+			}
+			cancel()
+			w.stopIndex = nil
+
+			if err != nil {
+				w.ShowError(fmt.Errorf("indexing symbols: %w", err))
+				return
+			}
+			w.symbols = index
+			if w.symbolSearch != nil {
+				w.symbolSearch.SetIndex(index)
+			}
+
+			// The walk may have read files before they were saved
+			saved := w.savedIndex
+			w.savedIndex = nil
+			for _, path := range saved {
+				w.updateSymbols(path)
+			}
+		})
+	}()
+}
+
+// updateSymbols re-indexes a saved Go file in the background
+func (w *Window) updateSymbols(path string) {
+	index := w.symbols
+	if index == nil {
+		if w.stopIndex != nil {
+			// Indexing is under way and may have read the file already
+			w.savedIndex = append(w.savedIndex, path)
+		}
+		return
+	}
+	go func() {
+		if err := index.UpdateFile(path); err != nil {
+			w.Post(func() { w.ShowError(fmt.Errorf("indexing symbols: %w", err)) })
+		}
+	}()
+}
+
+// setGenerateMenu fills the toolbar generate menu from the directives
+func (w *Window) setGenerateMenu() {
+	if w.toolBar == nil {
+		return
+	}
+
+	var options []string
+	if len(w.directives) > 0 {
+		options = append(options, generateAllOption, generatePackageOption)
+	}
+	for _, d := range w.directives {
+		options = append(options, generateDirectiveOption(d))
+	}
+	w.toolBar.SetMenu("generate", options)
+}
+
+// generateDirectiveOption returns the generate menu label of a directive
+func generateDirectiveOption(d core.GenerateDirective) string {
+	return fmt.Sprintf("%s:%d %s", filepath.ToSlash(d.RelPath), d.Line, d.Command)
+}
+
+// generate runs the directives selected in the generate menu, then
+// reloads the explorer and menu since files and directives may change
+func (w *Window) generate(option string) {
+	if w.config.EventHandler == nil || w.config.Project == nil {
+		return
+	}
+
+	var opts core.GenerateOptions
+	switch option {
+	case generateAllOption:
+	case generatePackageOption:
+		file := w.editor.GetCurrentFile()
+		if file == nil || !strings.HasSuffix(file.Path, ".go") {
+			w.ShowMessage("Open a Go file to generate its package")
+			return
+		}
+		pkg, err := core.PackagePatternForFile(w.config.Project.ForModule(context.Background(), file.Path).Path(), file.Path)
+		if err != nil {
+			w.ShowError(err)
+			return
+		}
+		opts.Package = pkg
+	default:
+		for i, d := range w.directives {
+			if generateDirectiveOption(d) == option {
+				opts.Directive = &w.directives[i]
+				break
+			}
+		}
+		if opts.Directive == nil {
+			return
+		}
+	}
+
+	// The handler reports the outcome itself
+	w.runInBackground("Generate", "Running go generate...", "", func() error {
+		err := w.config.EventHandler.OnGenerate(opts)
+		w.Post(func() {
+			if w.fileExplorer != nil {
+				w.fileExplorer.Refresh()
+			}
+			w.loadGenerateDirectives()
+		})
+		return err
+	})
+}
+
+// loadRunConfigs loads the project's run configurations into the toolbar
+func (w *Window) loadRunConfigs() {
+	w.runConfigs = []core.RunConfig{core.DefaultRunConfig()}
+	if w.config.Project != nil {
+		projectConfig, err := core.LoadProjectConfig(w.config.Project.Path())
+		if err != nil {
+			w.ShowError(err)
+		} else {
+			w.runConfigs = projectConfig.RunConfigsOrDefault()
+		}
+	}
+	w.runConfig = w.runConfigs[0]
+
+	if w.toolBar != nil {
+		names := make([]string, len(w.runConfigs))
+		for i, config := range w.runConfigs {
+			names[i] = config.Name
+		}
+		w.toolBar.SetOptions("run-config", names, 0)
+	}
+}
+
+// loadBuildContexts loads the configured build context, the host and
+// the matrix targets into the toolbar target dropdown. The other targets
+// keep the configured tags.
+func (w *Window) loadBuildContexts() {
+	base := core.HostBuildContext()
+	var targets []core.BuildTarget
+	if w.config.Project != nil {
+		projectConfig, err := core.LoadProjectConfig(w.config.Project.Path())
+		if err == nil {
+			base, err = projectConfig.BuildContextOrHost()
+		}
+		if err == nil {
+			targets, err = projectConfig.MatrixTargets()
+		}
+		if err != nil {
+			w.ShowError(err)
+		}
+	}
+
+	w.buildCtxs = []core.BuildContext{base}
+	seen := map[core.BuildTarget]bool{base.Target: true}
+	for _, target := range append([]core.BuildTarget{core.HostBuildContext().Target}, targets...) {
+		if !seen[target] {
+			seen[target] = true
+			w.buildCtxs = append(w.buildCtxs, core.BuildContext{Target: target, Tags: base.Tags})
+		}
+	}
+
+	if w.toolBar != nil {
+		names := make([]string, len(w.buildCtxs))
+		for i, ctx := range w.buildCtxs {
+			names[i] = ctx.String()
+		}
+		w.toolBar.SetOptions("target", names, 0)
+	}
+	w.setBuildContext(base)
+}
+
+// setBuildContext shows which files the build context excludes
+func (w *Window) setBuildContext(ctx core.BuildContext) {
+	if w.fileExplorer != nil {
+		w.fileExplorer.SetBuildContext(ctx)
+	}
+	if w.statusBar != nil {
+		w.statusBar.SetBuildContext(ctx)
+	}
+}
+
 // GetFileExplorer returns the file explorer component
 func (w *Window) GetFileExplorer() FileExplorer {
 	return w.fileExplorer
@@ -184,12 +641,35 @@ func (w *Window) setupEventHandlers() {
 	// Editor change handler
 	if w.editor != nil {
 		w.editor.SetOnChange(w.onEditorChange)
+		w.editor.SetOnDefinition(w.goToDefinition)
 	}
 
 	// Toolbar actions
 	if w.toolBar != nil {
 		w.setupToolbarActions()
 	}
+
+	// Dependency panel module selection
+	if w.depsPanel != nil {
+		w.depsPanel.SetOnModuleSelect(w.onModuleSelect)
+	}
+
+	// Redraw as command output streams in
+	if w.outputPanel != nil {
+		w.outputPanel.SetOnInvalidate(w.window.Invalidate)
+	}
+
+	// Redraw as directories load in the background
+	if w.fileExplorer != nil {
+		w.fileExplorer.SetOnInvalidate(w.window.Invalidate)
+	}
+
+	// Symbol search jumps to the selected declaration
+	if w.symbolSearch != nil {
+		w.symbolSearch.SetOnSelect(func(sym core.Symbol) {
+			w.openFileAt(sym.File, sym.Line, sym.Column)
+		})
+	}
 }
 
 // onFileSelect handles file selection from explorer
@@ -207,12 +687,120 @@ func (w *Window) onFileSelect(file *core.FileInfo) {
 	// Update status bar
 	w.statusBar.SetFileInfo(file, 1, 1) // TODO: Get actual cursor position
 
+	// Test at cursor is only meaningful in test files
+	w.toolBar.EnableAction("test-cursor", strings.HasSuffix(file.Name, "_test.go"))
+	w.toolBar.EnableAction("breakpoint", strings.HasSuffix(file.Name, ".go"))
+
+	// Generated files open read-only until unlocked
+	w.toolBar.EnableAction("unlock", file.Generated)
+
 	// Notify event handler
 	if w.config.EventHandler != nil {
 		w.config.EventHandler.OnFileOpen(file)
 	}
 }
 
+// openFileAt opens a file, unless it is already open, and moves the
+// caret to a 1-based line and byte column
+func (w *Window) openFileAt(path string, line, col int) {
+	if file := w.editor.GetCurrentFile(); file == nil || file.Path != path {
+		info, err := os.Stat(path)
+		if err != nil {
+			w.ShowError(fmt.Errorf("failed to open file: %w", err))
+			return
+		}
+		file := &core.FileInfo{
+			Name:      info.Name(),
+			Path:      path,
+			Size:      info.Size(),
+			ModTime:   info.ModTime().Unix(),
+			Language:  core.GetLanguageForFile(info.Name()),
+			Generated: core.IsGeneratedFile(path),
+		}
+		if w.config.Project != nil {
+			if rel, err := filepath.Rel(w.config.Project.Path(), path); err == nil {
+				file.RelPath = rel
+			}
+		}
+
+		w.onFileSelect(file)
+		if w.editor.GetCurrentFile() != file {
+			// onFileSelect reported the error
+			return
+		}
+	}
+
+	w.editor.GoToPosition(line, col)
+	w.statusBar.SetFileInfo(w.editor.GetCurrentFile(), line, col)
+}
+
+// goToDefinition resolves the identifier at a position of the open file
+// in the background, including unsaved edits, and opens its declaration.
+// A new request cancels the previous one.
+func (w *Window) goToDefinition(line, col int) {
+	file := w.editor.GetCurrentFile()
+	if file == nil || !strings.HasSuffix(file.Name, ".go") {
+		return
+	}
+	if w.stopDef != nil {
+		w.stopDef()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stopDef = cancel
+	w.ShowMessage("Finding definition...")
+
+	path, src := file.Path, []byte(w.editor.GetContent())
+	go func() {
+		def, err := core.FindDefinition(ctx, path, src, line, col)
+		w.Post(func() {
+			if ctx.Err() != nil {
+				// Superseded by another request
+				return
+			}
+			cancel()
+			w.stopDef = nil
+
+			if err != nil {
+				w.ShowError(err)
+				return
+			}
+			w.openFileAt(def.File, def.Line, def.Column)
+			w.ShowMessage(def.Kind + " " + def.QualifiedName())
+		})
+	}()
+}
+
+// onModuleSelect explains why the selected module is needed
+func (w *Window) onModuleSelect(module *core.GoModule) {
+	if w.config.EventHandler == nil {
+		return
+	}
+
+	graph := w.depsPanel.Graph()
+	// The handler reports the outcome itself
+	w.runInBackground("Module "+module.Path, "Explaining "+module.Path+"...", "", func() error {
+		return w.config.EventHandler.OnModuleSelect(graph, module)
+	})
+}
+
+// toggleDeps shows or hides the dependency panel, reloading the
+// dependencies when it is shown
+func (w *Window) toggleDeps() {
+	if w.depsPanel == nil {
+		return
+	}
+
+	w.showDeps = !w.showDeps
+	if !w.showDeps || w.config.EventHandler == nil {
+		return
+	}
+
+	w.depsPanel.SetGraph(nil, "Loading dependencies...")
+	// The handler fills the panel and reports the outcome itself
+	w.runInBackground("Dependencies", "Loading dependencies...", "", w.config.EventHandler.OnDependencies)
+}
+
 // onEditorChange handles editor content changes
 func (w *Window) onEditorChange() {
 	if file := w.editor.GetCurrentFile(); file != nil {
@@ -240,48 +828,467 @@ func (w *Window) setupToolbarActions() {
 		} else {
 			w.ShowMessage("File saved")
 			w.updateTitle() // Remove asterisk
+			file := w.editor.GetCurrentFile()
+			if file != nil && w.config.Project != nil {
+				w.filesChanged([]string{file.Path})
+			}
+			if file != nil && strings.HasSuffix(file.Name, ".go") {
+				w.updateGenerateDirectives(file.Path)
+				w.updateSymbols(file.Path)
+			}
 		}
 	})
 
+	// Unlock action makes a generated file editable
+	w.toolBar.SetOnAction("unlock", func() {
+		w.editor.SetReadOnly(false)
+		w.toolBar.EnableAction("unlock", false)
+		w.ShowMessage("Editing a generated file: changes are lost when it is regenerated")
+	})
+
 	// Build action
 	w.toolBar.SetOnAction("build", func() {
-		w.ShowMessage("Building...")
 		if w.config.EventHandler != nil {
-			if err := w.config.EventHandler.OnBuild(); err != nil {
-				w.ShowError(err)
-			} else {
-				w.ShowMessage("Build successful")
-			}
+			w.runInBackground("Build", "Building...", "Build successful", w.config.EventHandler.OnBuild)
+		}
+	})
+
+	// Check action
+	w.toolBar.SetOnAction("check", func() {
+		if w.config.EventHandler != nil {
+			// The handler reports the outcome itself
+			w.runInBackground("Check", "Running checks...", "", w.config.EventHandler.OnCheck)
 		}
 	})
 
-	// Run action
+	// Run action uses the configuration selected in the dropdown
 	w.toolBar.SetOnAction("run", func() {
-		w.ShowMessage("Running...")
 		if w.config.EventHandler != nil {
-			if err := w.config.EventHandler.OnRun(); err != nil {
-				w.ShowError(err)
-			} else {
-				w.ShowMessage("Execution completed")
+			config := w.runConfig
+			// The handler reports the started program itself
+			w.runInBackground("Run "+config.Name, "Running...", "", func() error {
+				return w.config.EventHandler.OnRunConfig(config)
+			})
+		}
+	})
+
+	// Stop action may interrupt a program while another command runs
+	w.toolBar.SetOnAction("stop", func() {
+		if w.config.EventHandler != nil {
+			go func() {
+				if err := w.config.EventHandler.OnStop(); err != nil {
+					w.Post(func() { w.ShowError(err) })
+				}
+			}()
+		}
+	})
+
+	// Restart action
+	w.toolBar.SetOnAction("restart", func() {
+		if w.config.EventHandler != nil {
+			w.runInBackground("Restart", "Restarting...", "", w.config.EventHandler.OnRestart)
+		}
+	})
+
+	// Debug actions; the session outlives the command that starts it
+	w.toolBar.SetOnAction("debug", func() {
+		if w.config.EventHandler != nil {
+			config := w.runConfig
+			// The handler reports the started session itself
+			w.runInBackground("Debug "+config.Name, "Starting debugger...", "", func() error {
+				return w.config.EventHandler.OnDebug(config)
+			})
+		}
+	})
+	w.toolBar.SetOnAction("breakpoint", w.toggleBreakpoint)
+	for action, command := range debugCommands {
+		w.toolBar.SetOnAction(action, func() {
+			w.debugInBackground(func() error {
+				return w.config.EventHandler.OnDebugCommand(command)
+			})
+		})
+	}
+	w.toolBar.SetOnAction("debug-stop", func() {
+		w.debugInBackground(func() error {
+			return w.config.EventHandler.OnDebugStop()
+		})
+	})
+	w.toolBar.SetOnSelect("run-config", func(name string) {
+		for _, config := range w.runConfigs {
+			if config.Name == name {
+				w.runConfig = config
+				break
+			}
+		}
+	})
+
+	// Watch mode dropdown
+	w.toolBar.SetOptions("watch", watchOptions, 0)
+	w.toolBar.SetOnSelect("watch", func(option string) {
+		w.setWatch(context.Background(), core.WatchAction(strings.TrimPrefix(option, "Watch ")))
+	})
+
+	// Task menu
+	w.toolBar.SetOnSelect("tasks", func(name string) {
+		if w.config.EventHandler == nil {
+			return
+		}
+		task, err := core.FindTask(w.tasks, name)
+		if err != nil {
+			w.ShowError(err)
+			return
+		}
+		// The handler reports the outcome itself
+		w.runInBackground("Task "+task.Name, "Running "+task.Name+"...", "", func() error {
+			return w.config.EventHandler.OnTask(task)
+		})
+	})
+
+	// Generate menu
+	w.toolBar.SetOnSelect("generate", w.generate)
+
+	// Dependency panel toggle
+	w.toolBar.SetOnAction("deps", w.toggleDeps)
+
+	// Symbol search popup, also opened by Ctrl+T
+	w.toolBar.SetOnAction("symbols", w.showSymbolSearch)
+
+	// Build context dropdown
+	w.toolBar.SetOnSelect("target", func(option string) {
+		for _, ctx := range w.buildCtxs {
+			if ctx.String() == option {
+				w.setBuildContext(ctx)
+				return
 			}
 		}
 	})
 
 	// Test action
 	w.toolBar.SetOnAction("test", func() {
-		w.ShowMessage("Running tests...")
 		if w.config.EventHandler != nil {
-			if err := w.config.EventHandler.OnTest(); err != nil {
-				w.ShowError(err)
-			} else {
-				w.ShowMessage("Tests passed")
-			}
+			w.runInBackground("Test", "Running tests...", "Tests passed", w.config.EventHandler.OnTest)
+		}
+	})
+
+	// Test at cursor action
+	w.toolBar.SetOnAction("test-cursor", w.testAtCursor)
+
+	// Affected tests action
+	w.toolBar.SetOnAction("test-affected", w.testAffected)
+
+	// Coverage action
+	w.toolBar.SetOnAction("cover", func() {
+		if w.config.EventHandler != nil {
+			// The handler reports the coverage percentage itself
+			w.runInBackground("Coverage", "Running tests with coverage...", "", w.config.EventHandler.OnCoverage)
+		}
+	})
+
+	// Profile menu
+	w.toolBar.SetMenu("profile", profileOptions)
+	w.toolBar.SetOnSelect("profile", w.profile)
+}
+
+// Options of the profile menu
+const (
+	profileCPUOption  = "CPU profile"
+	profileMemOption  = "Memory profile"
+	profileHideOption = "Hide profile"
+)
+
+var profileOptions = []string{profileCPUOption, profileMemOption, profileHideOption}
+
+// profile profiles the tests of the open file's package, or of the
+// project root package when no Go file is open
+func (w *Window) profile(option string) {
+	if option == profileHideOption {
+		w.editor.SetProfile(nil)
+		return
+	}
+	if w.config.EventHandler == nil || w.config.Project == nil {
+		return
+	}
+
+	opts := core.ProfileOptions{Kind: core.ProfileCPU}
+	if option == profileMemOption {
+		opts.Kind = core.ProfileMem
+	}
+	if file := w.editor.GetCurrentFile(); file != nil && strings.HasSuffix(file.Path, ".go") {
+		pkg, err := core.PackagePatternForFile(w.config.Project.ForModule(context.Background(), file.Path).Path(), file.Path)
+		if err != nil {
+			w.ShowError(err)
+			return
+		}
+		opts.Test.Package = pkg
+	}
+
+	// The handler reports the total cost itself
+	w.runInBackground("Profile", "Profiling...", "", func() error {
+		return w.config.EventHandler.OnProfile(opts)
+	})
+}
+
+// testAtCursor runs the TestXxx function enclosing the editor caret
+func (w *Window) testAtCursor() {
+	file := w.editor.GetCurrentFile()
+	if file == nil || w.config.EventHandler == nil || w.config.Project == nil {
+		return
+	}
+
+	line, _ := w.editor.GetCursorPosition()
+	name, ok := core.FindTestAt(file.Path, []byte(w.editor.GetContent()), line)
+	if !ok {
+		w.ShowMessage("No test function at cursor")
+		return
+	}
+
+	pkg, err := core.PackagePatternForFile(w.config.Project.ForModule(context.Background(), file.Path).Path(), file.Path)
+	if err != nil {
+		w.ShowError(err)
+		return
+	}
+
+	opts := core.TestOptions{
+		Package: pkg,
+		Run:     core.ExactTestPattern(name),
+		Count:   1,
+		Verbose: true,
+	}
+	w.runInBackground("Test "+name, "Running "+name+"...", name+" passed", func() error {
+		return w.config.EventHandler.OnTestSelected(opts)
+	})
+}
+
+// debugCommands maps the debug toolbar actions to debugger commands
+var debugCommands = map[string]core.DebugCommand{
+	"continue":  core.DebugContinue,
+	"step-over": core.DebugNext,
+	"step-in":   core.DebugStepIn,
+	"step-out":  core.DebugStepOut,
+}
+
+// toggleBreakpoint sets or clears a breakpoint on the editor caret's line
+func (w *Window) toggleBreakpoint() {
+	file := w.editor.GetCurrentFile()
+	if file == nil {
+		return
+	}
+
+	line, _ := w.editor.GetCursorPosition()
+	w.debugInBackground(func() error {
+		return w.config.EventHandler.OnToggleBreakpoint(file.Path, line)
+	})
+}
+
+// debugInBackground runs a debugger action off the UI goroutine. Unlike
+// runInBackground it neither waits for nor blocks other commands, since
+// the program being debugged is not one of them.
+func (w *Window) debugInBackground(action func() error) {
+	if w.config.EventHandler == nil {
+		return
+	}
+
+	go func() {
+		if err := action(); err != nil {
+			w.Post(func() { w.ShowError(err) })
+		}
+	}()
+}
+
+// watchOptions are the entries of the watch mode dropdown; the part after
+// "Watch " is the core.WatchAction, or "off"
+var watchOptions = []string{"Watch off", "Watch build", "Watch test", "Watch run"}
+
+// setWatch switches watch mode to the given action; any other value,
+// such as "off", stops watching
+func (w *Window) setWatch(ctx context.Context, action core.WatchAction) {
+	if w.stopWatch != nil {
+		w.stopWatch()
+		w.stopWatch = nil
+		w.watchQueue = nil
+		w.ShowMessage("Watch mode off")
+	}
+
+	if _, ok := core.ParseWatchAction(string(action)); !ok || w.config.Project == nil || w.config.EventHandler == nil {
+		return
+	}
+
+	// Watching a run starts the selected configuration right away
+	if action == core.WatchRun {
+		config := w.runConfig
+		w.runInBackground("Run "+config.Name, "Running...", "", func() error {
+			return w.config.EventHandler.OnWatchRun(config)
+		})
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	w.stopWatch = cancel
+	w.watchMode = action
+
+	watcher := core.NewWatcher(w.config.Project, w.config.Logger)
+	go watcher.Watch(watchCtx, func(changed []string) {
+		w.onWatchChange(watchCtx, action, changed)
+	})
+	w.ShowMessage(fmt.Sprintf("Watching for changes (%s)", action))
+}
+
+// onWatchChange runs the watch action for changed files. It is called on
+// the watcher goroutine and waits for the action to finish, so changes
+// made in the meantime trigger another run instead of overlapping it.
+// Changes arriving while another command runs are queued until it ends.
+func (w *Window) onWatchChange(ctx context.Context, action core.WatchAction, changed []string) {
+	done := make(chan struct{})
+
+	w.Post(func() {
+		w.filesChanged(changed)
+		if w.fileExplorer != nil {
+			w.fileExplorer.RefreshPaths(changed)
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			close(done)
+		case w.busy:
+			w.watchQueue = append(w.watchQueue, changed...)
+			close(done)
+		case !w.startWatchAction(action, changed, func() { close(done) }):
+			close(done)
+		}
+	})
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// runWatchQueue runs the watch action for changes queued while another
+// command was running
+func (w *Window) runWatchQueue() {
+	if len(w.watchQueue) == 0 || w.stopWatch == nil || w.busy {
+		return
+	}
+
+	changed := w.watchQueue
+	w.watchQueue = nil
+	slices.Sort(changed)
+	w.startWatchAction(w.watchMode, slices.Compact(changed), func() {})
+}
+
+// startWatchAction starts the watch action in the background, calling
+// done when it finishes. It returns false if nothing was started.
+func (w *Window) startWatchAction(action core.WatchAction, changed []string, done func()) bool {
+	handler := w.config.EventHandler
+	switch action {
+	case core.WatchBuild:
+		return w.runInBackground("Build", "Building...", "Build successful", func() error {
+			defer done()
+			return handler.OnBuild()
+		})
+	case core.WatchTest:
+		return w.runInBackground("Test affected", "Finding affected packages...", "", func() error {
+			defer done()
+			return handler.OnTestAffected(changed)
+		})
+	case core.WatchRun:
+		return w.runInBackground("Restart", "Restarting...", "", func() error {
+			defer done()
+			return handler.OnWatchRestart()
+		})
+	}
+	return false
+}
+
+// testAffected tests the packages affected by uncommitted changes and by
+// unsaved changes in the editor
+func (w *Window) testAffected() {
+	if w.config.EventHandler == nil || w.config.Project == nil {
+		return
+	}
+
+	root := w.config.Project.Path()
+	var dirty []string
+	if file := w.editor.GetCurrentFile(); file != nil && w.editor.IsDirty() {
+		dirty = append(dirty, file.Path)
+	}
+
+	w.runInBackground("Test affected", "Finding affected packages...", "", func() error {
+		changed, err := core.ChangedFiles(context.Background(), root)
+		if err != nil && len(dirty) == 0 {
+			return err
 		}
+		return w.config.EventHandler.OnTestAffected(append(changed, dirty...))
 	})
 }
 
-// layout renders the main IDE layout
+// runInBackground runs a long-running action off the UI goroutine so the
+// window keeps redrawing while output streams into the output panel.
+// An empty succeeded message leaves the status set by the action.
+// It returns false if another action is still running.
+func (w *Window) runInBackground(title, started, succeeded string, action func() error) bool {
+	if w.busy {
+		w.ShowMessage("Another command is still running")
+		return false
+	}
+
+	w.busy = true
+	w.ShowMessage(started)
+	if w.outputPanel != nil {
+		w.outputPanel.Clear(title)
+	}
+
+	go func() {
+		err := action()
+		w.Post(func() {
+			w.busy = false
+			if err != nil {
+				w.ShowError(err)
+			} else if succeeded != "" {
+				w.ShowMessage(succeeded)
+			}
+			w.runWatchQueue()
+		})
+	}()
+
+	return true
+}
+
+// showSymbolSearch opens the symbol search popup
+func (w *Window) showSymbolSearch() {
+	if w.symbolSearch == nil {
+		return
+	}
+	w.symbolSearch.Show()
+	if w.symbols == nil {
+		w.ShowMessage("Indexing symbols...")
+	}
+}
+
+// layout renders the main IDE layout with the symbol search popup over it
 func (w *Window) layout(gtx layout.Context) layout.Dimensions {
+	for {
+		ev, ok := gtx.Event(key.Filter{Name: "T", Required: key.ModShortcut})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			w.showSymbolSearch()
+		}
+	}
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(w.layoutMain),
+		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			if w.symbolSearch == nil {
+				return layout.Dimensions{}
+			}
+			return w.symbolSearch.Layout(gtx, w.theme.Theme)
+		}),
+	)
+}
+
+// layoutMain renders the toolbar, panels and status bar
+func (w *Window) layoutMain(gtx layout.Context) layout.Dimensions {
 	return layout.Flex{
 		Axis: layout.Vertical,
 	}.Layout(gtx,
@@ -302,9 +1309,30 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 					return w.fileExplorer.Layout(gtx, w.theme.Theme)
 				}),
 
-				// Editor area
+				// Dependency panel, when shown
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					if !w.showDeps || w.depsPanel == nil {
+						return layout.Dimensions{}
+					}
+					gtx.Constraints.Max.X = gtx.Dp(unit.Dp(280))
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return w.depsPanel.Layout(gtx, w.theme.Theme)
+				}),
+
+				// Editor area with output panel below
 				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
-					return w.editor.Layout(gtx, w.theme.Theme)
+					return layout.Flex{
+						Axis: layout.Vertical,
+					}.Layout(gtx,
+						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+							return w.editor.Layout(gtx, w.theme.Theme)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							gtx.Constraints.Max.Y = gtx.Dp(unit.Dp(200))
+							gtx.Constraints.Min.Y = gtx.Constraints.Max.Y
+							return w.outputPanel.Layout(gtx, w.theme.Theme)
+						}),
+					)
 				}),
 			)
 		}),
@@ -348,3 +1376,18 @@ func (w *Window) updateTitle() {
 func (w *Window) GetToolBar() ToolBar {
 	return w.toolBar
 }
+
+// GetOutputPanel returns the output panel component
+func (w *Window) GetOutputPanel() OutputPanel {
+	return w.outputPanel
+}
+
+// GetDependencyPanel returns the dependency panel component
+func (w *Window) GetDependencyPanel() DependencyPanel {
+	return w.depsPanel
+}
+
+// GetSymbolSearch returns the symbol search popup
+func (w *Window) GetSymbolSearch() SymbolSearch {
+	return w.symbolSearch
+}